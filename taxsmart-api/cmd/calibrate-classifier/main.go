@@ -0,0 +1,140 @@
+// Command calibrate-classifier fits the hybrid classifier's vote calibration
+// to a CSV of labelled transactions and reports precision and recall per
+// category before and after.
+//
+//	go run ./cmd/calibrate-classifier -data labelled.csv -out calibration.json
+//
+// The AI provider is configured from the same environment as the server; it
+// is only consulted when configured. Point CLASSIFIER_CALIBRATION_FILE at the
+// output to use it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/taxsmart/taxsmart-api/internal/config"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+)
+
+func main() {
+	dataPath := flag.String("data", "", "CSV of labelled transactions (description, amount, type, category[, date])")
+	outPath := flag.String("out", "calibration.json", "file to write the calibration to")
+	holdout := flag.Float64("holdout", 0.2, "share of transactions kept back to evaluate the calibration")
+	flag.Parse()
+
+	if *dataPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *holdout < 0 || *holdout >= 1 {
+		log.Fatal("-holdout must be at least 0 and below 1")
+	}
+
+	godotenv.Load()
+	cfg := config.Load()
+
+	c, err := newClassifier(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	file, err := os.Open(*dataPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples, err := classifier.ReadLabelledCSV(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", *dataPath, err)
+	}
+
+	train, test := split(samples, *holdout)
+	if len(train) == 0 {
+		log.Fatal("no labelled transactions to fit")
+	}
+	if len(test) == 0 {
+		test = train
+	}
+
+	ctx := context.Background()
+	fmt.Printf("Before calibration (%d transactions):\n", len(test))
+	c.Evaluate(ctx, test).WriteTable(os.Stdout)
+
+	calibration, err := c.FitCalibration(ctx, train)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c.SetCalibration(calibration)
+
+	fmt.Printf("\nAfter calibration on %d transactions:\n", len(train))
+	c.Evaluate(ctx, test).WriteTable(os.Stdout)
+
+	if err := classifier.SaveCalibration(*outPath, calibration); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\nCalibration written to %s\n", *outPath)
+}
+
+// newClassifier builds the classifier the server would, without storage
+func newClassifier(cfg *config.Config) (*classifier.Classifier, error) {
+	var ai *classifier.AIClassifier
+	aiConfig := classifier.AIConfig{
+		Provider:          cfg.AIProvider,
+		APIKey:            cfg.AIAPIKey,
+		Model:             cfg.AIModel,
+		BaseURL:           cfg.AIBaseURL,
+		BatchSize:         cfg.AIBatchSize,
+		Concurrency:       cfg.AIConcurrency,
+		RequestsPerSecond: cfg.AIRequestsPerSecond,
+	}
+	if aiConfig.Enabled() {
+		var err error
+		if ai, err = classifier.NewAIClassifier(aiConfig, nil); err != nil {
+			return nil, fmt.Errorf("failed to configure AI classifier: %w", err)
+		}
+	}
+
+	c := classifier.NewClassifier(ai, nil, classifier.NewCache(cfg.ClassificationCacheSize, cfg.ClassificationCacheTTL, nil))
+	thresholds := classifier.Thresholds{AI: cfg.ClassifierAIThreshold, AIVote: cfg.ClassifierAIVote, Accepted: cfg.ClassifierAccepted}
+	if err := c.SetThresholds(thresholds); err != nil {
+		return nil, fmt.Errorf("invalid classifier thresholds: %w", err)
+	}
+	if cfg.ClassifierLocalModel != "" {
+		local, err := classifier.LoadLocalModel(cfg.ClassifierLocalModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local classifier model: %w", err)
+		}
+		c.SetLocalModel(local)
+	}
+
+	if cfg.RulePacksDir != "" {
+		packs, err := classifier.LoadRulePacks(cfg.RulePacksDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rule packs: %w", err)
+		}
+		for _, pack := range packs {
+			if err := c.Rules().AddPack(pack); err != nil {
+				return nil, fmt.Errorf("failed to load rule pack %s: %w", pack.Name, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// split keeps back an evenly spread share of the samples for evaluation
+func split(samples []classifier.LabelledTransaction, share float64) (train, test []classifier.LabelledTransaction) {
+	for i, sample := range samples {
+		if int(float64(i+1)*share) != int(float64(i)*share) {
+			test = append(test, sample)
+		} else {
+			train = append(train, sample)
+		}
+	}
+	return train, test
+}
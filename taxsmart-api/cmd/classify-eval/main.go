@@ -0,0 +1,236 @@
+// Command classify-eval scores the rule engine, the AI classifier and the
+// hybrid classifier against a CSV of labelled transactions, printing each
+// one's per-category F1 and confusion matrix and its regressions from a
+// stored baseline. It exits with status 1 when macro F1 or any category's
+// F1 falls by more than the tolerance.
+//
+//	go run ./cmd/classify-eval
+//	go run ./cmd/classify-eval -update
+//
+// By default the AI answers are replayed from a recording, so runs are
+// repeatable and need no API key; testdata/benchmark/ai-recording.json is a
+// hand-written stub. With -ai record the configured provider is called and
+// its answers are saved to the recording for later runs. Rule packs, the
+// calibration, the local model and thresholds come from the same
+// environment as the server, and the hybrid classifier reuses the AI answers
+// from the same run.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+
+	"github.com/taxsmart/taxsmart-api/internal/config"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
+)
+
+// AI modes
+const (
+	aiReplay = "replay" // Answer from the recording
+	aiRecord = "record" // Call the configured provider and save its answers
+	aiNone   = "none"   // Leave the AI out
+)
+
+func main() {
+	dataPath := flag.String("data", "testdata/benchmark/labelled.csv", "CSV of labelled transactions (description, amount, type, category[, date])")
+	baselinePath := flag.String("baseline", "testdata/benchmark/baseline.json", "baseline to compare with")
+	aiMode := flag.String("ai", aiReplay, "AI answers: replay, record or none")
+	recordingPath := flag.String("recording", "testdata/benchmark/ai-recording.json", "recording of AI answers to replay or write")
+	update := flag.Bool("update", false, "write the results as the new baseline instead of comparing")
+	tolerance := flag.Float64("tolerance", 0.005, "F1 drop allowed before failing")
+	flag.Parse()
+
+	godotenv.Load()
+	cfg := config.Load()
+
+	file, err := os.Open(*dataPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples, err := classifier.ReadLabelledCSV(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", *dataPath, err)
+	}
+	if len(samples) == 0 {
+		log.Fatalf("%s: no labelled transactions", *dataPath)
+	}
+
+	ai, recording, err := newAIClassifier(cfg, *aiMode, *recordingPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	transactions := make([]model.ParsedTransaction, len(samples))
+	for i, sample := range samples {
+		transactions[i] = parser.EnrichNarration(sample.ParsedTransaction)
+	}
+
+	names := []string{"rules"}
+	results := make(map[string][]model.ClassificationResult)
+	if ai != nil {
+		answers, err := ai.ClassifyBatch(ctx, uuid.Nil, transactions)
+		if err != nil {
+			log.Fatalf("AI classification failed: %v", err)
+		}
+		if recording != nil {
+			if err := classifier.SaveAIRecording(*recordingPath, recording); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("AI answers recorded to %s\n\n", *recordingPath)
+			// The hybrid classifier replays the same answers rather than asking again
+			ai = classifier.NewReplayAIClassifier(recording)
+		}
+		names = append(names, "ai")
+		results["ai"] = answers
+	}
+
+	hybrid, err := newClassifier(cfg, ai)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rules := make([]model.ClassificationResult, len(transactions))
+	for i, tx := range transactions {
+		rules[i] = hybrid.Rules().ClassifyTransaction(tx)
+	}
+	results["rules"] = rules
+	names = append(names, "hybrid")
+	results["hybrid"] = hybrid.ClassifyBatch(ctx, uuid.Nil, transactions)
+	// As on upload, detected reversals, refunds and loans replace the hybrid's answers
+	for _, d := range classifier.NewCreditDetector().Detect(transactions, results["hybrid"], nil) {
+		results["hybrid"][d.Index] = d.Result
+	}
+
+	baselines, err := classifier.LoadBaselines(*baselinePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		baselines = nil
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
+	updated := make(map[string]classifier.Baseline)
+	failed := false
+	for _, name := range names {
+		predictions := results[name]
+		report := classifier.Evaluate(samples, predictions)
+		updated[name] = classifier.NewBaseline(samples, predictions, report)
+
+		fmt.Printf("== %s ==\n", name)
+		report.WriteTable(os.Stdout)
+		fmt.Println()
+		report.WriteConfusion(os.Stdout)
+		fmt.Println()
+
+		if *update {
+			continue
+		}
+		baseline, ok := baselines[name]
+		if !ok {
+			fmt.Printf("No %s baseline in %s; run with -update to record one\n\n", name, *baselinePath)
+			continue
+		}
+		comparison := baseline.Compare(samples, predictions, report, *tolerance)
+		fmt.Println("Regressions from baseline:")
+		comparison.WriteTable(os.Stdout)
+		fmt.Println()
+		if comparison.Failed() {
+			fmt.Printf("FAIL: %s F1 fell by more than %.3f\n\n", name, *tolerance)
+			failed = true
+		}
+	}
+
+	if *update {
+		if err := classifier.SaveBaselines(*baselinePath, updated); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Baseline written to %s\n", *baselinePath)
+		return
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// newAIClassifier builds the AI classifier for the mode. In record mode it
+// also returns the recording its answers are kept in.
+func newAIClassifier(cfg *config.Config, mode, recordingPath string) (*classifier.AIClassifier, *classifier.AIRecording, error) {
+	switch mode {
+	case aiNone:
+		return nil, nil, nil
+	case aiReplay:
+		recording, err := classifier.LoadAIRecording(recordingPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load AI recording: %w", err)
+		}
+		return classifier.NewReplayAIClassifier(recording), nil, nil
+	case aiRecord:
+		aiConfig := classifier.AIConfig{
+			Provider:          cfg.AIProvider,
+			APIKey:            cfg.AIAPIKey,
+			Model:             cfg.AIModel,
+			BaseURL:           cfg.AIBaseURL,
+			BatchSize:         cfg.AIBatchSize,
+			Concurrency:       cfg.AIConcurrency,
+			RequestsPerSecond: cfg.AIRequestsPerSecond,
+		}
+		if !aiConfig.Enabled() {
+			return nil, nil, fmt.Errorf("recording needs an AI provider; set AI_PROVIDER and AI_API_KEY")
+		}
+		ai, err := classifier.NewAIClassifier(aiConfig, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to configure AI classifier: %w", err)
+		}
+		recording := classifier.NewAIRecording(ai.Version())
+		ai.RecordTo(recording)
+		return ai, recording, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown -ai mode %q", mode)
+	}
+}
+
+// newClassifier builds the hybrid classifier the server would around the AI, without storage
+func newClassifier(cfg *config.Config, ai *classifier.AIClassifier) (*classifier.Classifier, error) {
+	c := classifier.NewClassifier(ai, nil, nil)
+	thresholds := classifier.Thresholds{AI: cfg.ClassifierAIThreshold, AIVote: cfg.ClassifierAIVote, Accepted: cfg.ClassifierAccepted}
+	if err := c.SetThresholds(thresholds); err != nil {
+		return nil, fmt.Errorf("invalid classifier thresholds: %w", err)
+	}
+	if cfg.ClassifierCalibration != "" {
+		calibration, err := classifier.LoadCalibration(cfg.ClassifierCalibration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load classifier calibration: %w", err)
+		}
+		c.SetCalibration(calibration)
+	}
+	if cfg.ClassifierLocalModel != "" {
+		local, err := classifier.LoadLocalModel(cfg.ClassifierLocalModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local classifier model: %w", err)
+		}
+		c.SetLocalModel(local)
+	}
+
+	if cfg.RulePacksDir != "" {
+		packs, err := classifier.LoadRulePacks(cfg.RulePacksDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rule packs: %w", err)
+		}
+		for _, pack := range packs {
+			if err := c.Rules().AddPack(pack); err != nil {
+				return nil, fmt.Errorf("failed to load rule pack %s: %w", pack.Name, err)
+			}
+		}
+	}
+	return c, nil
+}
@@ -16,6 +16,9 @@ import (
 	"github.com/taxsmart/taxsmart-api/internal/config"
 	"github.com/taxsmart/taxsmart-api/internal/handler"
 	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+	"github.com/taxsmart/taxsmart-api/internal/repository/postgres"
 )
 
 func main() {
@@ -43,8 +46,31 @@ func main() {
 		log.Fatalf("error initializing firebase app: %v\n", err)
 	}
 
+	// Initialize storage
+	var repo repository.Repository
+	if cfg.DatabaseURL != "" {
+		repo, err = postgres.New(ctx, cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("error initializing database: %v\n", err)
+		}
+	} else {
+		repo = memory.New()
+	}
+	defer repo.Close()
+
 	// Create handlers
-	h := handler.NewHandler(cfg.AIProvider, cfg.AIAPIKey)
+	h, err := handler.NewHandler(cfg, repo)
+	if err != nil {
+		log.Fatalf("error initializing handlers: %v\n", err)
+	}
+
+	// Start background jobs and webhook deliveries, resuming any left unfinished
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+	if err := h.StartJobs(jobsCtx); err != nil {
+		log.Fatalf("error starting background jobs: %v\n", err)
+	}
+	h.StartWebhooks(jobsCtx)
 
 	// Create router
 	r := chi.NewRouter()
@@ -55,7 +81,7 @@ func main() {
 	r.Use(chiMiddleware.RequestID)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "https://*.vercel.app"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -69,12 +95,64 @@ func main() {
 		// Public endpoints (no auth required for parsing/classification)
 		r.Post("/parse", h.ParseFile)
 		r.Post("/classify", h.ClassifyTransactions)
+		r.Get("/categories", h.ListCategories)
 		r.Post("/tax/quick-pit", h.QuickCalculatePIT)
 
 		// Protected endpoints
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.FirebaseAuth(app))
+			r.Post("/uploads", h.CreateUpload)
+			r.Get("/uploads", h.ListUploads)
+			r.Get("/uploads/{id}", h.GetUpload)
+			r.Get("/transactions", h.ListTransactions)
+			r.Post("/transactions", h.CreateTransaction)
+			r.Post("/transactions/bulk-update", h.BulkUpdateTransactions)
+			r.Get("/transactions/counterparties", h.ListCounterparties)
+			r.Get("/transactions/{id}", h.GetTransaction)
+			r.Patch("/transactions/{id}", h.UpdateTransaction)
+			r.Delete("/transactions/{id}", h.DeleteTransaction)
+			r.Get("/transactions/{id}/history", h.GetTransactionHistory)
+			r.Get("/income/sources", h.ListIncomeSources)
+			r.Post("/jobs", h.SubmitJob)
+			r.Get("/jobs", h.ListJobs)
+			r.Get("/jobs/{id}", h.GetJob)
+			r.Get("/jobs/{id}/events", h.StreamJobEvents)
+			r.Post("/jobs/{id}/cancel", h.CancelJob)
+			r.Post("/webhooks", h.CreateWebhook)
+			r.Get("/webhooks", h.ListWebhooks)
+			r.Delete("/webhooks/{id}", h.DeleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", h.ListWebhookDeliveries)
+			r.Post("/webhooks/deliveries/{id}/replay", h.ReplayWebhookDelivery)
+			r.Get("/rules/learned", h.ListUserRules)
+			r.Delete("/rules/learned/{id}", h.DeleteUserRule)
+			r.Get("/settings", h.GetSettings)
+			r.Patch("/settings", h.UpdateSettings)
+			r.Get("/settings/ai-audit", h.ListMyAIAudit)
 			r.Post("/tax/calculate", h.CalculateTax)
+			r.Post("/tax/calculate/years", h.CalculateTaxYears)
+			r.Get("/tax/reports", h.ListReports)
+			r.Get("/tax/reports/compare", h.CompareReports)
+			r.Get("/tax/reports/{year}", h.GetReport)
+			r.Post("/tax/simulate", h.SimulateTax)
+			r.Post("/tax/recommendations", h.RecommendReliefs)
+			r.Post("/tax/provisional", h.EstimateProvisionalTax)
+
+			// Admin endpoints
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireAdmin(cfg.AdminUIDs))
+				r.Get("/admin/rule-packs", h.ListRulePacks)
+				r.Put("/admin/rule-packs", h.SaveRulePack)
+				r.Post("/admin/rule-packs/dry-run", h.DryRunRulePack)
+				r.Get("/admin/rule-packs/{name}", h.GetRulePack)
+				r.Delete("/admin/rule-packs/{name}", h.DeleteRulePack)
+				r.Get("/admin/classifier/cache", h.GetClassificationCacheStats)
+				r.Delete("/admin/classifier/cache", h.ClearClassificationCache)
+				r.Get("/admin/classifier/calibration", h.GetClassifierCalibration)
+				r.Get("/admin/classifier/ai-errors", h.GetClassifierAIErrors)
+				r.Get("/admin/classifier/training-data", h.ExportTrainingData)
+				r.Post("/admin/classifier/evaluate", h.EvaluateClassifier)
+				r.Get("/admin/ai-audit", h.ListAIAudit)
+			})
 		})
 	})
 
@@ -88,7 +166,13 @@ func main() {
 	log.Printf("📊 AI Provider: %s", cfg.AIProvider)
 	log.Printf("🔥 Firebase Auth: Enabled")
 
-	if cfg.AIAPIKey != "" {
+	if cfg.DatabaseURL != "" {
+		log.Printf("🗄️  Storage: PostgreSQL")
+	} else {
+		log.Printf("🗄️  Storage: In-memory (data is lost on restart)")
+	}
+
+	if cfg.AIAPIKey != "" || cfg.AIBaseURL != "" {
 		log.Printf("🤖 AI Classification: Enabled")
 	} else {
 		log.Printf("📋 AI Classification: Disabled (rule-based only)")
@@ -0,0 +1,78 @@
+// Command train-classifier trains the offline local classifier on a CSV of
+// labelled transactions and reports its precision and recall per category on
+// a held-out share of them.
+//
+//	go run ./cmd/train-classifier -data labelled.csv -out local-model.json
+//
+// Labelled transactions can be exported from the admin API at
+// GET /api/admin/classifier/training-data. Point CLASSIFIER_LOCAL_MODEL_FILE
+// at the output to use the model.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+)
+
+func main() {
+	dataPath := flag.String("data", "", "CSV of labelled transactions (description, amount, type, category[, date])")
+	outPath := flag.String("out", "local-model.json", "file to write the model to")
+	holdout := flag.Float64("holdout", 0.2, "share of transactions kept back to evaluate the model")
+	flag.Parse()
+
+	if *dataPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *holdout < 0 || *holdout >= 1 {
+		log.Fatal("-holdout must be at least 0 and below 1")
+	}
+
+	file, err := os.Open(*dataPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples, err := classifier.ReadLabelledCSV(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", *dataPath, err)
+	}
+
+	train, test := split(samples, *holdout)
+	m, err := classifier.TrainLocalModel(train)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(test) > 0 {
+		predictions := make([]model.ClassificationResult, len(test))
+		for i, sample := range test {
+			predictions[i] = m.Classify(sample.ParsedTransaction)
+		}
+		fmt.Printf("Local model trained on %d transactions, evaluated on %d:\n", len(train), len(test))
+		classifier.Evaluate(test, predictions).WriteTable(os.Stdout)
+		fmt.Println()
+	}
+
+	if err := classifier.SaveLocalModel(*outPath, m); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Model with %d categories written to %s\n", len(m.Classes), *outPath)
+}
+
+// split keeps back an evenly spread share of the samples for evaluation
+func split(samples []classifier.LabelledTransaction, share float64) (train, test []classifier.LabelledTransaction) {
+	for i, sample := range samples {
+		if int(float64(i+1)*share) != int(float64(i)*share) {
+			test = append(test, sample)
+		} else {
+			train = append(train, sample)
+		}
+	}
+	return train, test
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,8 +13,30 @@ type Config struct {
 	SupabaseKey             string
 	SupabaseJWTSecret       string
 	FirebaseCredentialsFile string
-	AIProvider              string // "gemini", "openai", "claude"
+	AIProvider              string // "gemini", "openai", "anthropic" (or "claude"), "openai-compatible"
 	AIAPIKey                string
+	AIModel                 string        // Overrides the provider's default model
+	AIBaseURL               string        // Overrides the provider's endpoint, e.g. a local Ollama server
+	AIBatchSize             int           // Transactions per AI request
+	AIConcurrency           int           // AI requests in flight at once
+	AIRequestsPerSecond     float64       // AI request rate limit
+	ClassificationCacheSize int           // Classification results kept in memory
+	ClassificationCacheTTL  time.Duration // How long a cached classification is reused
+	ClassifierAIThreshold   float64       // Ask the AI below this local ensemble confidence
+	ClassifierAIVote        float64       // Count AI answers above this calibrated confidence
+	ClassifierAccepted      float64       // Leave transactions uncategorized below this confidence
+	ClassifierCalibration   string        // Calibration file written by calibrate-classifier
+	ClassifierLocalModel    string        // Local model file written by train-classifier
+	DatabaseURL             string        // PostgreSQL connection string; in-memory storage when empty
+	AdminUIDs               []string      // Firebase user IDs allowed to use the admin API
+	RulePacksDir            string        // Directory of additional classification rule packs
+	JobWorkers              int           // Background jobs processed at once
+	JobQueueSize            int           // Background jobs waiting for a worker before submissions are refused
+	WebhookMaxAttempts      int           // Attempts at a webhook delivery before it fails
+	WebhookRetryDelay       time.Duration // Wait after a failed delivery, doubled after each further failure
+	WebhookMaxRetryDelay    time.Duration // Longest wait between delivery attempts
+	WebhookTimeout          time.Duration // How long a webhook has to respond
+	WebhookWorkers          int           // Webhook deliveries sent at once
 	Environment             string
 }
 
@@ -24,6 +49,28 @@ func Load() *Config {
 		FirebaseCredentialsFile: getEnv("FIREBASE_CREDENTIALS_FILE", ""),
 		AIProvider:              getEnv("AI_PROVIDER", "gemini"),
 		AIAPIKey:                getEnv("AI_API_KEY", ""),
+		AIModel:                 getEnv("AI_MODEL", ""),
+		AIBaseURL:               getEnv("AI_BASE_URL", ""),
+		AIBatchSize:             getEnvInt("AI_BATCH_SIZE", 50),
+		AIConcurrency:           getEnvInt("AI_CONCURRENCY", 4),
+		AIRequestsPerSecond:     getEnvFloat("AI_REQUESTS_PER_SECOND", 2),
+		ClassificationCacheSize: getEnvInt("CLASSIFICATION_CACHE_SIZE", 10000),
+		ClassificationCacheTTL:  getEnvDuration("CLASSIFICATION_CACHE_TTL", 30*24*time.Hour),
+		ClassifierAIThreshold:   getEnvFloat("CLASSIFIER_AI_THRESHOLD", 0.8),
+		ClassifierAIVote:        getEnvFloat("CLASSIFIER_AI_VOTE_THRESHOLD", 0.7),
+		ClassifierAccepted:      getEnvFloat("CLASSIFIER_ACCEPT_THRESHOLD", 0),
+		ClassifierCalibration:   getEnv("CLASSIFIER_CALIBRATION_FILE", ""),
+		ClassifierLocalModel:    getEnv("CLASSIFIER_LOCAL_MODEL_FILE", ""),
+		DatabaseURL:             getEnv("DATABASE_URL", ""),
+		AdminUIDs:               getEnvList("ADMIN_UIDS"),
+		RulePacksDir:            getEnv("RULE_PACKS_DIR", ""),
+		JobWorkers:              getEnvInt("JOB_WORKERS", 2),
+		JobQueueSize:            getEnvInt("JOB_QUEUE_SIZE", 100),
+		WebhookMaxAttempts:      getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryDelay:       getEnvDuration("WEBHOOK_RETRY_DELAY", 30*time.Second),
+		WebhookMaxRetryDelay:    getEnvDuration("WEBHOOK_MAX_RETRY_DELAY", 6*time.Hour),
+		WebhookTimeout:          getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookWorkers:          getEnvInt("WEBHOOK_WORKERS", 4),
 		Environment:             getEnv("ENVIRONMENT", "development"),
 	}
 }
@@ -34,3 +81,41 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvInt reads an integer variable, using the fallback when unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvFloat reads a float variable, using the fallback when unset or invalid
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration reads a duration such as "720h", using the fallback when unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList splits a comma-separated variable, ignoring blank entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// maxDryRunTransactions bounds the sample a dry run will classify
+const maxDryRunTransactions = 10000
+
+// maxEvaluationSamples bounds the labelled transactions an evaluation will classify
+const maxEvaluationSamples = 10000
+
+// maxTrainingExport bounds the labelled transactions exported as training data
+const maxTrainingExport = 100000
+
+// ruleChange shows how a rule pack change reclassifies one sample transaction
+type ruleChange struct {
+	Transaction model.ParsedTransaction    `json:"transaction"`
+	Before      model.ClassificationResult `json:"before"`
+	After       model.ClassificationResult `json:"after"`
+	Changed     bool                       `json:"changed"`
+}
+
+// ListRulePacks handles listing the installed classification rule packs
+func (h *Handler) ListRulePacks(w http.ResponseWriter, r *http.Request) {
+	packs := h.classifier.Rules().Packs()
+
+	summaries := make([]map[string]interface{}, len(packs))
+	for i, pack := range packs {
+		summaries[i] = map[string]interface{}{
+			"name":        pack.Name,
+			"version":     pack.Version,
+			"description": pack.Description,
+			"rule_count":  len(pack.Rules),
+		}
+	}
+
+	response.Success(w, map[string]interface{}{
+		"packs": summaries,
+		"count": len(summaries),
+	})
+}
+
+// GetRulePack handles fetching an installed rule pack with its rules
+func (h *Handler) GetRulePack(w http.ResponseWriter, r *http.Request) {
+	pack, ok := h.classifier.Rules().Pack(chi.URLParam(r, "name"))
+	if !ok {
+		response.NotFound(w, "Rule pack not found")
+		return
+	}
+	response.Success(w, pack)
+}
+
+// SaveRulePack handles adding a rule pack or replacing one with the same name
+func (h *Handler) SaveRulePack(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	var pack classifier.RulePack
+	if err := json.Unmarshal(body, &pack); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+	if err := pack.Validate(); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	h.rulePacksMu.Lock()
+	defer h.rulePacksMu.Unlock()
+
+	if h.rulePacksDir != "" {
+		if err := classifier.SaveRulePack(h.rulePacksDir, pack); err != nil {
+			response.InternalError(w, "Failed to save rule pack")
+			return
+		}
+	}
+	if err := h.classifier.Rules().AddPack(pack); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	response.Created(w, map[string]interface{}{
+		"name":       pack.Name,
+		"version":    pack.Version,
+		"rule_count": len(pack.Rules),
+		"persisted":  h.rulePacksDir != "",
+	})
+}
+
+// DeleteRulePack handles removing an installed rule pack
+func (h *Handler) DeleteRulePack(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	h.rulePacksMu.Lock()
+	defer h.rulePacksMu.Unlock()
+
+	if _, ok := h.classifier.Rules().Pack(name); !ok {
+		response.NotFound(w, "Rule pack not found")
+		return
+	}
+	if err := h.classifier.Rules().RemovePack(name); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	if h.rulePacksDir != "" {
+		if err := classifier.DeleteRulePack(h.rulePacksDir, name); err != nil {
+			response.InternalError(w, "Failed to delete rule pack file")
+			return
+		}
+	}
+
+	response.Success(w, map[string]string{"name": name})
+}
+
+// DryRunRulePack handles previewing how adding or replacing a rule pack would
+// reclassify a sample of transactions. Only the rule engine is consulted, so
+// learned user rules and AI results do not mask the change.
+func (h *Handler) DryRunRulePack(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pack         classifier.RulePack       `json:"pack"`
+		Transactions []model.ParsedTransaction `json:"transactions"`
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+	if len(req.Transactions) == 0 {
+		response.BadRequest(w, "No transactions provided")
+		return
+	}
+	if len(req.Transactions) > maxDryRunTransactions {
+		response.BadRequest(w, "Too many transactions for a dry run")
+		return
+	}
+
+	current := h.classifier.Rules()
+	proposed, err := current.WithPack(req.Pack)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	changes := make([]ruleChange, len(req.Transactions))
+	before := make(map[model.Category]int)
+	after := make(map[model.Category]int)
+	changed := 0
+	for i, tx := range req.Transactions {
+		change := ruleChange{
+			Transaction: tx,
+			Before:      current.ClassifyTransaction(tx),
+			After:       proposed.ClassifyTransaction(tx),
+		}
+		change.Changed = change.Before.Category != change.After.Category ||
+			change.Before.Confidence != change.After.Confidence
+		if change.Changed {
+			changed++
+		}
+		before[change.Before.Category]++
+		after[change.After.Category]++
+		changes[i] = change
+	}
+
+	response.Success(w, map[string]interface{}{
+		"pack":              req.Pack.Name,
+		"version":           req.Pack.Version,
+		"total":             len(changes),
+		"changed":           changed,
+		"categories_before": before,
+		"categories_after":  after,
+		"transactions":      changes,
+	})
+}
+
+// GetClassificationCacheStats handles reporting the classification cache hit rate
+func (h *Handler) GetClassificationCacheStats(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, h.classifier.Cache().Stats())
+}
+
+// ClearClassificationCache handles dropping every cached classification result
+func (h *Handler) ClearClassificationCache(w http.ResponseWriter, r *http.Request) {
+	if err := h.classifier.Cache().Clear(r.Context()); err != nil {
+		response.InternalError(w, "Failed to clear classification cache")
+		return
+	}
+	response.Success(w, map[string]bool{"cleared": true})
+}
+
+// GetClassifierAIErrors handles reporting the AI requests that failed, by
+// kind, which classification otherwise hides by falling back to local votes
+func (h *Handler) GetClassifierAIErrors(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, h.classifier.AIErrors())
+}
+
+// GetClassifierCalibration handles reporting the classifier's thresholds and vote calibration
+func (h *Handler) GetClassifierCalibration(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, map[string]interface{}{
+		"thresholds":  h.classifier.Thresholds(),
+		"calibration": h.classifier.Calibration(),
+	})
+}
+
+// EvaluateClassifier handles scoring the classifier against an uploaded CSV of
+// labelled transactions, reporting precision and recall per category
+func (h *Handler) EvaluateClassifier(w http.ResponseWriter, r *http.Request) {
+	r.ParseMultipartForm(10 << 20)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		response.BadRequest(w, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	samples, err := classifier.ReadLabelledCSV(file)
+	if err != nil {
+		response.BadRequest(w, "Invalid labelled CSV: "+err.Error())
+		return
+	}
+	if len(samples) == 0 {
+		response.BadRequest(w, "No labelled transactions provided")
+		return
+	}
+	if len(samples) > maxEvaluationSamples {
+		response.BadRequest(w, "Too many transactions for an evaluation")
+		return
+	}
+
+	response.Success(w, h.classifier.Evaluate(r.Context(), samples))
+}
+
+// ExportTrainingData handles downloading manually categorized transactions as
+// a labelled CSV for train-classifier. Descriptions are redacted, so exports
+// carry no account numbers, contact details or names of people.
+func (h *Handler) ExportTrainingData(w http.ResponseWriter, r *http.Request) {
+	limit := maxTrainingExport
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTrainingExport {
+			response.BadRequest(w, "limit must be between 1 and "+strconv.Itoa(maxTrainingExport))
+			return
+		}
+		limit = n
+	}
+
+	txs, err := h.repo.ListLabelledTransactions(r.Context(), limit)
+	if err != nil {
+		response.InternalError(w, "Failed to load labelled transactions")
+		return
+	}
+
+	redactor := classifier.NewRedactor()
+	samples := make([]classifier.LabelledTransaction, len(txs))
+	for i, tx := range txs {
+		var names []string
+		if classifier.IsPersonalName(tx.Counterparty) {
+			names = append(names, tx.Counterparty)
+		}
+		samples[i] = classifier.LabelledTransaction{
+			ParsedTransaction: model.ParsedTransaction{
+				Date:        tx.TransactionDate,
+				Description: redactor.Redact(tx.Description, names...),
+				Amount:      tx.Amount,
+				Type:        tx.TransactionType,
+			},
+			Label: tx.Category,
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="training-data.csv"`)
+	classifier.WriteLabelledCSV(w, samples)
+}
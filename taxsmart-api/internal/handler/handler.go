@@ -1,16 +1,29 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/config"
 	"github.com/taxsmart/taxsmart-api/internal/middleware"
 	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
 	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+	"github.com/taxsmart/taxsmart-api/internal/service/income"
+	"github.com/taxsmart/taxsmart-api/internal/service/jobs"
 	"github.com/taxsmart/taxsmart-api/internal/service/parser"
 	"github.com/taxsmart/taxsmart-api/internal/service/tax"
+	"github.com/taxsmart/taxsmart-api/internal/service/webhook"
 	"github.com/taxsmart/taxsmart-api/pkg/response"
 )
 
@@ -18,16 +31,99 @@ import (
 type Handler struct {
 	csvParser  *parser.CSVParser
 	classifier *classifier.Classifier
+	detector   *classifier.CreditDetector
 	taxEngine  *tax.Engine
+	repo       repository.Repository
+	jobs       *jobs.Runner
+	webhooks   *webhook.Dispatcher
+	knownUsers sync.Map // User IDs already stored, to avoid a write per request
+
+	rulePacksDir string
+	rulePacksMu  sync.Mutex // Serialises rule pack changes with their files
 }
 
 // NewHandler creates a new handler with all dependencies
-func NewHandler(aiProvider, aiAPIKey string) *Handler {
-	return &Handler{
-		csvParser:  parser.NewCSVParser(),
-		classifier: classifier.NewClassifier(aiProvider, aiAPIKey),
-		taxEngine:  tax.NewEngine(),
+func NewHandler(cfg *config.Config, repo repository.Repository) (*Handler, error) {
+	var ai *classifier.AIClassifier
+	aiConfig := classifier.AIConfig{
+		Provider:          cfg.AIProvider,
+		APIKey:            cfg.AIAPIKey,
+		Model:             cfg.AIModel,
+		BaseURL:           cfg.AIBaseURL,
+		BatchSize:         cfg.AIBatchSize,
+		Concurrency:       cfg.AIConcurrency,
+		RequestsPerSecond: cfg.AIRequestsPerSecond,
+	}
+	if aiConfig.Enabled() {
+		var err error
+		if ai, err = classifier.NewAIClassifier(aiConfig, repo); err != nil {
+			return nil, fmt.Errorf("failed to configure AI classifier: %w", err)
+		}
+	}
+
+	h := &Handler{
+		csvParser:    parser.NewCSVParser(),
+		classifier:   classifier.NewClassifier(ai, repo, classifier.NewCache(cfg.ClassificationCacheSize, cfg.ClassificationCacheTTL, repo)),
+		detector:     classifier.NewCreditDetector(),
+		taxEngine:    tax.NewEngine(),
+		repo:         repo,
+		rulePacksDir: cfg.RulePacksDir,
+	}
+	h.jobs = jobs.NewRunner(repo, jobPipeline{h}, cfg.JobWorkers, cfg.JobQueueSize)
+	h.webhooks = webhook.NewDispatcher(repo, webhook.Config{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		RetryDelay:  cfg.WebhookRetryDelay,
+		MaxDelay:    cfg.WebhookMaxRetryDelay,
+		Timeout:     cfg.WebhookTimeout,
+		Workers:     cfg.WebhookWorkers,
+		Development: cfg.Environment == "development",
+	})
+
+	thresholds := classifier.Thresholds{AI: cfg.ClassifierAIThreshold, AIVote: cfg.ClassifierAIVote, Accepted: cfg.ClassifierAccepted}
+	if err := h.classifier.SetThresholds(thresholds); err != nil {
+		return nil, fmt.Errorf("invalid classifier thresholds: %w", err)
+	}
+	if cfg.ClassifierCalibration != "" {
+		calibration, err := classifier.LoadCalibration(cfg.ClassifierCalibration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load classifier calibration: %w", err)
+		}
+		h.classifier.SetCalibration(calibration)
+	}
+	if cfg.ClassifierLocalModel != "" {
+		local, err := classifier.LoadLocalModel(cfg.ClassifierLocalModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local classifier model: %w", err)
+		}
+		h.classifier.SetLocalModel(local)
+	}
+
+	if cfg.RulePacksDir != "" {
+		packs, err := classifier.LoadRulePacks(cfg.RulePacksDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rule packs: %w", err)
+		}
+		for _, pack := range packs {
+			if err := h.classifier.Rules().AddPack(pack); err != nil {
+				return nil, fmt.Errorf("failed to load rule pack %s: %w", pack.Name, err)
+			}
+		}
 	}
+
+	return h, nil
+}
+
+// ensureUser stores the authenticated user on first use so their records can reference it
+func (h *Handler) ensureUser(ctx context.Context, userID uuid.UUID) error {
+	if _, ok := h.knownUsers.Load(userID); ok {
+		return nil
+	}
+	authUID, _ := middleware.GetUserID(ctx)
+	if err := h.repo.EnsureUser(ctx, &model.User{ID: userID, AuthUID: authUID, CreatedAt: time.Now()}); err != nil {
+		return err
+	}
+	h.knownUsers.Store(userID, true)
+	return nil
 }
 
 // HealthCheck handles health check requests
@@ -38,39 +134,62 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ParseFile handles file upload and parsing
-func (h *Handler) ParseFile(w http.ResponseWriter, r *http.Request) {
+// parsedUpload is the result of parsing an uploaded statement
+type parsedUpload struct {
+	filename     string
+	bankFormat   string
+	transactions []model.ParsedTransaction
+}
+
+// parseUploadedFile reads and parses the "file" form field, writing an error response on failure
+func (h *Handler) parseUploadedFile(w http.ResponseWriter, r *http.Request) (*parsedUpload, bool) {
 	// Limit file size to 10MB
 	r.ParseMultipartForm(10 << 20)
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
 		response.BadRequest(w, "Failed to read uploaded file")
-		return
+		return nil, false
 	}
 	defer file.Close()
 
-	// Determine file type
-	filename := header.Filename
+	parsed, err := h.parseStatement(header.Filename, file)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return nil, false
+	}
+	return parsed, true
+}
+
+// parseStatement parses a statement by its file type. Errors are fit to show the user.
+func (h *Handler) parseStatement(filename string, file io.Reader) (*parsedUpload, error) {
 	var transactions []model.ParsedTransaction
 	var bankFormat string
 
 	if strings.HasSuffix(strings.ToLower(filename), ".csv") {
 		txs, format, err := h.csvParser.Parse(file)
 		if err != nil {
-			response.BadRequest(w, "Failed to parse CSV: "+err.Error())
-			return
+			return nil, errors.New("Failed to parse CSV: " + err.Error())
 		}
 		transactions = txs
 		bankFormat = string(format)
 	} else if strings.HasSuffix(strings.ToLower(filename), ".pdf") {
 		// PDF parsing would go here
-		response.BadRequest(w, "PDF parsing not yet implemented")
-		return
+		return nil, errors.New("PDF parsing not yet implemented")
 	} else {
-		response.BadRequest(w, "Unsupported file format. Please upload CSV or PDF")
+		return nil, errors.New("Unsupported file format. Please upload CSV or PDF")
+	}
+
+	return &parsedUpload{filename: filename, bankFormat: bankFormat, transactions: transactions}, nil
+}
+
+// ParseFile handles file upload and parsing
+func (h *Handler) ParseFile(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.parseUploadedFile(w, r)
+	if !ok {
 		return
 	}
+	transactions, bankFormat, filename := upload.transactions, upload.bankFormat, upload.filename
 
 	response.Success(w, map[string]interface{}{
 		"transactions": transactions,
@@ -96,19 +215,51 @@ func (h *Handler) ClassifyTransactions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Classify transactions
-	results := h.classifier.ClassifyBatch(r.Context(), transactions)
+	for i := range transactions {
+		transactions[i] = parser.EnrichNarration(transactions[i])
+	}
+
+	results := h.classifier.ClassifyBatch(r.Context(), uuid.Nil, transactions)
+	linked := make(map[int]int)
+	for _, d := range h.detector.Detect(transactions, results, nil) {
+		results[d.Index] = d.Result
+		if d.Linked >= 0 {
+			linked[d.Index] = d.Linked
+		}
+	}
+
+	// Payments in the batch forming a regular stream are more certain
+	batch := make([]model.Transaction, len(transactions))
+	for i, tx := range transactions {
+		batch[i] = model.Transaction{
+			ID:              uuid.New(),
+			TransactionDate: tx.Date,
+			Description:     tx.Description,
+			Amount:          tx.Amount,
+			TransactionType: tx.Type,
+			Category:        results[i].Category,
+			Confidence:      results[i].Confidence,
+			Narration:       tx.Narration,
+		}
+	}
+	income.Boost(batch, results, income.FindStreams(batch))
 
 	// Build response with transactions and their classifications
 	classified := make([]map[string]interface{}, len(transactions))
 	for i, tx := range transactions {
 		classified[i] = map[string]interface{}{
-			"date":        tx.Date,
-			"description": tx.Description,
-			"amount":      tx.Amount,
-			"type":        tx.Type,
-			"category":    results[i].Category,
-			"confidence":  results[i].Confidence,
-			"method":      results[i].Method,
+			"date":         tx.Date,
+			"description":  tx.Description,
+			"amount":       tx.Amount,
+			"type":         tx.Type,
+			"channel":      tx.Channel,
+			"counterparty": tx.Counterparty,
+			"category":     results[i].Category,
+			"confidence":   results[i].Confidence,
+			"method":       results[i].Method,
+		}
+		if j, ok := linked[i]; ok {
+			classified[i]["linked_index"] = j
 		}
 	}
 
@@ -118,9 +269,18 @@ func (h *Handler) ClassifyTransactions(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// CalculateTax handles tax calculation requests
+// ListCategories handles listing the category taxonomy with each category's tax treatment
+func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, map[string]interface{}{
+		"categories": model.Taxonomy,
+		"count":      len(model.Taxonomy),
+	})
+}
+
+// CalculateTax handles tax calculation requests. Without a tax year, the
+// report is for the year of the latest dated transaction sent.
 func (h *Handler) CalculateTax(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserID(r.Context())
+	userID, ok := middleware.GetUserUUID(r.Context())
 	if !ok {
 		response.Unauthorized(w, "User not authenticated")
 		return
@@ -143,26 +303,305 @@ func (h *Handler) CalculateTax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.TaxYear == 0 {
+		for _, tx := range req.Transactions {
+			if !tx.TransactionDate.IsZero() {
+				req.TaxYear = max(req.TaxYear, tx.TransactionDate.Year())
+			}
+		}
+	}
+	// Reports are stored per year, so one is never made of every year's income
+	if req.TaxYear < 1 {
+		response.BadRequest(w, "tax_year is required unless dated transactions are sent")
+		return
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+
+	// Use the user's stored transactions for the year when none are sent
+	if len(req.Transactions) == 0 {
+		stored, err := h.yearTransactions(r.Context(), userID, req.TaxYear)
+		if err != nil {
+			response.InternalError(w, "Failed to load transactions")
+			return
+		}
+		req.Transactions = stored
+	}
+
 	// Build calculation request
 	calcReq := model.TaxCalculationRequest{
+		UserID:       userID,
 		TaxYear:      req.TaxYear,
 		Transactions: req.Transactions,
 		Reliefs:      req.Reliefs,
 	}
 
-	// Parse user ID
-	_ = userID // Will be used when saving to database
-
 	// Calculate tax
 	report, err := h.taxEngine.CalculateTax(calcReq)
 	if err != nil {
 		response.InternalError(w, "Tax calculation failed: "+err.Error())
 		return
 	}
+	addIncomeSources(report, calcReq.Transactions)
+
+	if err := h.repo.SaveReport(r.Context(), report); err != nil {
+		response.InternalError(w, "Failed to save report")
+		return
+	}
+	h.publishReport(r.Context(), report)
 
 	response.Success(w, report)
 }
 
+// yearTransactions returns the user's stored transactions dated in a tax year
+func (h *Handler) yearTransactions(ctx context.Context, userID uuid.UUID, taxYear int) ([]model.Transaction, error) {
+	stored, _, err := h.repo.ListTransactions(ctx, userID, repository.TransactionFilter{
+		From: time.Date(taxYear, time.January, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(taxYear+1, time.January, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond),
+	})
+	return stored, err
+}
+
+// CalculateTaxYears handles tax calculation for transactions spanning several years
+func (h *Handler) CalculateTaxYears(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req model.MultiYearRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+
+	for i := range req.Transactions {
+		req.Transactions[i].UserID = userID
+	}
+
+	reports, err := h.taxEngine.CalculateYears(req)
+	if err != nil {
+		response.InternalError(w, "Tax calculation failed: "+err.Error())
+		return
+	}
+
+	byYear, undated := tax.SplitByYear(req.Transactions)
+	for _, report := range reports {
+		report.UserID = userID
+		addIncomeSources(report, byYear[report.TaxYear])
+		if err := h.repo.SaveReport(r.Context(), report); err != nil {
+			response.InternalError(w, "Failed to save report")
+			return
+		}
+		h.publishReport(r.Context(), report)
+	}
+
+	response.Success(w, map[string]interface{}{
+		"reports":       reports,
+		"count":         len(reports),
+		"undated_count": len(undated),
+	})
+}
+
+// ListReports handles listing a user's stored reports by tax year
+func (h *Handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	reports, err := h.repo.ListReports(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load reports")
+		return
+	}
+
+	summaries := make([]model.TaxReportSummary, len(reports))
+	for i, report := range reports {
+		summaries[i] = tax.Summarise(report)
+	}
+
+	response.Success(w, map[string]interface{}{
+		"reports": summaries,
+		"count":   len(summaries),
+	})
+}
+
+// GetReport handles fetching a user's stored report for a tax year
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil {
+		response.BadRequest(w, "Invalid tax year")
+		return
+	}
+
+	report, err := h.repo.GetReportByYear(r.Context(), userID, year)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "No report for tax year "+strconv.Itoa(year))
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to load report")
+		return
+	}
+
+	response.Success(w, report)
+}
+
+// CompareReports handles year-over-year comparison of stored reports
+func (h *Handler) CompareReports(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	fromYear, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		response.BadRequest(w, "Invalid 'from' tax year")
+		return
+	}
+	toYear, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		response.BadRequest(w, "Invalid 'to' tax year")
+		return
+	}
+
+	reports := make([]*model.TaxReport, 2)
+	for i, year := range []int{fromYear, toYear} {
+		report, err := h.repo.GetReportByYear(r.Context(), userID, year)
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "No report for tax year "+strconv.Itoa(year))
+			return
+		}
+		if err != nil {
+			response.InternalError(w, "Failed to load report")
+			return
+		}
+		reports[i] = report
+	}
+
+	response.Success(w, tax.CompareYears(reports[0], reports[1]))
+}
+
+// SimulateTax handles what-if simulations against a base calculation
+func (h *Handler) SimulateTax(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserID(r.Context()); !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req model.SimulationRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if len(req.Scenarios) == 0 {
+		response.BadRequest(w, "At least one scenario is required")
+		return
+	}
+
+	result, err := h.taxEngine.Simulate(req)
+	if err != nil {
+		response.BadRequest(w, "Simulation failed: "+err.Error())
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// RecommendReliefs handles requests for tax-saving recommendations
+func (h *Handler) RecommendReliefs(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserID(r.Context()); !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req model.TaxCalculationRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	recommendations, err := h.taxEngine.Recommend(req)
+	if err != nil {
+		response.InternalError(w, "Recommendation failed: "+err.Error())
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"recommendations": recommendations,
+		"count":           len(recommendations),
+	})
+}
+
+// EstimateProvisionalTax handles monthly and quarterly provisional tax estimates
+func (h *Handler) EstimateProvisionalTax(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.GetUserID(r.Context()); !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req model.ProvisionalRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	estimate, err := h.taxEngine.EstimateProvisional(req)
+	if err != nil {
+		response.BadRequest(w, "Provisional estimate failed: "+err.Error())
+		return
+	}
+
+	response.Success(w, estimate)
+}
+
 // QuickCalculatePIT handles simple PIT calculation
 func (h *Handler) QuickCalculatePIT(w http.ResponseWriter, r *http.Request) {
 	var req struct {
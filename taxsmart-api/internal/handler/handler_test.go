@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+)
+
+// testAuthUID is the user every test request is authenticated as
+const testAuthUID = "uid-test"
+
+// testUserID returns the ID the handlers derive for testAuthUID
+func testUserID() uuid.UUID {
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, testAuthUID)
+	id, _ := middleware.GetUserUUID(ctx)
+	return id
+}
+
+// newTestRouter routes the handlers under test as the server does, for an
+// authenticated test user
+func newTestRouter(h *Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, testAuthUID)))
+		})
+	})
+	r.Get("/transactions", h.ListTransactions)
+	r.Post("/transactions", h.CreateTransaction)
+	r.Post("/transactions/bulk-update", h.BulkUpdateTransactions)
+	r.Patch("/transactions/{id}", h.UpdateTransaction)
+	r.Get("/transactions/{id}/history", h.GetTransactionHistory)
+	r.Post("/tax/calculate", h.CalculateTax)
+	r.Get("/tax/reports", h.ListReports)
+	return r
+}
+
+// request sends a request with an optional JSON body, returning the status and
+// the response's data, or its error message when it failed
+func request(t *testing.T, router http.Handler, method, target string, body any) (int, json.RawMessage, string) {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, target, &payload))
+
+	var resp struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: decoding response %q: %v", method, target, rec.Body.String(), err)
+	}
+	return rec.Code, resp.Data, resp.Error
+}
+
+func TestCalculateTax_TaxYear(t *testing.T) {
+	salary := func(date string) map[string]any {
+		return map[string]any{"transaction_date": date + "T00:00:00Z", "amount": 1_000_000, "transaction_type": "credit", "category": model.CategoryEmployment}
+	}
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+		year   int
+	}{
+		{"defaults to the latest dated year", map[string]any{"transactions": []any{salary("2025-06-28"), salary("2026-01-28")}}, http.StatusOK, 2026},
+		{"given year", map[string]any{"tax_year": 2025, "transactions": []any{salary("2025-06-28"), salary("2026-01-28")}}, http.StatusOK, 2025},
+		{"nothing dated", map[string]any{"transactions": []any{map[string]any{"amount": 1000, "transaction_type": "credit"}}}, http.StatusBadRequest, 0},
+		{"no transactions or year", map[string]any{}, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.New()
+			router := newTestRouter(newTestHandler(repo))
+
+			status, data, message := request(t, router, http.MethodPost, "/tax/calculate", tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d (%s), want %d", status, message, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				if reports, _ := repo.ListReports(context.Background(), testUserID()); len(reports) != 0 {
+					t.Errorf("stored %d reports, want none", len(reports))
+				}
+				return
+			}
+
+			var report model.TaxReport
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("decoding report: %v", err)
+			}
+			// Only the report's own year is counted
+			if report.TaxYear != tt.year || report.TotalIncome != 1_000_000 {
+				t.Errorf("report for %d with income %.2f, want %d with 1,000,000", report.TaxYear, report.TotalIncome, tt.year)
+			}
+			if _, err := repo.GetReportByYear(context.Background(), testUserID(), tt.year); err != nil {
+				t.Errorf("report not stored for %d: %v", tt.year, err)
+			}
+		})
+	}
+}
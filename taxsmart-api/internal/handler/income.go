@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/income"
+	"github.com/taxsmart/taxsmart-api/internal/service/tax"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// ListIncomeSources handles profiling who pays the user, with the regular
+// income streams, such as salaries and retainers, their payments form
+func (h *Handler) ListIncomeSources(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	filter, _, _, err := parseTransactionFilter(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	// Streams need every payment in the range, not one page
+	filter.Limit, filter.Offset = 0, 0
+
+	transactions, _, err := h.repo.ListTransactions(r.Context(), userID, filter)
+	if err != nil {
+		response.InternalError(w, "Failed to load transactions")
+		return
+	}
+
+	streams := income.FindStreams(transactions)
+	sources := income.Sources(transactions, streams)
+	response.Success(w, map[string]interface{}{
+		"sources": sources,
+		"streams": streams,
+		"count":   len(sources),
+	})
+}
+
+// addIncomeSources adds who paid the report's income to its breakdown, from
+// the transactions it was calculated from
+func addIncomeSources(report *model.TaxReport, transactions []model.Transaction) {
+	var year []model.Transaction
+	for _, tx := range transactions {
+		if tax.InTaxYear(tx, report.TaxYear) {
+			year = append(year, tx)
+		}
+	}
+	report.Breakdown.IncomeSources = income.Sources(year, income.FindStreams(year))
+}
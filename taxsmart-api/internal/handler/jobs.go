@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/service/jobs"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// jobHeartbeat is how often an idle job event stream is kept alive
+const jobHeartbeat = 15 * time.Second
+
+// StartJobs starts the background job workers, resuming jobs left
+// unfinished by the last run. Workers stop when ctx is done.
+func (h *Handler) StartJobs(ctx context.Context) error {
+	return h.jobs.Start(ctx)
+}
+
+// jobPipeline runs a job's stages with the same parsing, classification and
+// tax calculation as the synchronous endpoints
+type jobPipeline struct {
+	h *Handler
+}
+
+// Parse parses the job's uploaded file, loading it from the stored job when
+// the job was queued without it
+func (p jobPipeline) Parse(ctx context.Context, job *model.Job) ([]model.ParsedTransaction, error) {
+	input := job.Input
+	if input == nil {
+		stored, err := p.h.repo.GetJob(ctx, job.UserID, job.ID)
+		if err != nil {
+			return nil, errors.New("Failed to load uploaded file")
+		}
+		input = stored.Input
+	}
+
+	parsed, err := p.h.parseStatement(job.Filename, bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	job.BankFormat = parsed.bankFormat
+	return parsed.transactions, nil
+}
+
+// Classify classifies the transactions and stores them as an upload with the
+// job's ID, defaulting the job's tax year to that of the latest dated
+// transaction
+func (p jobPipeline) Classify(ctx context.Context, job *model.Job, transactions []model.ParsedTransaction, progress func(done int)) error {
+	if job.TaxYear == 0 {
+		for _, tx := range transactions {
+			if !tx.Date.IsZero() {
+				job.TaxYear = max(job.TaxYear, tx.Date.Year())
+			}
+		}
+	}
+
+	// An upload is stored with its transactions at once, so a job interrupted
+	// after storing it has nothing left to import
+	if _, err := p.h.repo.GetUpload(ctx, job.UserID, job.ID); err == nil {
+		job.UploadID = &job.ID
+		return nil
+	}
+
+	parsed := &parsedUpload{filename: job.Filename, bankFormat: job.BankFormat, transactions: transactions}
+	imported, err := p.h.importUpload(ctx, job.UserID, job.ID, parsed, progress)
+	if err != nil {
+		var failed *importError
+		if errors.As(err, &failed) {
+			return errors.New(failed.message)
+		}
+		return err
+	}
+	job.UploadID = &imported.upload.ID
+	return nil
+}
+
+// Calculate calculates and stores the report for the job's tax year from all
+// of the user's transactions in that year
+func (p jobPipeline) Calculate(ctx context.Context, job *model.Job) error {
+	if job.TaxYear < 1 {
+		return nil // Nothing dated to calculate
+	}
+	transactions, err := p.h.yearTransactions(ctx, job.UserID, job.TaxYear)
+	if err != nil {
+		return errors.New("Failed to load transactions")
+	}
+
+	report, err := p.h.taxEngine.CalculateTax(model.TaxCalculationRequest{
+		UserID:       job.UserID,
+		TaxYear:      job.TaxYear,
+		Transactions: transactions,
+		Reliefs:      job.Reliefs,
+	})
+	if err != nil {
+		return errors.New("Tax calculation failed: " + err.Error())
+	}
+	addIncomeSources(report, transactions)
+	if err := p.h.repo.SaveReport(ctx, report); err != nil {
+		return errors.New("Failed to save report")
+	}
+	job.ReportID = &report.ID
+	p.h.publishReport(ctx, report)
+	return nil
+}
+
+// Finished notifies the user's webhooks that the job has finished
+func (p jobPipeline) Finished(ctx context.Context, job model.Job) {
+	p.h.publish(ctx, job.UserID, model.EventJobFinished, map[string]interface{}{"job": job})
+}
+
+// SubmitJob handles queueing an uploaded statement to be parsed, classified,
+// stored and used to calculate the tax year's report in the background
+func (h *Handler) SubmitJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	// Limit file size to 10MB
+	r.ParseMultipartForm(10 << 20)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.BadRequest(w, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+	input, err := io.ReadAll(file)
+	if err != nil {
+		response.BadRequest(w, "Failed to read uploaded file")
+		return
+	}
+
+	job := &model.Job{UserID: userID, Filename: header.Filename, Input: input}
+	if value := r.FormValue("tax_year"); value != "" {
+		if job.TaxYear, err = strconv.Atoi(value); err != nil || job.TaxYear < 1 {
+			response.BadRequest(w, "Invalid tax year")
+			return
+		}
+	}
+	if value := r.FormValue("reliefs"); value != "" {
+		if err := json.Unmarshal([]byte(value), &job.Reliefs); err != nil {
+			response.BadRequest(w, "Invalid reliefs")
+			return
+		}
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+
+	err = h.jobs.Submit(r.Context(), job)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		response.Error(w, http.StatusServiceUnavailable, "Too many jobs are waiting, please try again later")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to queue job")
+		return
+	}
+
+	response.Created(w, map[string]interface{}{"job": job})
+}
+
+// ListJobs handles listing the user's background jobs, newest first
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	list, err := h.repo.ListJobs(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load jobs")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"jobs":  list,
+		"count": len(list),
+	})
+}
+
+// GetJob handles polling a background job's status and progress
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	job, ok := h.loadJob(w, r, userID)
+	if !ok {
+		return
+	}
+
+	response.Success(w, map[string]interface{}{"job": job})
+}
+
+// CancelJob handles stopping a queued or running background job
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobs.Cancel(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Job not found")
+		return
+	}
+	if errors.Is(err, jobs.ErrJobFinished) {
+		response.Error(w, http.StatusConflict, "Job has already finished")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to cancel job")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{"job": job})
+}
+
+// StreamJobEvents handles following a background job as Server-Sent Events:
+// a "progress" event with the job's state now and after each step, then a
+// "done" event with its final state
+func (h *Handler) StreamJobEvents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.InternalError(w, "Streaming not supported")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid job ID")
+		return
+	}
+	// Watch before loading, so no step between the two is missed
+	updates, stop := h.jobs.Watch(id)
+	defer stop()
+
+	job, ok := h.loadJob(w, r, userID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, job *model.Job) {
+		data, _ := json.Marshal(job)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+	if job.Finished() {
+		send("done", job)
+		return
+	}
+	send("progress", job)
+
+	heartbeat := time.NewTicker(jobHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case update, open := <-updates:
+			if !open {
+				final, err := h.repo.GetJob(r.Context(), userID, id)
+				if err != nil {
+					return
+				}
+				send("done", final)
+				return
+			}
+			send("progress", &update)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// loadJob fetches the job named in the URL, writing an error response on failure
+func (h *Handler) loadJob(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*model.Job, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid job ID")
+		return nil, false
+	}
+
+	job, err := h.repo.GetJob(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Job not found")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to load job")
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+	"github.com/taxsmart/taxsmart-api/internal/service/jobs"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
+	"github.com/taxsmart/taxsmart-api/internal/service/tax"
+	"github.com/taxsmart/taxsmart-api/internal/service/webhook"
+)
+
+const statement = `Date,Description,Credit,Debit
+2026-01-25,SALARY ACME LTD,500000,
+2026-02-25,SALARY ACME LTD,500000,
+,OPENING BALANCE,1000,
+`
+
+// newTestHandler creates a handler on a memory store without an AI provider
+func newTestHandler(repo *memory.Store) *Handler {
+	h := &Handler{
+		csvParser:  parser.NewCSVParser(),
+		classifier: classifier.NewClassifier(nil, repo, nil),
+		detector:   classifier.NewCreditDetector(),
+		taxEngine:  tax.NewEngine(),
+		repo:       repo,
+		webhooks:   webhook.NewDispatcher(repo, webhook.Config{}),
+	}
+	h.jobs = jobs.NewRunner(repo, jobPipeline{h}, 1, 10)
+	return h
+}
+
+// waitJob polls until the job has finished
+func waitJob(t *testing.T, repo *memory.Store, job *model.Job) *model.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		got, err := repo.GetJob(context.Background(), job.UserID, job.ID)
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		if got.Finished() {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", job.ID)
+	return nil
+}
+
+func TestJobPipeline_Resume(t *testing.T) {
+	tests := []struct {
+		name     string
+		imported bool // The upload was stored before the job was interrupted
+	}{
+		{"interrupted while classifying", false},
+		{"interrupted after storing the upload", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			repo := memory.New()
+			h := newTestHandler(repo)
+
+			user := &model.User{ID: uuid.New(), AuthUID: "uid", CreatedAt: time.Now()}
+			if err := repo.EnsureUser(ctx, user); err != nil {
+				t.Fatalf("EnsureUser failed: %v", err)
+			}
+			job := &model.Job{
+				ID: uuid.New(), UserID: user.ID, Status: model.JobRunning, Stage: model.StageClassify,
+				Filename: "jan.csv", Input: []byte(statement), CreatedAt: time.Now(),
+			}
+			if err := repo.CreateJob(ctx, job); err != nil {
+				t.Fatalf("CreateJob failed: %v", err)
+			}
+			if tt.imported {
+				parsed, err := h.parseStatement(job.Filename, strings.NewReader(statement))
+				if err != nil {
+					t.Fatalf("parseStatement failed: %v", err)
+				}
+				if _, err := h.importUpload(ctx, user.ID, job.ID, parsed, nil); err != nil {
+					t.Fatalf("importUpload failed: %v", err)
+				}
+			}
+
+			if err := h.StartJobs(ctx); err != nil {
+				t.Fatalf("StartJobs failed: %v", err)
+			}
+			got := waitJob(t, repo, job)
+			if got.Status != model.JobSucceeded || got.UploadID == nil || got.ReportID == nil {
+				t.Fatalf("resumed job = %+v, want succeeded with an upload and report", got)
+			}
+			if got.TaxYear != 2026 {
+				t.Errorf("TaxYear = %d, want 2026 from the dated rows", got.TaxYear)
+			}
+
+			stored, total, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{UploadID: job.ID})
+			if err != nil || total != 3 {
+				t.Fatalf("upload has %d transactions, %v, want the statement's 3 stored once", len(stored), err)
+			}
+			report, err := repo.GetReportByYear(ctx, user.ID, 2026)
+			if err != nil || report.EmploymentIncome != 1_000_000 {
+				t.Errorf("report = %+v, %v, want both salaries counted", report, err)
+			}
+		})
+	}
+}
+
+func TestJobPipeline_UndatedStatement(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := memory.New()
+	h := newTestHandler(repo)
+
+	user := &model.User{ID: uuid.New(), AuthUID: "uid", CreatedAt: time.Now()}
+	if err := repo.EnsureUser(ctx, user); err != nil {
+		t.Fatalf("EnsureUser failed: %v", err)
+	}
+	if err := h.StartJobs(ctx); err != nil {
+		t.Fatalf("StartJobs failed: %v", err)
+	}
+	job := &model.Job{UserID: user.ID, Filename: "undated.csv", Input: []byte("Date,Description,Credit,Debit\n,OPENING BALANCE,1000,\n")}
+	if err := h.jobs.Submit(ctx, job); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	// Undated rows leave the tax year unset rather than making it year 1
+	got := waitJob(t, repo, job)
+	if got.Status != model.JobSucceeded || got.TaxYear != 0 || got.ReportID != nil {
+		t.Errorf("job = %+v, want succeeded without a tax year or report", got)
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// ListUserRules handles listing the classification rules learned from the user's corrections
+func (h *Handler) ListUserRules(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	rules, err := h.repo.ListUserRules(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load learned rules")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// DeleteUserRule handles forgetting a learned classification rule
+func (h *Handler) DeleteUserRule(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid rule ID")
+		return
+	}
+
+	err = h.repo.DeleteUserRule(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Rule not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to delete rule")
+		return
+	}
+
+	response.Success(w, map[string]string{"id": id.String()})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// Bounds for AI audit listings
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// GetSettings handles fetching the user's preferences
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+	user, err := h.repo.GetUser(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load settings")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"external_ai": !user.ExternalAIDisabled,
+	})
+}
+
+// UpdateSettings handles changing the user's preferences. Turning
+// external_ai off keeps the user's transactions away from third-party AI;
+// they are classified by rules only.
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		ExternalAI *bool `json:"external_ai"`
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+	user, err := h.repo.GetUser(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load settings")
+		return
+	}
+
+	if req.ExternalAI != nil {
+		user.ExternalAIDisabled = !*req.ExternalAI
+	}
+	if err := h.repo.UpdateUserSettings(r.Context(), user); err != nil {
+		response.InternalError(w, "Failed to save settings")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"external_ai": !user.ExternalAIDisabled,
+	})
+}
+
+// ListMyAIAudit handles listing what was sent to the AI provider for the user
+func (h *Handler) ListMyAIAudit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+	h.listAIAudit(w, r, userID)
+}
+
+// ListAIAudit handles listing what was sent to the AI provider, optionally for one user
+func (h *Handler) ListAIAudit(w http.ResponseWriter, r *http.Request) {
+	var userID uuid.UUID
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			response.BadRequest(w, "Invalid user_id")
+			return
+		}
+		userID = id
+	}
+	h.listAIAudit(w, r, userID)
+}
+
+// listAIAudit writes the newest AI audit entries, honouring the limit query parameter
+func (h *Handler) listAIAudit(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	limit := defaultAuditLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			response.BadRequest(w, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.repo.ListAIAudit(r.Context(), repository.AIAuditFilter{UserID: userID, Limit: limit})
+	if err != nil {
+		response.InternalError(w, "Failed to load AI audit log")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
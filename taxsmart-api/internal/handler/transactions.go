@@ -0,0 +1,510 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	maxBulkUpdate   = 1000
+)
+
+// ListTransactions handles listing, filtering and paging through the user's transactions
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	filter, page, pageSize, err := parseTransactionFilter(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	transactions, total, err := h.repo.ListTransactions(r.Context(), userID, filter)
+	if err != nil {
+		response.InternalError(w, "Failed to load transactions")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"transactions": transactions,
+		"count":        len(transactions),
+		"total":        total,
+		"page":         page,
+		"page_size":    pageSize,
+	})
+}
+
+// ListCounterparties handles grouping the user's transactions by counterparty
+func (h *Handler) ListCounterparties(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	filter, _, _, err := parseTransactionFilter(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	counterparties, err := h.repo.ListCounterparties(r.Context(), userID, filter)
+	if err != nil {
+		response.InternalError(w, "Failed to load counterparties")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"counterparties": counterparties,
+		"count":          len(counterparties),
+	})
+}
+
+// parseTransactionFilter reads filter and paging parameters from the query string
+func parseTransactionFilter(r *http.Request) (repository.TransactionFilter, int, int, error) {
+	var filter repository.TransactionFilter
+	query := r.URL.Query()
+
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid 'from' date, expected YYYY-MM-DD")
+		}
+		filter.From = from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid 'to' date, expected YYYY-MM-DD")
+		}
+		filter.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	if v := query.Get("category"); v != "" {
+		filter.Category = model.Category(v)
+		if !filter.Category.IsValid() {
+			return filter, 0, 0, fmt.Errorf("unknown category %q", v)
+		}
+	}
+	if v := query.Get("counterparty"); v != "" {
+		filter.Counterparty = parser.NormaliseCounterparty(v)
+	}
+	if v := query.Get("upload_id"); v != "" {
+		uploadID, err := uuid.Parse(v)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid upload_id")
+		}
+		filter.UploadID = uploadID
+	}
+	if v := query.Get("min_confidence"); v != "" {
+		min, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid min_confidence")
+		}
+		filter.MinConfidence = &min
+	}
+	if v := query.Get("max_confidence"); v != "" {
+		max, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid max_confidence")
+		}
+		filter.MaxConfidence = &max
+	}
+
+	page := 1
+	if v := query.Get("page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 {
+			return filter, 0, 0, fmt.Errorf("invalid page")
+		}
+		page = p
+	}
+	pageSize := defaultPageSize
+	if v := query.Get("page_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 || size > maxPageSize {
+			return filter, 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+		pageSize = size
+	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	return filter, page, pageSize, nil
+}
+
+// CreateTransaction handles adding a manual transaction
+func (h *Handler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var tx model.Transaction
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &tx); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if tx.Category == "" {
+		tx.Category = model.CategoryUncategorized
+	}
+	if err := validateTransaction(&tx); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+
+	tx.ID = uuid.New()
+	tx.UploadID = uuid.Nil
+	tx.UserID = userID
+	tx.Confidence = 1
+	tx.IsManual = true
+	tx.CreatedAt = time.Now()
+	tx.Narration = parser.ParseNarration(tx.Description)
+
+	if err := h.repo.CreateTransactions(r.Context(), []model.Transaction{tx}); err != nil {
+		response.InternalError(w, "Failed to save transaction")
+		return
+	}
+
+	response.Created(w, tx)
+}
+
+// GetTransaction handles fetching a single transaction
+func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	tx, ok := h.loadTransaction(w, r, userID)
+	if !ok {
+		return
+	}
+
+	response.Success(w, tx)
+}
+
+// UpdateTransaction handles editing a single transaction
+func (h *Handler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var patch model.TransactionPatch
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &patch); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	tx, ok := h.loadTransaction(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.applyEdits(r, []*model.Transaction{tx}, patch); err != nil {
+		writeEditError(w, err)
+		return
+	}
+
+	response.Success(w, tx)
+}
+
+// BulkUpdateTransactions handles applying the same edit to many transactions
+func (h *Handler) BulkUpdateTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		IDs     []uuid.UUID            `json:"ids"`
+		Changes model.TransactionPatch `json:"changes"`
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		response.BadRequest(w, "At least one transaction ID is required")
+		return
+	}
+	if len(req.IDs) > maxBulkUpdate {
+		response.BadRequest(w, fmt.Sprintf("At most %d transactions can be updated at once", maxBulkUpdate))
+		return
+	}
+
+	transactions := make([]*model.Transaction, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		tx, err := h.repo.GetTransaction(r.Context(), userID, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			response.NotFound(w, "Transaction not found: "+id.String())
+			return
+		}
+		if err != nil {
+			response.InternalError(w, "Failed to load transaction")
+			return
+		}
+		transactions = append(transactions, tx)
+	}
+
+	if err := h.applyEdits(r, transactions, req.Changes); err != nil {
+		writeEditError(w, err)
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"transactions": transactions,
+		"count":        len(transactions),
+	})
+}
+
+// DeleteTransaction handles removing a transaction
+func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid transaction ID")
+		return
+	}
+
+	err = h.repo.DeleteTransaction(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Transaction not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to delete transaction")
+		return
+	}
+
+	response.Success(w, map[string]string{"id": id.String()})
+}
+
+// GetTransactionHistory handles fetching a transaction's edit and classification history
+func (h *Handler) GetTransactionHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	tx, ok := h.loadTransaction(w, r, userID)
+	if !ok {
+		return
+	}
+
+	edits, err := h.repo.ListTransactionEdits(r.Context(), userID, tx.ID)
+	if err != nil {
+		response.InternalError(w, "Failed to load edit history")
+		return
+	}
+	classifications, err := h.repo.ListClassifications(r.Context(), userID, tx.ID)
+	if err != nil {
+		response.InternalError(w, "Failed to load classification history")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"transaction":     tx,
+		"edits":           edits,
+		"classifications": classifications,
+	})
+}
+
+// loadTransaction fetches the transaction named in the URL, writing an error response on failure
+func (h *Handler) loadTransaction(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*model.Transaction, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid transaction ID")
+		return nil, false
+	}
+
+	tx, err := h.repo.GetTransaction(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Transaction not found")
+		return nil, false
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to load transaction")
+		return nil, false
+	}
+
+	return tx, true
+}
+
+// errInvalidTransaction marks input rejected by validation rather than storage failures
+var errInvalidTransaction = errors.New("invalid transaction")
+
+// writeEditError maps an applyEdits error to a response
+func writeEditError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidTransaction) {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	response.InternalError(w, "Failed to update transaction")
+}
+
+// applyEdits applies a patch to each transaction, marks the changed ones as
+// manual and stores them together with an edit history entry per changed
+// field. Category corrections also teach the user's classifier a rule for the
+// counterparty. Transactions the patch leaves unchanged are not written.
+func (h *Handler) applyEdits(r *http.Request, transactions []*model.Transaction, patch model.TransactionPatch) error {
+	now := time.Now()
+	var changed []model.Transaction
+	var edits []model.TransactionEdit
+	var classifications []model.Classification
+	var userRules []model.UserRule
+
+	for _, tx := range transactions {
+		changes := applyPatch(tx, patch)
+		if len(changes) == 0 {
+			continue
+		}
+		if err := validateTransaction(tx); err != nil {
+			return err
+		}
+
+		tx.IsManual = true
+		for _, change := range changes {
+			edits = append(edits, model.TransactionEdit{
+				ID:            uuid.New(),
+				TransactionID: tx.ID,
+				UserID:        tx.UserID,
+				Field:         change.field,
+				OldValue:      change.oldValue,
+				NewValue:      change.newValue,
+				CreatedAt:     now,
+			})
+			if change.field == "category" {
+				tx.Confidence = 1
+				classifications = append(classifications, model.Classification{
+					ID:            uuid.New(),
+					TransactionID: tx.ID,
+					UserID:        tx.UserID,
+					Category:      tx.Category,
+					Confidence:    1,
+					Method:        "manual",
+					CreatedAt:     now,
+				})
+				if rule, ok := classifier.LearnRule(*tx, now); ok {
+					userRules = append(userRules, rule)
+				}
+			}
+		}
+		changed = append(changed, *tx)
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+	return h.repo.EditTransactions(r.Context(), changed, edits, classifications, userRules)
+}
+
+// fieldChange describes one field changed by a patch
+type fieldChange struct {
+	field    string
+	oldValue string
+	newValue string
+}
+
+// applyPatch sets the patched fields on the transaction and returns the fields that changed
+func applyPatch(tx *model.Transaction, patch model.TransactionPatch) []fieldChange {
+	var changes []fieldChange
+
+	if patch.TransactionDate != nil && !patch.TransactionDate.Equal(tx.TransactionDate) {
+		changes = append(changes, fieldChange{"transaction_date",
+			tx.TransactionDate.Format(time.RFC3339), patch.TransactionDate.Format(time.RFC3339)})
+		tx.TransactionDate = *patch.TransactionDate
+	}
+	if patch.Description != nil && *patch.Description != tx.Description {
+		changes = append(changes, fieldChange{"description", tx.Description, *patch.Description})
+		tx.Description = *patch.Description
+		tx.Narration = parser.ParseNarration(tx.Description)
+	}
+	if patch.Amount != nil && *patch.Amount != tx.Amount {
+		changes = append(changes, fieldChange{"amount",
+			strconv.FormatFloat(tx.Amount, 'f', 2, 64), strconv.FormatFloat(*patch.Amount, 'f', 2, 64)})
+		tx.Amount = *patch.Amount
+	}
+	if patch.TransactionType != nil && *patch.TransactionType != tx.TransactionType {
+		changes = append(changes, fieldChange{"transaction_type", tx.TransactionType, *patch.TransactionType})
+		tx.TransactionType = *patch.TransactionType
+	}
+	if patch.Category != nil && *patch.Category != tx.Category {
+		changes = append(changes, fieldChange{"category", string(tx.Category), string(*patch.Category)})
+		tx.Category = *patch.Category
+	}
+
+	return changes
+}
+
+// validateTransaction checks user-supplied transaction fields
+func validateTransaction(tx *model.Transaction) error {
+	if tx.TransactionType != "credit" && tx.TransactionType != "debit" {
+		return fmt.Errorf("%w: transaction_type must be 'credit' or 'debit'", errInvalidTransaction)
+	}
+	if tx.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", errInvalidTransaction)
+	}
+	if !tx.Category.IsValid() {
+		return fmt.Errorf("%w: unknown category %q", errInvalidTransaction, tx.Category)
+	}
+	return nil
+}
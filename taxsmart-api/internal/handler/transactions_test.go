@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+)
+
+// seedTransactions stores transactions for the test user, returning them
+func seedTransactions(t *testing.T, repo *memory.Store, txs ...model.Transaction) []model.Transaction {
+	t.Helper()
+	ctx := context.Background()
+	userID := testUserID()
+	if err := repo.EnsureUser(ctx, &model.User{ID: userID, AuthUID: testAuthUID, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("EnsureUser failed: %v", err)
+	}
+	for i := range txs {
+		txs[i].ID, txs[i].UserID, txs[i].CreatedAt = uuid.New(), userID, time.Now()
+	}
+	if err := repo.CreateTransactions(ctx, txs); err != nil {
+		t.Fatalf("CreateTransactions failed: %v", err)
+	}
+	return txs
+}
+
+// rent is an uncorrected rent payment
+func rent(amount float64) model.Transaction {
+	return model.Transaction{
+		TransactionDate: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Description: "TRF TO LANDLORD RENT",
+		Amount: amount, TransactionType: "debit", Category: model.CategoryExpense, Confidence: 0.6,
+		Narration: model.Narration{Counterparty: "LANDLORD"},
+	}
+}
+
+func TestParseTransactionFilter(t *testing.T) {
+	tests := []struct {
+		query  string
+		valid  bool
+		limit  int
+		offset int
+	}{
+		{"", true, defaultPageSize, 0},
+		{"from=2026-01-01&to=2026-01-31&category=employment_income&min_confidence=0.5&page=3&page_size=20", true, 20, 40},
+		{"from=01/01/2026", false, 0, 0},
+		{"to=2026-13-01", false, 0, 0},
+		{"category=salary", false, 0, 0},
+		{"upload_id=abc", false, 0, 0},
+		{"max_confidence=high", false, 0, 0},
+		{"page=0", false, 0, 0},
+		{"page_size=0", false, 0, 0},
+		{fmt.Sprintf("page_size=%d", maxPageSize+1), false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		filter, _, _, err := parseTransactionFilter(httptest.NewRequest(http.MethodGet, "/transactions?"+tt.query, nil))
+		if (err == nil) != tt.valid {
+			t.Errorf("parseTransactionFilter(%q) error = %v, want valid %v", tt.query, err, tt.valid)
+			continue
+		}
+		if tt.valid && (filter.Limit != tt.limit || filter.Offset != tt.offset) {
+			t.Errorf("parseTransactionFilter(%q) pages by %d from %d, want %d from %d", tt.query, filter.Limit, filter.Offset, tt.limit, tt.offset)
+		}
+	}
+
+	// The end date includes the whole day
+	filter, _, _, _ := parseTransactionFilter(httptest.NewRequest(http.MethodGet, "/transactions?to=2026-01-31", nil))
+	if lastMoment := time.Date(2026, time.January, 31, 23, 59, 59, 0, time.UTC); filter.To.Before(lastMoment) {
+		t.Errorf("To = %s, want the end of 2026-01-31", filter.To)
+	}
+
+	router := newTestRouter(newTestHandler(memory.New()))
+	if status, _, message := request(t, router, http.MethodGet, "/transactions?page=-1", nil); status != http.StatusBadRequest {
+		t.Errorf("listing an invalid page = %d (%s), want 400", status, message)
+	}
+}
+
+// history is the edit and classification history of a transaction
+type history struct {
+	Transaction     model.Transaction       `json:"transaction"`
+	Edits           []model.TransactionEdit `json:"edits"`
+	Classifications []model.Classification  `json:"classifications"`
+}
+
+// getHistory fetches a transaction's history through the API
+func getHistory(t *testing.T, router http.Handler, id uuid.UUID) history {
+	t.Helper()
+	status, data, message := request(t, router, http.MethodGet, "/transactions/"+id.String()+"/history", nil)
+	if status != http.StatusOK {
+		t.Fatalf("history = %d (%s)", status, message)
+	}
+	var got history
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decoding history: %v", err)
+	}
+	return got
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  map[string]any
+		status int
+		fields []string // Fields in the edit history, in order
+	}{
+		{"changes each field once", map[string]any{"amount": 200_000, "category": model.CategoryRentExpense}, http.StatusOK, []string{"amount", "category"}},
+		{"unchanged values", map[string]any{"amount": 150_000, "category": model.CategoryExpense}, http.StatusOK, nil},
+		{"invalid amount", map[string]any{"amount": -5}, http.StatusBadRequest, nil},
+		{"unknown category", map[string]any{"category": "salary"}, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.New()
+			router := newTestRouter(newTestHandler(repo))
+			tx := seedTransactions(t, repo, rent(150_000))[0]
+
+			status, _, message := request(t, router, http.MethodPatch, "/transactions/"+tx.ID.String(), tt.patch)
+			if status != tt.status {
+				t.Fatalf("status = %d (%s), want %d", status, message, tt.status)
+			}
+
+			got := getHistory(t, router, tx.ID)
+			if len(got.Edits) != len(tt.fields) {
+				t.Fatalf("edit history = %+v, want edits to %v", got.Edits, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if got.Edits[i].Field != field {
+					t.Errorf("edit %d changed %s, want %s", i, got.Edits[i].Field, field)
+				}
+			}
+			if edited := len(tt.fields) > 0; got.Transaction.IsManual != edited {
+				t.Errorf("IsManual = %v, want %v", got.Transaction.IsManual, edited)
+			}
+			if len(tt.fields) == 0 && got.Transaction.Amount != tx.Amount {
+				t.Errorf("amount = %.2f, want the original %.2f", got.Transaction.Amount, tx.Amount)
+			}
+		})
+	}
+
+	// A category correction is recorded as a manual classification and learned
+	repo := memory.New()
+	router := newTestRouter(newTestHandler(repo))
+	tx := seedTransactions(t, repo, rent(150_000))[0]
+	request(t, router, http.MethodPatch, "/transactions/"+tx.ID.String(), map[string]any{"category": model.CategoryRentExpense})
+	got := getHistory(t, router, tx.ID)
+	if len(got.Classifications) != 1 || got.Classifications[0].Method != "manual" || got.Transaction.Confidence != 1 {
+		t.Errorf("classification history = %+v with confidence %.2f, want one manual classification", got.Classifications, got.Transaction.Confidence)
+	}
+	if rules, err := repo.ListUserRules(context.Background(), testUserID()); err != nil || len(rules) != 1 || rules[0].Category != model.CategoryRentExpense {
+		t.Errorf("learned rules = %+v, %v, want the correction learned", rules, err)
+	}
+}
+
+func TestBulkUpdateTransactions(t *testing.T) {
+	repo := memory.New()
+	router := newTestRouter(newTestHandler(repo))
+	corrected := rent(100_000)
+	corrected.Category = model.CategoryRentExpense
+	txs := seedTransactions(t, repo, rent(150_000), corrected, rent(90_000))
+	ids := []uuid.UUID{txs[0].ID, txs[1].ID, txs[2].ID}
+
+	tooMany := make([]uuid.UUID, maxBulkUpdate+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.New()
+	}
+	rejected := []struct {
+		name   string
+		body   map[string]any
+		status int
+	}{
+		{"no IDs", map[string]any{"ids": []uuid.UUID{}, "changes": map[string]any{"category": model.CategoryRentExpense}}, http.StatusBadRequest},
+		{"over the limit", map[string]any{"ids": tooMany, "changes": map[string]any{"category": model.CategoryRentExpense}}, http.StatusBadRequest},
+		{"unknown ID", map[string]any{"ids": append([]uuid.UUID{uuid.New()}, ids...), "changes": map[string]any{"category": model.CategoryRentExpense}}, http.StatusNotFound},
+		{"invalid change", map[string]any{"ids": ids, "changes": map[string]any{"transaction_type": "transfer"}}, http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		if status, _, message := request(t, router, http.MethodPost, "/transactions/bulk-update", tt.body); status != tt.status {
+			t.Errorf("%s: status = %d (%s), want %d", tt.name, status, message, tt.status)
+		}
+	}
+	for _, tx := range txs {
+		if got := getHistory(t, router, tx.ID); len(got.Edits) != 0 || got.Transaction.IsManual {
+			t.Fatalf("rejected updates changed %s: %+v", tx.ID, got)
+		}
+	}
+
+	status, data, message := request(t, router, http.MethodPost, "/transactions/bulk-update",
+		map[string]any{"ids": ids, "changes": map[string]any{"category": model.CategoryRentExpense}})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", status, message)
+	}
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Count != 3 {
+		t.Errorf("response = %s, %v, want 3 transactions", data, err)
+	}
+
+	// The already corrected transaction is left as it was
+	for i, tx := range txs {
+		got := getHistory(t, router, tx.ID)
+		wantEdits := 1
+		if i == 1 {
+			wantEdits = 0
+		}
+		if len(got.Edits) != wantEdits || got.Transaction.IsManual != (wantEdits == 1) || got.Transaction.Category != model.CategoryRentExpense {
+			t.Errorf("transaction %d = %+v with %d edits, want %d edits", i, got.Transaction, len(got.Edits), wantEdits)
+		}
+	}
+}
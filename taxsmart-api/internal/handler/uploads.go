@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/service/income"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// CreateUpload handles parsing, classifying and storing an uploaded statement
+func (h *Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	parsed, ok := h.parseUploadedFile(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+
+	imported, err := h.importUpload(r.Context(), userID, uuid.New(), parsed, nil)
+	if err != nil {
+		var failed *importError
+		if errors.As(err, &failed) {
+			response.InternalError(w, failed.message)
+			return
+		}
+		response.InternalError(w, "Failed to import upload")
+		return
+	}
+
+	response.Created(w, map[string]interface{}{
+		"upload":          imported.upload,
+		"transactions":    imported.transactions,
+		"classifications": imported.classifications,
+		"count":           len(imported.transactions),
+	})
+}
+
+// importChunk is how many transactions are classified between progress reports
+const importChunk = 200
+
+// importedUpload is a stored upload with its transactions and their classifications
+type importedUpload struct {
+	upload          *model.Upload
+	transactions    []model.Transaction
+	classifications []model.Classification
+}
+
+// importError carries the message shown to the user when an import fails
+type importError struct {
+	message string
+	err     error
+}
+
+func (e *importError) Error() string { return e.message + ": " + e.err.Error() }
+func (e *importError) Unwrap() error { return e.err }
+
+// importUpload classifies a parsed statement and stores it as an upload with
+// the given ID, notifying the user's webhooks. progress, when set, is called
+// with the number of transactions classified so far after every importChunk;
+// the import stops between chunks once ctx is done.
+func (h *Handler) importUpload(ctx context.Context, userID, uploadID uuid.UUID, parsed *parsedUpload, progress func(done int)) (*importedUpload, error) {
+	now := time.Now()
+	upload := &model.Upload{
+		ID:               uploadID,
+		UserID:           userID,
+		Filename:         parsed.filename,
+		BankFormat:       parsed.bankFormat,
+		TransactionCount: len(parsed.transactions),
+		CreatedAt:        now,
+	}
+
+	results := make([]model.ClassificationResult, 0, len(parsed.transactions))
+	for start := 0; start < len(parsed.transactions); start += importChunk {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		chunk := parsed.transactions[start:min(start+importChunk, len(parsed.transactions))]
+		results = append(results, h.classifier.ClassifyBatch(ctx, userID, chunk)...)
+		if progress != nil {
+			progress(len(results))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	stored, err := h.loadHistory(ctx, userID, parsed.transactions)
+	if err != nil {
+		return nil, &importError{"Failed to load transactions", err}
+	}
+	detections := h.detector.Detect(parsed.transactions, results, stored)
+	for _, d := range detections {
+		results[d.Index] = d.Result
+	}
+
+	transactions := make([]model.Transaction, len(parsed.transactions))
+	for i, ptx := range parsed.transactions {
+		transactions[i] = model.Transaction{
+			ID:              uuid.New(),
+			UploadID:        upload.ID,
+			UserID:          userID,
+			TransactionDate: ptx.Date,
+			Description:     ptx.Description,
+			Amount:          ptx.Amount,
+			TransactionType: ptx.Type,
+			Category:        results[i].Category,
+			Confidence:      results[i].Confidence,
+			CreatedAt:       now,
+			Narration:       ptx.Narration,
+		}
+	}
+	for _, d := range detections {
+		switch {
+		case d.Linked >= 0:
+			transactions[d.Index].LinkedTransactionID = &transactions[d.Linked].ID
+		case d.LinkedID != uuid.Nil:
+			linkedID := d.LinkedID
+			transactions[d.Index].LinkedTransactionID = &linkedID
+		}
+	}
+
+	// Payments continuing a regular stream from the same payer are more certain
+	income.Boost(transactions, results, income.FindStreams(append(stored, transactions...)))
+
+	classifications := make([]model.Classification, len(transactions))
+	for i := range transactions {
+		classifications[i] = model.Classification{
+			ID:            uuid.New(),
+			TransactionID: transactions[i].ID,
+			UserID:        userID,
+			Category:      results[i].Category,
+			Confidence:    results[i].Confidence,
+			Method:        results[i].Method,
+			Rule:          results[i].Rule,
+			Explanation:   results[i].Explanation,
+			Alternatives:  results[i].Alternatives,
+			CreatedAt:     now,
+		}
+	}
+
+	if err := h.repo.CreateUpload(ctx, upload, transactions, classifications); err != nil {
+		return nil, &importError{"Failed to save upload", err}
+	}
+	imported := &importedUpload{upload: upload, transactions: transactions, classifications: classifications}
+	h.publishUpload(ctx, imported)
+	return imported, nil
+}
+
+// historyLookback is how far before a batch the user's stored transactions
+// are loaded, long enough to find the streams the batch continues and the
+// debits its reversals return
+const historyLookback = 13 // months
+
+// loadHistory returns the user's stored transactions from historyLookback
+// before the earliest dated transaction in the batch to its latest
+func (h *Handler) loadHistory(ctx context.Context, userID uuid.UUID, transactions []model.ParsedTransaction) ([]model.Transaction, error) {
+	var from, to time.Time
+	for _, tx := range transactions {
+		if tx.Date.IsZero() {
+			continue
+		}
+		if from.IsZero() || tx.Date.Before(from) {
+			from = tx.Date
+		}
+		if tx.Date.After(to) {
+			to = tx.Date
+		}
+	}
+	if from.IsZero() {
+		return nil, nil
+	}
+
+	stored, _, err := h.repo.ListTransactions(ctx, userID, repository.TransactionFilter{From: from.AddDate(0, -historyLookback, 0), To: to})
+	return stored, err
+}
+
+// ListUploads handles listing the user's uploaded statements
+func (h *Handler) ListUploads(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	uploads, err := h.repo.ListUploads(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load uploads")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"uploads": uploads,
+		"count":   len(uploads),
+	})
+}
+
+// GetUpload handles fetching an uploaded statement with its transactions
+func (h *Handler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	uploadID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid upload ID")
+		return
+	}
+
+	upload, err := h.repo.GetUpload(r.Context(), userID, uploadID)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Upload not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to load upload")
+		return
+	}
+
+	transactions, _, err := h.repo.ListTransactions(r.Context(), userID, repository.TransactionFilter{UploadID: uploadID})
+	if err != nil {
+		response.InternalError(w, "Failed to load transactions")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"upload":       upload,
+		"transactions": transactions,
+		"count":        len(transactions),
+	})
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/middleware"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/service/tax"
+	"github.com/taxsmart/taxsmart-api/internal/service/webhook"
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// Default and maximum number of deliveries listed at once
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// StartWebhooks starts sending webhook deliveries in the background,
+// including those left pending by the last run, until ctx is done
+func (h *Handler) StartWebhooks(ctx context.Context) {
+	h.webhooks.Start(ctx)
+}
+
+// publish queues an event for the user's webhooks. Notifications are best
+// effort: failing to queue one does not fail the work that raised it.
+func (h *Handler) publish(ctx context.Context, userID uuid.UUID, event string, data map[string]interface{}) {
+	h.webhooks.Publish(context.WithoutCancel(ctx), userID, event, data)
+}
+
+// publishUpload notifies the user's webhooks that an upload was classified,
+// with the number of its transactions in each category
+func (h *Handler) publishUpload(ctx context.Context, imported *importedUpload) {
+	categories := make(map[model.Category]int)
+	for _, tx := range imported.transactions {
+		categories[tx.Category]++
+	}
+	h.publish(ctx, imported.upload.UserID, model.EventUploadClassified, map[string]interface{}{
+		"upload":     imported.upload,
+		"categories": categories,
+	})
+}
+
+// publishReport notifies the user's webhooks that a report is ready
+func (h *Handler) publishReport(ctx context.Context, report *model.TaxReport) {
+	h.publish(ctx, report.UserID, model.EventReportReady, map[string]interface{}{
+		"report": tax.Summarise(report),
+	})
+}
+
+// CreateWebhook handles subscribing a URL to events. The response holds the
+// secret deliveries are signed with, which is not shown again.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		response.BadRequest(w, "Failed to read request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		response.BadRequest(w, "Invalid JSON format")
+		return
+	}
+
+	target, err := h.webhooks.CheckURL(r.Context(), req.URL)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(model.WebhookEvents, event) {
+			response.BadRequest(w, "Unknown event: "+event)
+			return
+		}
+	}
+
+	if err := h.ensureUser(r.Context(), userID); err != nil {
+		response.InternalError(w, "Failed to load user")
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		response.InternalError(w, "Failed to create webhook secret")
+		return
+	}
+	hook := &model.Webhook{
+		ID:        uuid.New(),
+		UserID:    userID,
+		URL:       target.String(),
+		Events:    append([]string{}, req.Events...),
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if err := h.repo.CreateWebhook(r.Context(), hook); err != nil {
+		response.InternalError(w, "Failed to save webhook")
+		return
+	}
+
+	response.Created(w, map[string]interface{}{"webhook": hook})
+}
+
+// ListWebhooks handles listing the user's webhooks, without their secrets
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	webhooks, err := h.repo.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w, "Failed to load webhooks")
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	response.Success(w, map[string]interface{}{
+		"webhooks": webhooks,
+		"events":   model.WebhookEvents,
+		"count":    len(webhooks),
+	})
+}
+
+// DeleteWebhook handles unsubscribing a webhook, deleting its delivery log
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID")
+		return
+	}
+
+	err = h.repo.DeleteWebhook(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Webhook not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to delete webhook")
+		return
+	}
+
+	response.Success(w, map[string]string{"id": id.String()})
+}
+
+// ListWebhookDeliveries handles listing a webhook's newest deliveries,
+// honouring the limit query parameter
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid webhook ID")
+		return
+	}
+	limit := defaultDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveryLimit {
+			response.BadRequest(w, "limit must be between 1 and "+strconv.Itoa(maxDeliveryLimit))
+			return
+		}
+		limit = n
+	}
+
+	_, err = h.repo.GetWebhook(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Webhook not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to load webhook")
+		return
+	}
+
+	deliveries, err := h.repo.ListWebhookDeliveries(r.Context(), userID, repository.WebhookDeliveryFilter{WebhookID: id, Limit: limit})
+	if err != nil {
+		response.InternalError(w, "Failed to load deliveries")
+		return
+	}
+
+	response.Success(w, map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// ReplayWebhookDelivery handles sending a past delivery again, as a new
+// delivery with the same payload
+func (h *Handler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserUUID(r.Context())
+	if !ok {
+		response.Unauthorized(w, "User not authenticated")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhooks.Replay(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.NotFound(w, "Delivery not found")
+		return
+	}
+	if err != nil {
+		response.InternalError(w, "Failed to replay delivery")
+		return
+	}
+
+	response.Created(w, map[string]interface{}{"delivery": delivery})
+}
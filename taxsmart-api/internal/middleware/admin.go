@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/taxsmart/taxsmart-api/pkg/response"
+)
+
+// RequireAdmin creates a middleware that only lets the given users through.
+// It must run after FirebaseAuth.
+func RequireAdmin(adminUIDs []string) func(http.Handler) http.Handler {
+	admins := make(map[string]bool, len(adminUIDs))
+	for _, uid := range adminUIDs {
+		admins[uid] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserID(r.Context())
+			if !ok {
+				response.Unauthorized(w, "User not authenticated")
+				return
+			}
+			if !admins[userID] {
+				response.Forbidden(w, "Admin access required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
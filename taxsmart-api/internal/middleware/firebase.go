@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	firebase "firebase.google.com/go/v4"
+	"github.com/google/uuid"
 	"github.com/taxsmart/taxsmart-api/pkg/response"
 )
 
@@ -13,6 +14,9 @@ type contextKey string
 
 const UserIDKey contextKey = "userID"
 
+// userNamespace scopes UUIDs derived from Firebase user IDs
+var userNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://taxsmart.ng/users"))
+
 // FirebaseAuth creates a middleware that verifies Firebase ID tokens
 func FirebaseAuth(app *firebase.App) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -53,3 +57,12 @@ func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(UserIDKey).(string)
 	return userID, ok
 }
+
+// GetUserUUID returns a stable UUID derived from the authenticated user ID
+func GetUserUUID(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := GetUserID(ctx)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return uuid.NewSHA1(userNamespace, []byte(userID)), true
+}
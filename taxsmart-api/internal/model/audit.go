@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AIAuditEntry records one request sent to a third-party AI provider.
+// Prompt is the text exactly as sent, after redaction; the masked values
+// themselves are never stored.
+type AIAuditEntry struct {
+	ID               uuid.UUID      `json:"id"`
+	UserID           uuid.UUID      `json:"user_id"` // uuid.Nil for anonymous requests
+	Provider         string         `json:"provider"`
+	Prompt           string         `json:"prompt"`
+	TransactionCount int            `json:"transaction_count"`
+	Redactions       map[string]int `json:"redactions"` // Values masked per kind, e.g. {"PHONE": 1}
+	Status           string         `json:"status"`     // "ok" or "error"
+	Error            string         `json:"error,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// CachedClassification is a classification result stored for reuse across
+// users. Key hashes the normalised narration, transaction type, amount bucket
+// and Version, which identifies the rule packs and AI prompt that produced it.
+type CachedClassification struct {
+	Key       string               `json:"key"`
+	Version   string               `json:"version"`
+	Result    ClassificationResult `json:"result"`
+	CreatedAt time.Time            `json:"created_at"`
+	ExpiresAt time.Time            `json:"expires_at"`
+}
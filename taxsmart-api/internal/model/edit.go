@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransactionPatch holds user edits to a transaction. Nil fields are left unchanged.
+type TransactionPatch struct {
+	TransactionDate *time.Time `json:"transaction_date,omitempty"`
+	Description     *string    `json:"description,omitempty"`
+	Amount          *float64   `json:"amount,omitempty"`
+	TransactionType *string    `json:"transaction_type,omitempty"`
+	Category        *Category  `json:"category,omitempty"`
+}
+
+// TransactionEdit records a single field change made by a user
+type TransactionEdit struct {
+	ID            uuid.UUID `json:"id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	Field         string    `json:"field"`
+	OldValue      string    `json:"old_value"`
+	NewValue      string    `json:"new_value"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Income stream kinds
+const (
+	StreamSalary    = "salary"    // Regular pay from an employer
+	StreamRetainer  = "retainer"  // Regular fee from a freelance client
+	StreamRecurring = "recurring" // Any other regular income
+)
+
+// Income stream cadences
+const (
+	CadenceWeekly    = "weekly"
+	CadenceBiweekly  = "biweekly"
+	CadenceMonthly   = "monthly"
+	CadenceQuarterly = "quarterly"
+)
+
+// IncomeStream is a run of credits from one payer at a regular cadence and a similar amount
+type IncomeStream struct {
+	Payer          string      `json:"payer"`
+	Kind           string      `json:"kind"`     // "salary", "retainer" or "recurring"
+	Cadence        string      `json:"cadence"`  // "weekly", "biweekly", "monthly" or "quarterly"
+	Category       Category    `json:"category"` // Most common category of its payments
+	Count          int         `json:"count"`
+	Total          float64     `json:"total"`
+	AverageAmount  float64     `json:"average_amount"`
+	FirstDate      time.Time   `json:"first_date"`
+	LastDate       time.Time   `json:"last_date"`
+	Confidence     float64     `json:"confidence"` // How regular and well established the stream is, from 0 to 1
+	TransactionIDs []uuid.UUID `json:"transaction_ids"`
+}
+
+// IncomeSource is one payer's share of a user's income
+type IncomeSource struct {
+	Payer     string       `json:"payer"`
+	Category  Category     `json:"category"` // Category of most of the payer's income
+	Treatment TaxTreatment `json:"treatment"`
+	Kind      string       `json:"kind,omitempty"`    // Kind of the payer's largest stream, if any
+	Cadence   string       `json:"cadence,omitempty"` // Cadence of that stream
+	Count     int          `json:"count"`
+	Total     float64      `json:"total"`
+	Share     float64      `json:"share"` // Fraction of all income
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Job stages, run in this order
+const (
+	StageParse     = "parse"
+	StageClassify  = "classify"
+	StageCalculate = "calculate"
+)
+
+// Job is a statement upload processed in the background: parsed, classified
+// and stored as an upload, then used to calculate the tax year's report
+type Job struct {
+	ID         uuid.UUID   `json:"id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	Status     string      `json:"status"`    // "queued", "running", "succeeded", "failed" or "canceled"
+	Stage      string      `json:"stage"`     // "parse", "classify" or "calculate"
+	Progress   int         `json:"progress"`  // Percent complete over all stages
+	Processed  int         `json:"processed"` // Transactions classified so far
+	Total      int         `json:"total"`     // Transactions parsed from the file
+	Filename   string      `json:"filename"`
+	BankFormat string      `json:"bank_format,omitempty"`
+	TaxYear    int         `json:"tax_year,omitempty"` // Defaults to the year of the latest transaction
+	Reliefs    ReliefInput `json:"reliefs"`
+	UploadID   *uuid.UUID  `json:"upload_id,omitempty"`
+	ReportID   *uuid.UUID  `json:"report_id,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Input      []byte      `json:"-"` // The uploaded file, kept until the job finishes
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has stopped for good
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Payment channels found in bank narrations
+const (
+	ChannelNIP    = "NIP"
+	ChannelPOS    = "POS"
+	ChannelUSSD   = "USSD"
+	ChannelATM    = "ATM"
+	ChannelWeb    = "WEB"
+	ChannelMobile = "MOBILE"
+)
+
+// Narration holds the structured parts extracted from a bank narration
+type Narration struct {
+	Channel       string `json:"channel,omitempty"`
+	Counterparty  string `json:"counterparty,omitempty"` // Normalised name of the other party
+	BankCode      string `json:"bank_code,omitempty"`    // Counterparty bank, e.g. "GTB" or "058"
+	AccountNumber string `json:"account_number,omitempty"`
+	SessionRef    string `json:"session_ref,omitempty"`
+}
+
+// CounterpartySummary groups a user's transactions with one counterparty
+type CounterpartySummary struct {
+	Counterparty     string     `json:"counterparty"`
+	TransactionCount int        `json:"transaction_count"`
+	TotalCredit      float64    `json:"total_credit"`
+	TotalDebit       float64    `json:"total_debit"`
+	Categories       []Category `json:"categories"`
+	FirstSeen        time.Time  `json:"first_seen"`
+	LastSeen         time.Time  `json:"last_seen"`
+}
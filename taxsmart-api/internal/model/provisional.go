@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// Provisional estimate periods
+const (
+	PeriodMonthly   = "monthly"
+	PeriodQuarterly = "quarterly"
+)
+
+// Projection methods for provisional estimates
+const (
+	ProjectionAnnualise  = "annualise"    // Scale year-to-date income to a full year
+	ProjectionTrailing12 = "trailing_12m" // Use income from the 12 months ending each period
+)
+
+// ProvisionalRequest represents a request for in-year tax estimates
+type ProvisionalRequest struct {
+	TaxYear      int           `json:"tax_year"`
+	Period       string        `json:"period"`          // "monthly" or "quarterly"
+	Method       string        `json:"method"`          // "annualise" or "trailing_12m"
+	AsOf         time.Time     `json:"as_of,omitempty"` // Defaults to the latest transaction in the tax year
+	Transactions []Transaction `json:"transactions"`
+	Reliefs      ReliefInput   `json:"reliefs"`
+}
+
+// PeriodEstimate is the provisional position at the end of a single period
+type PeriodEstimate struct {
+	Label                 string    `json:"label"`
+	Start                 time.Time `json:"start"`
+	End                   time.Time `json:"end"`
+	Income                float64   `json:"income"`
+	YTDIncome             float64   `json:"ytd_income"`
+	ProjectedAnnualIncome float64   `json:"projected_annual_income"`
+	ProjectedAnnualTax    float64   `json:"projected_annual_tax"`
+	YTDTax                float64   `json:"ytd_tax"`
+	SetAside              float64   `json:"set_aside"`
+}
+
+// ProvisionalEstimate holds period-by-period estimates for a tax year
+type ProvisionalEstimate struct {
+	TaxYear            int              `json:"tax_year"`
+	Period             string           `json:"period"`
+	Method             string           `json:"method"`
+	AsOf               time.Time        `json:"as_of"`
+	Periods            []PeriodEstimate `json:"periods"`
+	ProjectedAnnualTax float64          `json:"projected_annual_tax"`
+	YTDTax             float64          `json:"ytd_tax"`
+	TotalSetAside      float64          `json:"total_set_aside"`
+}
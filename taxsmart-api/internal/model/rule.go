@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserRule is a classification rule learned from a user's manual corrections.
+// It matches any of the user's transactions of the same type whose normalised
+// description contains every token of the key.
+type UserRule struct {
+	ID              uuid.UUID `json:"id"`
+	UserID          uuid.UUID `json:"user_id"`
+	Key             string    `json:"key"`
+	TransactionType string    `json:"transaction_type"`
+	Category        Category  `json:"category"`
+	Hits            int       `json:"hits"` // Number of corrections that taught this rule
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// Adjustment types supported by the what-if simulator
+const (
+	AdjustmentAddTransaction    = "add_transaction"
+	AdjustmentRemoveTransaction = "remove_transaction"
+	AdjustmentRecategorise      = "recategorise"
+	AdjustmentAdjustReliefs     = "adjust_reliefs"
+	AdjustmentSetReliefs        = "set_reliefs"
+)
+
+// Adjustment is a single change applied to the base calculation request.
+// Existing transactions are referenced by ID, or by index into the base list
+// when the client has not assigned IDs.
+type Adjustment struct {
+	Type             string       `json:"type"`
+	Transaction      *Transaction `json:"transaction,omitempty"`       // add_transaction
+	TransactionID    uuid.UUID    `json:"transaction_id,omitempty"`    // remove_transaction, recategorise
+	TransactionIndex *int         `json:"transaction_index,omitempty"` // remove_transaction, recategorise
+	Category         Category     `json:"category,omitempty"`          // recategorise
+	Reliefs          *ReliefInput `json:"reliefs,omitempty"`           // adjust_reliefs (added), set_reliefs (replaced)
+}
+
+// Scenario is a named set of adjustments evaluated against the baseline
+type Scenario struct {
+	Name        string       `json:"name"`
+	Adjustments []Adjustment `json:"adjustments"`
+}
+
+// SimulationRequest represents a what-if simulation request
+type SimulationRequest struct {
+	Base      TaxCalculationRequest `json:"base"`
+	Scenarios []Scenario            `json:"scenarios"`
+}
+
+// TaxReportDiff holds scenario figures minus baseline figures
+type TaxReportDiff struct {
+	TotalIncome      float64            `json:"total_income"`
+	TotalReliefs     float64            `json:"total_reliefs"`
+	TaxableIncome    float64            `json:"taxable_income"`
+	PITAmount        float64            `json:"pit_amount"`
+	TotalTax         float64            `json:"total_tax"`
+	EffectiveRate    float64            `json:"effective_rate"`
+	IncomeByCategory map[string]float64 `json:"income_by_category,omitempty"`
+	Reliefs          map[string]float64 `json:"reliefs,omitempty"`
+}
+
+// ScenarioResult is the outcome of a single scenario
+type ScenarioResult struct {
+	Name   string        `json:"name"`
+	Report *TaxReport    `json:"report"`
+	Diff   TaxReportDiff `json:"diff"`
+}
+
+// SimulationResult holds the baseline report and every scenario outcome
+type SimulationResult struct {
+	Baseline  *TaxReport       `json:"baseline"`
+	Scenarios []ScenarioResult `json:"scenarios"`
+}
@@ -43,9 +43,26 @@ type TaxReport struct {
 
 // TaxBreakdown provides detailed breakdown of tax calculations
 type TaxBreakdown struct {
-	PITBreakdown     []BracketDetail    `json:"pit_breakdown"`
-	IncomeByCategory map[string]float64 `json:"income_by_category"`
-	ReliefsApplied   map[string]float64 `json:"reliefs_applied"`
+	PITBreakdown      []BracketDetail         `json:"pit_breakdown"`
+	IncomeByCategory  map[string]float64      `json:"income_by_category"`            // Taxable income
+	IncomeByTreatment map[string]float64      `json:"income_by_treatment,omitempty"` // All income, including exempt and withheld
+	IncomeSources     []IncomeSource          `json:"income_sources,omitempty"`      // Payers of all income, largest first
+	ReliefsApplied    map[string]ReliefDetail `json:"reliefs_applied"`
+}
+
+// Relief statuses reported in ReliefDetail
+const (
+	ReliefStatusApplied  = "applied"
+	ReliefStatusCapped   = "capped"
+	ReliefStatusRejected = "rejected"
+)
+
+// ReliefDetail explains how a claimed relief was treated against income
+type ReliefDetail struct {
+	Claimed float64 `json:"claimed"`
+	Allowed float64 `json:"allowed"`
+	Status  string  `json:"status"` // "applied", "capped" or "rejected"
+	Reason  string  `json:"reason,omitempty"`
 }
 
 // BracketDetail shows tax applied in each bracket
@@ -79,3 +96,44 @@ type TaxCalculationRequest struct {
 	Transactions []Transaction `json:"transactions"`
 	Reliefs      ReliefInput   `json:"reliefs"`
 }
+
+// Recommendation is a suggested action that legally reduces tax
+type Recommendation struct {
+	Action           string  `json:"action"` // "pension_top_up", "nhis_top_up", "nhf_registration", "claim_rent_relief"
+	Title            string  `json:"title"`
+	Description      string  `json:"description"`
+	SuggestedAmount  float64 `json:"suggested_amount"`
+	AdditionalRelief float64 `json:"additional_relief"`
+	MarginalRate     float64 `json:"marginal_rate"`
+	ExpectedSaving   float64 `json:"expected_saving"`
+	Rule             string  `json:"rule"`
+}
+
+// TaxReportSummary is a compact view of a stored report for history listings
+type TaxReportSummary struct {
+	ID            uuid.UUID `json:"id"`
+	TaxYear       int       `json:"tax_year"`
+	TotalIncome   float64   `json:"total_income"`
+	TotalReliefs  float64   `json:"total_reliefs"`
+	TaxableIncome float64   `json:"taxable_income"`
+	TotalTax      float64   `json:"total_tax"`
+	EffectiveRate float64   `json:"effective_rate"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// YearComparison compares a user's reports for two tax years
+type YearComparison struct {
+	FromYear          int           `json:"from_year"`
+	ToYear            int           `json:"to_year"`
+	From              *TaxReport    `json:"from"`
+	To                *TaxReport    `json:"to"`
+	FromEffectiveRate float64       `json:"from_effective_rate"`
+	ToEffectiveRate   float64       `json:"to_effective_rate"`
+	Change            TaxReportDiff `json:"change"`
+}
+
+// MultiYearRequest represents a request to calculate tax for every year a set of transactions spans
+type MultiYearRequest struct {
+	Transactions  []Transaction       `json:"transactions"`
+	ReliefsByYear map[int]ReliefInput `json:"reliefs_by_year"`
+}
@@ -0,0 +1,142 @@
+package model
+
+import "strings"
+
+// Category represents transaction classification. Subcategories are named
+// after their parent, e.g. "freelance_income.foreign".
+type Category string
+
+const (
+	CategoryEmployment    Category = "employment_income"
+	CategoryFreelance     Category = "freelance_income"
+	CategoryRental        Category = "rental_income"
+	CategoryInvestment    Category = "investment_income"
+	CategoryCrypto        Category = "crypto_income"
+	CategoryInterest      Category = "interest_income"
+	CategoryOtherIncome   Category = "other_income"
+	CategoryExpense       Category = "expense"
+	CategoryRentExpense   Category = "rent_expense"
+	CategoryTransfer      Category = "transfer"
+	CategoryUncategorized Category = "uncategorized"
+)
+
+// Subcategories
+const (
+	CategoryFreelanceDomestic Category = "freelance_income.domestic"
+	CategoryFreelanceForeign  Category = "freelance_income.foreign"
+	CategoryDividend          Category = "investment_income.dividend"
+	CategoryCapitalGain       Category = "investment_income.capital_gain"
+	CategoryGift              Category = "other_income.gift"
+	CategoryOwnerTransfer     Category = "transfer.owner"
+	CategoryLoan              Category = "transfer.loan"
+	CategoryRefund            Category = "transfer.refund"
+	CategoryReversal          Category = "transfer.reversal"
+)
+
+// TaxTreatment is how money in a category is taxed
+type TaxTreatment string
+
+const (
+	TreatmentTaxable   TaxTreatment = "taxable"    // Counted towards personal income tax
+	TreatmentExempt    TaxTreatment = "exempt"     // Income the law exempts
+	TreatmentFinalWHT  TaxTreatment = "final_wht"  // Income already taxed in full by withholding
+	TreatmentNotIncome TaxTreatment = "not_income" // Spending or money that was never income
+)
+
+// CategoryNode is a category in the taxonomy
+type CategoryNode struct {
+	Category    Category     `json:"category"`
+	Parent      Category     `json:"parent,omitempty"`
+	Description string       `json:"description"`
+	Treatment   TaxTreatment `json:"treatment"`
+}
+
+// Taxonomy lists every category, each parent before its subcategories
+var Taxonomy = []CategoryNode{
+	{CategoryEmployment, "", "Salary, wages, payroll from employer", TreatmentTaxable},
+	{CategoryFreelance, "", "Payments from freelance platforms or clients", TreatmentTaxable},
+	{CategoryFreelanceDomestic, CategoryFreelance, "Paid by clients in Nigeria", TreatmentTaxable},
+	{CategoryFreelanceForeign, CategoryFreelance, "Paid by clients abroad or through foreign platforms", TreatmentTaxable},
+	{CategoryRental, "", "Rent received from tenants", TreatmentTaxable},
+	{CategoryInvestment, "", "Dividends, investment returns", TreatmentTaxable},
+	{CategoryDividend, CategoryInvestment, "Dividends, taxed by withholding at source", TreatmentFinalWHT},
+	{CategoryCapitalGain, CategoryInvestment, "Gains on selling shares, funds or property", TreatmentTaxable},
+	{CategoryCrypto, "", "Cryptocurrency trading/sales", TreatmentTaxable},
+	{CategoryInterest, "", "Bank interest, taxed by withholding at source", TreatmentFinalWHT},
+	{CategoryOtherIncome, "", "Other income sources", TreatmentTaxable},
+	{CategoryGift, CategoryOtherIncome, "Gifts from family or friends", TreatmentExempt},
+	{CategoryExpense, "", "General expenses", TreatmentNotIncome},
+	{CategoryRentExpense, "", "Rent payments to landlord", TreatmentNotIncome},
+	{CategoryTransfer, "", "Money transfers between accounts", TreatmentNotIncome},
+	{CategoryOwnerTransfer, CategoryTransfer, "Owner moving money into or out of their business", TreatmentNotIncome},
+	{CategoryLoan, CategoryTransfer, "Loan disbursements and repayments", TreatmentNotIncome},
+	{CategoryRefund, CategoryTransfer, "Refunds of earlier purchases", TreatmentNotIncome},
+	{CategoryReversal, CategoryTransfer, "Reversals of failed or duplicate transactions", TreatmentNotIncome},
+	{CategoryUncategorized, "", "Cannot determine", TreatmentNotIncome},
+}
+
+// Categories lists every defined category in taxonomy order
+var Categories = func() []Category {
+	categories := make([]Category, len(Taxonomy))
+	for i, node := range Taxonomy {
+		categories[i] = node.Category
+	}
+	return categories
+}()
+
+// taxonomyIndex finds a category's node
+var taxonomyIndex = func() map[Category]CategoryNode {
+	index := make(map[Category]CategoryNode, len(Taxonomy))
+	for _, node := range Taxonomy {
+		index[node.Category] = node
+	}
+	return index
+}()
+
+// Node returns the category's place in the taxonomy
+func (c Category) Node() (CategoryNode, bool) {
+	node, ok := taxonomyIndex[c]
+	return node, ok
+}
+
+// IsValid returns true if the category is one of the defined categories
+func (c Category) IsValid() bool {
+	_, ok := taxonomyIndex[c]
+	return ok
+}
+
+// Parent returns the category a subcategory belongs to, or "" for a top-level category
+func (c Category) Parent() Category {
+	return taxonomyIndex[c].Parent
+}
+
+// Root returns the top-level category the category belongs to
+func (c Category) Root() Category {
+	for c.Parent() != "" {
+		c = c.Parent()
+	}
+	return c
+}
+
+// IsA returns true if the category is ancestor or one of its subcategories
+func (c Category) IsA(ancestor Category) bool {
+	return c == ancestor || strings.HasPrefix(string(c), string(ancestor)+".")
+}
+
+// Treatment returns how the category is taxed. Unknown categories are not income.
+func (c Category) Treatment() TaxTreatment {
+	if node, ok := taxonomyIndex[c]; ok {
+		return node.Treatment
+	}
+	return TreatmentNotIncome
+}
+
+// IsIncome returns true if the category represents income, taxable or not
+func (c Category) IsIncome() bool {
+	return c.Treatment() != TreatmentNotIncome
+}
+
+// IsTaxable returns true if income in the category counts towards personal income tax
+func (c Category) IsTaxable() bool {
+	return c.Treatment() == TreatmentTaxable
+}
@@ -20,42 +20,10 @@ type Transaction struct {
 	IsManual        bool      `json:"is_manual"`
 	RawData         string    `json:"raw_data,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
-}
-
-// Category represents transaction classification
-type Category string
-
-const (
-	CategoryEmployment    Category = "employment_income"
-	CategoryFreelance     Category = "freelance_income"
-	CategoryRental        Category = "rental_income"
-	CategoryInvestment    Category = "investment_income"
-	CategoryCrypto        Category = "crypto_income"
-	CategoryInterest      Category = "interest_income"
-	CategoryOtherIncome   Category = "other_income"
-	CategoryExpense       Category = "expense"
-	CategoryRentExpense   Category = "rent_expense"
-	CategoryTransfer      Category = "transfer"
-	CategoryUncategorized Category = "uncategorized"
-)
+	Narration
 
-// IsIncome returns true if the category represents income
-func (c Category) IsIncome() bool {
-	incomeCategories := map[Category]bool{
-		CategoryEmployment:  true,
-		CategoryFreelance:   true,
-		CategoryRental:      true,
-		CategoryInvestment:  true,
-		CategoryCrypto:      true,
-		CategoryInterest:    true,
-		CategoryOtherIncome: true,
-	}
-	return incomeCategories[c]
-}
-
-// IsTaxable returns true if the category is taxable
-func (c Category) IsTaxable() bool {
-	return c.IsIncome()
+	// LinkedTransactionID is the debit a reversal or refund returns
+	LinkedTransactionID *uuid.UUID `json:"linked_transaction_id,omitempty"`
 }
 
 // ParsedTransaction represents a transaction parsed from a file before classification
@@ -66,11 +34,46 @@ type ParsedTransaction struct {
 	Type        string    `json:"type"` // "credit" or "debit"
 	Balance     float64   `json:"balance,omitempty"`
 	Reference   string    `json:"reference,omitempty"`
+	Narration
 }
 
 // ClassificationResult represents the result of classifying a transaction
 type ClassificationResult struct {
+	Category     Category      `json:"category"`
+	Confidence   float64       `json:"confidence"`
+	Method       string        `json:"method"`         // "user_rule", "rules", "local", "ai" or "detector"
+	Rule         string        `json:"rule,omitempty"` // Learned rule key or "pack/rule-id" that matched
+	Explanation  *Explanation  `json:"explanation,omitempty"`
+	Alternatives []Alternative `json:"alternatives,omitempty"` // Other likely categories, best first
+}
+
+// Explanation records the evidence behind a classification
+type Explanation struct {
+	Summary   string `json:"summary"`
+	Pattern   string `json:"pattern,omitempty"`   // Rule pattern or learned rule key that matched
+	Field     string `json:"field,omitempty"`     // "description", "counterparty" or "channel"
+	Matched   string `json:"matched,omitempty"`   // Text the pattern matched
+	Span      *Span  `json:"span,omitempty"`      // Position of Matched within Field
+	Rationale string `json:"rationale,omitempty"` // The AI's reason for its answer
+	Votes     []Vote `json:"votes,omitempty"`     // Votes the ensemble counted
+}
+
+// Vote is one classifier stage's answer as counted by the ensemble
+type Vote struct {
+	Source     string   `json:"source"` // "user_rule", "rules", "local" or "ai"
 	Category   Category `json:"category"`
-	Confidence float64  `json:"confidence"`
-	Method     string   `json:"method"` // "ai" or "rules"
+	Confidence float64  `json:"confidence"` // Calibrated confidence
+	Weight     float64  `json:"weight"`
+}
+
+// Span is a byte range [Start, End) within a text field
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Alternative is another category a transaction may belong to
+type Alternative struct {
+	Category Category `json:"category"`
+	Score    float64  `json:"score"`
 }
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User represents an account holder, linked to their Firebase identity
+type User struct {
+	ID                 uuid.UUID `json:"id"`
+	AuthUID            string    `json:"auth_uid"`
+	ExternalAIDisabled bool      `json:"external_ai_disabled"` // Keep transactions away from third-party AI
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// Upload represents an uploaded bank statement
+type Upload struct {
+	ID               uuid.UUID `json:"id"`
+	UserID           uuid.UUID `json:"user_id"`
+	Filename         string    `json:"filename"`
+	BankFormat       string    `json:"bank_format"`
+	TransactionCount int       `json:"transaction_count"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// Classification records a single classification of a stored transaction
+type Classification struct {
+	ID            uuid.UUID     `json:"id"`
+	TransactionID uuid.UUID     `json:"transaction_id"`
+	UserID        uuid.UUID     `json:"user_id"`
+	Category      Category      `json:"category"`
+	Confidence    float64       `json:"confidence"`
+	Method        string        `json:"method"`
+	Rule          string        `json:"rule,omitempty"`
+	Explanation   *Explanation  `json:"explanation,omitempty"`
+	Alternatives  []Alternative `json:"alternatives,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
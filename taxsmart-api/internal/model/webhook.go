@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook events
+const (
+	EventUploadClassified = "upload.classified" // An upload's transactions were classified and stored
+	EventReportReady      = "report.ready"      // A tax report was calculated and stored
+	EventJobFinished      = "job.finished"      // A background job succeeded, failed or was canceled
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{EventUploadClassified, EventReportReady, EventJobFinished}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a URL an account has subscribed to be notified at
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`           // Empty subscribes to every event
+	Secret    string    `json:"secret,omitempty"` // Signs deliveries; only shown when the webhook is created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook is notified of an event
+func (w *Webhook) Subscribes(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // "pending", "succeeded" or "failed"
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"` // HTTP status of the last attempt
+	Error          string          `json:"error,omitempty"`           // Why the last attempt failed
+	ReplayOf       *uuid.UUID      `json:"replay_of,omitempty"`       // The delivery this one replays
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"` // Set while pending
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,866 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+)
+
+// reportKey identifies a user's report for a tax year
+type reportKey struct {
+	userID  uuid.UUID
+	taxYear int
+}
+
+// Store is an in-memory repository for tests and local development.
+// Records are copied in and out so callers cannot mutate stored state.
+type Store struct {
+	mu              sync.RWMutex
+	users           map[uuid.UUID]*model.User
+	uploads         map[uuid.UUID]*model.Upload
+	transactions    map[uuid.UUID]*model.Transaction
+	classifications map[uuid.UUID][]model.Classification
+	edits           map[uuid.UUID][]model.TransactionEdit
+	userRules       map[uuid.UUID]*model.UserRule
+	cache           map[string]*model.CachedClassification
+	aiAudit         []model.AIAuditEntry
+	reports         map[reportKey]*model.TaxReport
+	jobs            map[uuid.UUID]*model.Job
+	webhooks        map[uuid.UUID]*model.Webhook
+	deliveries      map[uuid.UUID]*model.WebhookDelivery
+}
+
+// New creates an empty in-memory store
+func New() *Store {
+	return &Store{
+		users:           make(map[uuid.UUID]*model.User),
+		uploads:         make(map[uuid.UUID]*model.Upload),
+		transactions:    make(map[uuid.UUID]*model.Transaction),
+		classifications: make(map[uuid.UUID][]model.Classification),
+		edits:           make(map[uuid.UUID][]model.TransactionEdit),
+		userRules:       make(map[uuid.UUID]*model.UserRule),
+		cache:           make(map[string]*model.CachedClassification),
+		reports:         make(map[reportKey]*model.TaxReport),
+		jobs:            make(map[uuid.UUID]*model.Job),
+		webhooks:        make(map[uuid.UUID]*model.Webhook),
+		deliveries:      make(map[uuid.UUID]*model.WebhookDelivery),
+	}
+}
+
+// Close is a no-op for the in-memory store
+func (s *Store) Close() error {
+	return nil
+}
+
+// EnsureUser creates the user if it does not exist yet
+func (s *Store) EnsureUser(ctx context.Context, user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.ID]; !ok {
+		stored := *user
+		s.users[user.ID] = &stored
+	}
+	return nil
+}
+
+// GetUser returns a user by ID
+func (s *Store) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+// UpdateUserSettings stores the user's preferences
+func (s *Store) UpdateUserSettings(ctx context.Context, user *model.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	stored.ExternalAIDisabled = user.ExternalAIDisabled
+	return nil
+}
+
+// CreateUpload stores a new upload with its transactions and their classifications
+func (s *Store) CreateUpload(ctx context.Context, upload *model.Upload, txs []model.Transaction, classifications []model.Classification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *upload
+	s.uploads[upload.ID] = &stored
+	for _, tx := range txs {
+		storedTx := tx
+		s.transactions[tx.ID] = &storedTx
+	}
+	for _, c := range classifications {
+		s.classifications[c.TransactionID] = append(s.classifications[c.TransactionID], c)
+	}
+	return nil
+}
+
+// GetUpload returns one of the user's uploads
+func (s *Store) GetUpload(ctx context.Context, userID, id uuid.UUID) (*model.Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	upload, ok := s.uploads[id]
+	if !ok || upload.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	found := *upload
+	return &found, nil
+}
+
+// ListUploads returns the user's uploads, newest first
+func (s *Store) ListUploads(ctx context.Context, userID uuid.UUID) ([]*model.Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uploads := []*model.Upload{}
+	for _, upload := range s.uploads {
+		if upload.UserID == userID {
+			found := *upload
+			uploads = append(uploads, &found)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].CreatedAt.After(uploads[j].CreatedAt)
+	})
+	return uploads, nil
+}
+
+// CreateTransactions stores new transactions
+func (s *Store) CreateTransactions(ctx context.Context, txs []model.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tx := range txs {
+		stored := tx
+		s.transactions[tx.ID] = &stored
+	}
+	return nil
+}
+
+// GetTransaction returns one of the user's transactions
+func (s *Store) GetTransaction(ctx context.Context, userID, id uuid.UUID) (*model.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx, ok := s.transactions[id]
+	if !ok || tx.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	found := *tx
+	return &found, nil
+}
+
+// ListTransactions returns one page of the user's transactions matching the filter, ordered by date
+func (s *Store) ListTransactions(ctx context.Context, userID uuid.UUID, filter repository.TransactionFilter) ([]model.Transaction, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	txs := []model.Transaction{}
+	for _, tx := range s.transactions {
+		if tx.UserID != userID || !matches(tx, filter) {
+			continue
+		}
+		txs = append(txs, *tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].TransactionDate.Equal(txs[j].TransactionDate) {
+			if txs[i].CreatedAt.Equal(txs[j].CreatedAt) {
+				return txs[i].ID.String() < txs[j].ID.String()
+			}
+			return txs[i].CreatedAt.Before(txs[j].CreatedAt)
+		}
+		return txs[i].TransactionDate.Before(txs[j].TransactionDate)
+	})
+
+	total := len(txs)
+	if filter.Offset > 0 {
+		if filter.Offset >= len(txs) {
+			return []model.Transaction{}, total, nil
+		}
+		txs = txs[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(txs) {
+		txs = txs[:filter.Limit]
+	}
+	return txs, total, nil
+}
+
+// ListLabelledTransactions returns manually categorized transactions of all users, newest first
+func (s *Store) ListLabelledTransactions(ctx context.Context, limit int) ([]model.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	txs := []model.Transaction{}
+	for _, tx := range s.transactions {
+		if tx.IsManual && tx.Category != model.CategoryUncategorized {
+			txs = append(txs, *tx)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].CreatedAt.Equal(txs[j].CreatedAt) {
+			return txs[i].ID.String() < txs[j].ID.String()
+		}
+		return txs[i].CreatedAt.After(txs[j].CreatedAt)
+	})
+	if limit > 0 && limit < len(txs) {
+		txs = txs[:limit]
+	}
+	return txs, nil
+}
+
+// matches reports whether a transaction satisfies the filter
+func matches(tx *model.Transaction, filter repository.TransactionFilter) bool {
+	if filter.UploadID != uuid.Nil && tx.UploadID != filter.UploadID {
+		return false
+	}
+	if !filter.From.IsZero() && tx.TransactionDate.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && tx.TransactionDate.After(filter.To) {
+		return false
+	}
+	if filter.Category != "" && !tx.Category.IsA(filter.Category) {
+		return false
+	}
+	if filter.Counterparty != "" && tx.Counterparty != filter.Counterparty {
+		return false
+	}
+	if filter.MinConfidence != nil && tx.Confidence < *filter.MinConfidence {
+		return false
+	}
+	if filter.MaxConfidence != nil && tx.Confidence > *filter.MaxConfidence {
+		return false
+	}
+	return true
+}
+
+// ListCounterparties groups the user's matching transactions by counterparty, largest total first
+func (s *Store) ListCounterparties(ctx context.Context, userID uuid.UUID, filter repository.TransactionFilter) ([]model.CounterpartySummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make(map[string]*model.CounterpartySummary)
+	for _, tx := range s.transactions {
+		if tx.UserID != userID || tx.Counterparty == "" || !matches(tx, filter) {
+			continue
+		}
+		group, ok := groups[tx.Counterparty]
+		if !ok {
+			group = &model.CounterpartySummary{
+				Counterparty: tx.Counterparty,
+				Categories:   []model.Category{},
+				FirstSeen:    tx.TransactionDate,
+				LastSeen:     tx.TransactionDate,
+			}
+			groups[tx.Counterparty] = group
+		}
+		group.TransactionCount++
+		if tx.TransactionType == "credit" {
+			group.TotalCredit += tx.Amount
+		} else {
+			group.TotalDebit += tx.Amount
+		}
+		if !slices.Contains(group.Categories, tx.Category) {
+			group.Categories = append(group.Categories, tx.Category)
+		}
+		if tx.TransactionDate.Before(group.FirstSeen) {
+			group.FirstSeen = tx.TransactionDate
+		}
+		if tx.TransactionDate.After(group.LastSeen) {
+			group.LastSeen = tx.TransactionDate
+		}
+	}
+
+	summaries := make([]model.CounterpartySummary, 0, len(groups))
+	for _, group := range groups {
+		slices.Sort(group.Categories)
+		summaries = append(summaries, *group)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a := summaries[i].TotalCredit + summaries[i].TotalDebit
+		b := summaries[j].TotalCredit + summaries[j].TotalDebit
+		if a != b {
+			return a > b
+		}
+		return summaries[i].Counterparty < summaries[j].Counterparty
+	})
+	return summaries, nil
+}
+
+// UpdateTransaction replaces a stored transaction
+func (s *Store) UpdateTransaction(ctx context.Context, tx *model.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.transactions[tx.ID]
+	if !ok || existing.UserID != tx.UserID {
+		return repository.ErrNotFound
+	}
+	stored := *tx
+	s.transactions[tx.ID] = &stored
+	return nil
+}
+
+// DeleteTransaction removes one of the user's transactions with its history
+func (s *Store) DeleteTransaction(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, ok := s.transactions[id]
+	if !ok || tx.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(s.transactions, id)
+	delete(s.classifications, id)
+	delete(s.edits, id)
+	for _, other := range s.transactions {
+		if other.LinkedTransactionID != nil && *other.LinkedTransactionID == id {
+			other.LinkedTransactionID = nil
+		}
+	}
+	return nil
+}
+
+// EditTransactions stores edited transactions with their edit history,
+// classifications and learned rules, storing nothing if a transaction is missing
+func (s *Store) EditTransactions(ctx context.Context, txs []model.Transaction, edits []model.TransactionEdit, classifications []model.Classification, rules []model.UserRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tx := range txs {
+		if existing, ok := s.transactions[tx.ID]; !ok || existing.UserID != tx.UserID {
+			return repository.ErrNotFound
+		}
+	}
+	for _, tx := range txs {
+		stored := tx
+		s.transactions[tx.ID] = &stored
+	}
+	for _, edit := range edits {
+		s.edits[edit.TransactionID] = append(s.edits[edit.TransactionID], edit)
+	}
+	for _, c := range classifications {
+		s.classifications[c.TransactionID] = append(s.classifications[c.TransactionID], c)
+	}
+	for i := range rules {
+		s.saveUserRule(&rules[i])
+	}
+	return nil
+}
+
+// ListTransactionEdits returns the edit history of a transaction, oldest first
+func (s *Store) ListTransactionEdits(ctx context.Context, userID, transactionID uuid.UUID) ([]model.TransactionEdit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	edits := []model.TransactionEdit{}
+	for _, edit := range s.edits[transactionID] {
+		if edit.UserID == userID {
+			edits = append(edits, edit)
+		}
+	}
+	return edits, nil
+}
+
+// SaveClassifications appends to the classification history of transactions
+func (s *Store) SaveClassifications(ctx context.Context, classifications []model.Classification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range classifications {
+		s.classifications[c.TransactionID] = append(s.classifications[c.TransactionID], c)
+	}
+	return nil
+}
+
+// ListClassifications returns the classification history of a transaction, oldest first
+func (s *Store) ListClassifications(ctx context.Context, userID, transactionID uuid.UUID) ([]model.Classification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	classifications := []model.Classification{}
+	for _, c := range s.classifications[transactionID] {
+		if c.UserID == userID {
+			classifications = append(classifications, c)
+		}
+	}
+	return classifications, nil
+}
+
+// SaveUserRule stores a learned rule, or updates the category and hit count
+// of the existing rule with the same key and transaction type
+func (s *Store) SaveUserRule(ctx context.Context, rule *model.UserRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saveUserRule(rule)
+	return nil
+}
+
+// saveUserRule stores or updates a learned rule with s.mu held
+func (s *Store) saveUserRule(rule *model.UserRule) {
+	for _, existing := range s.userRules {
+		if existing.UserID == rule.UserID && existing.Key == rule.Key && existing.TransactionType == rule.TransactionType {
+			existing.Category = rule.Category
+			existing.Hits++
+			existing.UpdatedAt = rule.UpdatedAt
+			return
+		}
+	}
+	stored := *rule
+	s.userRules[rule.ID] = &stored
+}
+
+// ListUserRules returns the user's learned rules, oldest first
+func (s *Store) ListUserRules(ctx context.Context, userID uuid.UUID) ([]model.UserRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := []model.UserRule{}
+	for _, rule := range s.userRules {
+		if rule.UserID == userID {
+			rules = append(rules, *rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].Key < rules[j].Key
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules, nil
+}
+
+// DeleteUserRule removes one of the user's learned rules
+func (s *Store) DeleteUserRule(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.userRules[id]
+	if !ok || rule.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(s.userRules, id)
+	return nil
+}
+
+// GetCachedClassification returns a cached classification by key
+func (s *Store) GetCachedClassification(ctx context.Context, key string) (*model.CachedClassification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.cache[key]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	stored := *entry
+	return &stored, nil
+}
+
+// SaveCachedClassification stores a cached classification, replacing any with the same key
+func (s *Store) SaveCachedClassification(ctx context.Context, entry *model.CachedClassification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *entry
+	s.cache[entry.Key] = &stored
+	return nil
+}
+
+// DeleteStaleClassifications removes cached classifications from other versions or expired by now
+func (s *Store) DeleteStaleClassifications(ctx context.Context, version string, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, entry := range s.cache {
+		if entry.Version != version || !now.Before(entry.ExpiresAt) {
+			delete(s.cache, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// ClearClassificationCache removes every cached classification
+func (s *Store) ClearClassificationCache(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.cache)
+	return nil
+}
+
+// SaveAIAudit records a request sent to an AI provider
+func (s *Store) SaveAIAudit(ctx context.Context, entry *model.AIAuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *entry
+	stored.Redactions = maps.Clone(entry.Redactions)
+	s.aiAudit = append(s.aiAudit, stored)
+	return nil
+}
+
+// ListAIAudit returns matching AI audit entries, newest first
+func (s *Store) ListAIAudit(ctx context.Context, filter repository.AIAuditFilter) ([]model.AIAuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []model.AIAuditEntry{}
+	for i := len(s.aiAudit) - 1; i >= 0; i-- {
+		entry := s.aiAudit[i]
+		if filter.UserID != uuid.Nil && entry.UserID != filter.UserID {
+			continue
+		}
+		entry.Redactions = maps.Clone(entry.Redactions)
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// SaveReport stores a report, replacing any report for the same user and year
+func (s *Store) SaveReport(ctx context.Context, report *model.TaxReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reports[reportKey{report.UserID, report.TaxYear}] = copyReport(report)
+	return nil
+}
+
+// GetReportByYear returns the user's report for a tax year
+func (s *Store) GetReportByYear(ctx context.Context, userID uuid.UUID, taxYear int) (*model.TaxReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report, ok := s.reports[reportKey{userID, taxYear}]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return copyReport(report), nil
+}
+
+// ListReports returns the user's reports ordered by tax year
+func (s *Store) ListReports(ctx context.Context, userID uuid.UUID) ([]*model.TaxReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reports := []*model.TaxReport{}
+	for key, report := range s.reports {
+		if key.userID == userID {
+			reports = append(reports, copyReport(report))
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].TaxYear < reports[j].TaxYear
+	})
+	return reports, nil
+}
+
+// copyReport copies a report with its own breakdown
+func copyReport(report *model.TaxReport) *model.TaxReport {
+	found := *report
+	if report.Breakdown != nil {
+		breakdown := *report.Breakdown
+		breakdown.PITBreakdown = slices.Clone(breakdown.PITBreakdown)
+		breakdown.IncomeByCategory = maps.Clone(breakdown.IncomeByCategory)
+		breakdown.IncomeByTreatment = maps.Clone(breakdown.IncomeByTreatment)
+		breakdown.IncomeSources = slices.Clone(breakdown.IncomeSources)
+		breakdown.ReliefsApplied = maps.Clone(breakdown.ReliefsApplied)
+		found.Breakdown = &breakdown
+	}
+	return &found
+}
+
+// copyJob copies a job with its own pointers, and its input when withInput is set
+func copyJob(job *model.Job, withInput bool) *model.Job {
+	found := *job
+	found.UploadID = copyID(job.UploadID)
+	found.ReportID = copyID(job.ReportID)
+	found.Input = nil
+	if withInput {
+		found.Input = slices.Clone(job.Input)
+	}
+	if job.FinishedAt != nil {
+		finishedAt := *job.FinishedAt
+		found.FinishedAt = &finishedAt
+	}
+	return &found
+}
+
+// copyID copies an optional ID
+func copyID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	found := *id
+	return &found
+}
+
+// CreateJob stores a new job with its input
+func (s *Store) CreateJob(ctx context.Context, job *model.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = copyJob(job, true)
+	return nil
+}
+
+// GetJob returns one of the user's jobs
+func (s *Store) GetJob(ctx context.Context, userID, id uuid.UUID) (*model.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	return copyJob(job, true), nil
+}
+
+// ListJobs returns the user's jobs, newest first
+func (s *Store) ListJobs(ctx context.Context, userID uuid.UUID) ([]*model.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := []*model.Job{}
+	for _, job := range s.jobs {
+		if job.UserID == userID {
+			jobs = append(jobs, copyJob(job, false))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// UpdateJob stores a job's progress, keeping its input until it has finished
+func (s *Store) UpdateJob(ctx context.Context, job *model.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.jobs[job.ID]
+	if !ok || stored.UserID != job.UserID {
+		return repository.ErrNotFound
+	}
+	updated := copyJob(job, false)
+	updated.Filename, updated.CreatedAt = stored.Filename, stored.CreatedAt
+	if !job.Finished() {
+		updated.Input = stored.Input
+	}
+	s.jobs[job.ID] = updated
+	return nil
+}
+
+// ListUnfinishedJobs returns the queued and running jobs of all users, oldest first, without their input
+func (s *Store) ListUnfinishedJobs(ctx context.Context) ([]*model.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := []*model.Job{}
+	for _, job := range s.jobs {
+		if !job.Finished() {
+			jobs = append(jobs, copyJob(job, false))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// copyWebhook copies a webhook with its own event list
+func copyWebhook(webhook *model.Webhook) model.Webhook {
+	found := *webhook
+	found.Events = slices.Clone(webhook.Events)
+	return found
+}
+
+// CreateWebhook stores a new webhook
+func (s *Store) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := copyWebhook(webhook)
+	s.webhooks[webhook.ID] = &stored
+	return nil
+}
+
+// GetWebhook returns one of the user's webhooks
+func (s *Store) GetWebhook(ctx context.Context, userID, id uuid.UUID) (*model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	found := copyWebhook(webhook)
+	return &found, nil
+}
+
+// ListWebhooks returns the user's webhooks, oldest first
+func (s *Store) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := []model.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID.String() < webhooks[j].ID.String()
+	})
+	return webhooks, nil
+}
+
+// DeleteWebhook removes one of the user's webhooks and its deliveries
+func (s *Store) DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok || webhook.UserID != userID {
+		return repository.ErrNotFound
+	}
+	delete(s.webhooks, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// copyDelivery copies a delivery with its own payload and pointers
+func copyDelivery(delivery *model.WebhookDelivery) model.WebhookDelivery {
+	found := *delivery
+	found.Payload = slices.Clone(delivery.Payload)
+	found.ReplayOf = copyID(delivery.ReplayOf)
+	if delivery.NextAttemptAt != nil {
+		next := *delivery.NextAttemptAt
+		found.NextAttemptAt = &next
+	}
+	return found
+}
+
+// CreateWebhookDelivery stores a new delivery
+func (s *Store) CreateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := copyDelivery(delivery)
+	s.deliveries[delivery.ID] = &stored
+	return nil
+}
+
+// UpdateWebhookDelivery stores the outcome of an attempt at a delivery
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.deliveries[delivery.ID]
+	if !ok || stored.UserID != delivery.UserID {
+		return repository.ErrNotFound
+	}
+	updated := copyDelivery(delivery)
+	updated.Payload, updated.CreatedAt = stored.Payload, stored.CreatedAt
+	s.deliveries[delivery.ID] = &updated
+	return nil
+}
+
+// GetWebhookDelivery returns one of the user's deliveries
+func (s *Store) GetWebhookDelivery(ctx context.Context, userID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok || delivery.UserID != userID {
+		return nil, repository.ErrNotFound
+	}
+	found := copyDelivery(delivery)
+	return &found, nil
+}
+
+// ListWebhookDeliveries returns the user's matching deliveries, newest first
+func (s *Store) ListWebhookDeliveries(ctx context.Context, userID uuid.UUID, filter repository.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []model.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.UserID != userID || (filter.WebhookID != uuid.Nil && delivery.WebhookID != filter.WebhookID) {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(delivery))
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID.String() < deliveries[j].ID.String()
+	})
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+// ClaimDueWebhookDeliveries claims pending deliveries due by now until the given time
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*model.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == model.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		claimed := until
+		delivery.NextAttemptAt = &claimed
+		deliveries = append(deliveries, copyDelivery(delivery))
+	}
+	return deliveries, nil
+}
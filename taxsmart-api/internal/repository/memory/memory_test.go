@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/taxsmart/taxsmart-api/internal/repository/repotest"
+)
+
+func TestStore(t *testing.T) {
+	repotest.Run(t, New())
+}
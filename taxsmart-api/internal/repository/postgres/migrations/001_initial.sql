@@ -0,0 +1,72 @@
+CREATE TABLE users (
+    id         UUID PRIMARY KEY,
+    auth_uid   TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE uploads (
+    id                UUID PRIMARY KEY,
+    user_id           UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    filename          TEXT NOT NULL,
+    bank_format       TEXT NOT NULL,
+    transaction_count INTEGER NOT NULL DEFAULT 0,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX uploads_user_id_idx ON uploads (user_id, created_at DESC);
+
+CREATE TABLE transactions (
+    id               UUID PRIMARY KEY,
+    upload_id        UUID REFERENCES uploads (id) ON DELETE CASCADE,
+    user_id          UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    transaction_date TIMESTAMPTZ NOT NULL,
+    description      TEXT NOT NULL,
+    amount           NUMERIC(18, 2) NOT NULL,
+    transaction_type TEXT NOT NULL,
+    category         TEXT NOT NULL,
+    confidence       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    is_manual        BOOLEAN NOT NULL DEFAULT FALSE,
+    raw_data         TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX transactions_user_date_idx ON transactions (user_id, transaction_date);
+CREATE INDEX transactions_upload_id_idx ON transactions (upload_id);
+
+CREATE TABLE classifications (
+    id             UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    user_id        UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    category       TEXT NOT NULL,
+    confidence     DOUBLE PRECISION NOT NULL,
+    method         TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX classifications_transaction_id_idx ON classifications (transaction_id, created_at);
+
+CREATE TABLE tax_reports (
+    id                UUID PRIMARY KEY,
+    user_id           UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    tax_year          INTEGER NOT NULL,
+    total_income      NUMERIC(18, 2) NOT NULL,
+    employment_income NUMERIC(18, 2) NOT NULL,
+    freelance_income  NUMERIC(18, 2) NOT NULL,
+    rental_income     NUMERIC(18, 2) NOT NULL,
+    investment_income NUMERIC(18, 2) NOT NULL,
+    crypto_income     NUMERIC(18, 2) NOT NULL,
+    other_income      NUMERIC(18, 2) NOT NULL,
+    rent_relief       NUMERIC(18, 2) NOT NULL,
+    pension_deduction NUMERIC(18, 2) NOT NULL,
+    nhis_deduction    NUMERIC(18, 2) NOT NULL,
+    nhf_deduction     NUMERIC(18, 2) NOT NULL,
+    total_reliefs     NUMERIC(18, 2) NOT NULL,
+    taxable_income    NUMERIC(18, 2) NOT NULL,
+    pit_amount        NUMERIC(18, 2) NOT NULL,
+    cgt_amount        NUMERIC(18, 2) NOT NULL,
+    total_tax         NUMERIC(18, 2) NOT NULL,
+    breakdown         JSONB,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, tax_year)
+);
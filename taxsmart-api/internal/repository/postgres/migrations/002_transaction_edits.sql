@@ -0,0 +1,12 @@
+CREATE TABLE transaction_edits (
+    id             UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    user_id        UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    field          TEXT NOT NULL,
+    old_value      TEXT NOT NULL,
+    new_value      TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX transaction_edits_transaction_id_idx ON transaction_edits (transaction_id, created_at);
+CREATE INDEX transactions_user_category_idx ON transactions (user_id, category);
@@ -0,0 +1,11 @@
+CREATE TABLE user_rules (
+    id               UUID PRIMARY KEY,
+    user_id          UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key              TEXT NOT NULL,
+    transaction_type TEXT NOT NULL,
+    category         TEXT NOT NULL,
+    hits             INTEGER NOT NULL DEFAULT 1,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, key, transaction_type)
+);
@@ -0,0 +1,8 @@
+ALTER TABLE transactions
+    ADD COLUMN channel        TEXT NOT NULL DEFAULT '',
+    ADD COLUMN counterparty   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN bank_code      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN account_number TEXT NOT NULL DEFAULT '',
+    ADD COLUMN session_ref    TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX transactions_user_counterparty_idx ON transactions (user_id, counterparty);
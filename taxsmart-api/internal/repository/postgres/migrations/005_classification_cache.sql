@@ -0,0 +1,12 @@
+CREATE TABLE classification_cache (
+    key        TEXT PRIMARY KEY,
+    version    TEXT NOT NULL,
+    category   TEXT NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL,
+    method     TEXT NOT NULL,
+    rule       TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX classification_cache_version_idx ON classification_cache (version, expires_at);
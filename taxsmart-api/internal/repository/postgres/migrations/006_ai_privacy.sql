@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN external_ai_disabled BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE ai_audit_log (
+    id                UUID PRIMARY KEY,
+    user_id           UUID REFERENCES users (id) ON DELETE CASCADE,
+    provider          TEXT NOT NULL,
+    prompt            TEXT NOT NULL,
+    transaction_count INTEGER NOT NULL,
+    redactions        JSONB NOT NULL DEFAULT '{}',
+    status            TEXT NOT NULL,
+    error             TEXT NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ai_audit_log_user_id_idx ON ai_audit_log (user_id, created_at DESC);
@@ -0,0 +1,8 @@
+ALTER TABLE classifications
+    ADD COLUMN rule         TEXT NOT NULL DEFAULT '',
+    ADD COLUMN explanation  JSONB,
+    ADD COLUMN alternatives JSONB;
+
+ALTER TABLE classification_cache
+    ADD COLUMN explanation  JSONB,
+    ADD COLUMN alternatives JSONB;
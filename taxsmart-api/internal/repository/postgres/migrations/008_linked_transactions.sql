@@ -0,0 +1,4 @@
+-- Deferred so a batch can store a credit before the debit it links to
+ALTER TABLE transactions
+    ADD COLUMN linked_transaction_id UUID REFERENCES transactions (id) ON DELETE SET NULL
+        DEFERRABLE INITIALLY DEFERRED;
@@ -0,0 +1,26 @@
+-- Background jobs that parse, classify and calculate an uploaded statement.
+-- The uploaded file is kept in input until the job finishes, so queued and
+-- running jobs can be resumed after a restart.
+CREATE TABLE jobs (
+    id          UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    status      TEXT NOT NULL,
+    stage       TEXT NOT NULL DEFAULT '',
+    progress    INTEGER NOT NULL DEFAULT 0,
+    processed   INTEGER NOT NULL DEFAULT 0,
+    total       INTEGER NOT NULL DEFAULT 0,
+    filename    TEXT NOT NULL,
+    bank_format TEXT NOT NULL DEFAULT '',
+    tax_year    INTEGER NOT NULL DEFAULT 0,
+    reliefs     JSONB NOT NULL DEFAULT '{}',
+    upload_id   UUID REFERENCES uploads (id) ON DELETE SET NULL,
+    report_id   UUID,
+    error       TEXT NOT NULL DEFAULT '',
+    input       BYTEA,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX jobs_user_id_idx ON jobs (user_id, created_at DESC);
+CREATE INDEX jobs_unfinished_idx ON jobs (created_at) WHERE status IN ('queued', 'running');
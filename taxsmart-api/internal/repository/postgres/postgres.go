@@ -0,0 +1,1153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+)
+
+// Store is a PostgreSQL-backed repository
+type Store struct {
+	db *sql.DB
+}
+
+// New connects to PostgreSQL and applies pending migrations
+func New(ctx context.Context, databaseURL string) (*Store, error) {
+	db, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database connection pool
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// nullUUID maps uuid.Nil to SQL NULL
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+// linkedID stores an optional transaction link as NULL when unset
+func linkedID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return nullUUID(*id)
+}
+
+// notFound maps sql.ErrNoRows to repository.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// EnsureUser creates the user if it does not exist yet
+func (s *Store) EnsureUser(ctx context.Context, user *model.User) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO users (id, auth_uid, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO NOTHING`,
+		user.ID, user.AuthUID, user.CreatedAt)
+	return err
+}
+
+// GetUser returns a user by ID
+func (s *Store) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	var user model.User
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, auth_uid, external_ai_disabled, created_at FROM users WHERE id = $1`, id).
+		Scan(&user.ID, &user.AuthUID, &user.ExternalAIDisabled, &user.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+// UpdateUserSettings stores the user's preferences
+func (s *Store) UpdateUserSettings(ctx context.Context, user *model.User) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE users SET external_ai_disabled = $2 WHERE id = $1`, user.ID, user.ExternalAIDisabled)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// CreateUpload stores a new upload with its transactions and their
+// classifications in a single database transaction
+func (s *Store) CreateUpload(ctx context.Context, upload *model.Upload, txs []model.Transaction, classifications []model.Classification) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if _, err := dbTx.ExecContext(ctx, `
+		INSERT INTO uploads (id, user_id, filename, bank_format, transaction_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		upload.ID, upload.UserID, upload.Filename, upload.BankFormat, upload.TransactionCount, upload.CreatedAt); err != nil {
+		return err
+	}
+	if err := insertTransactions(ctx, dbTx, txs); err != nil {
+		return err
+	}
+	if err := insertClassifications(ctx, dbTx, classifications); err != nil {
+		return err
+	}
+
+	return dbTx.Commit()
+}
+
+const uploadColumns = `id, user_id, filename, bank_format, transaction_count, created_at`
+
+func scanUpload(row interface{ Scan(...any) error }) (*model.Upload, error) {
+	var upload model.Upload
+	err := row.Scan(&upload.ID, &upload.UserID, &upload.Filename, &upload.BankFormat,
+		&upload.TransactionCount, &upload.CreatedAt)
+	return &upload, err
+}
+
+// GetUpload returns one of the user's uploads
+func (s *Store) GetUpload(ctx context.Context, userID, id uuid.UUID) (*model.Upload, error) {
+	upload, err := scanUpload(s.db.QueryRowContext(ctx,
+		`SELECT `+uploadColumns+` FROM uploads WHERE id = $1 AND user_id = $2`, id, userID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return upload, nil
+}
+
+// ListUploads returns the user's uploads, newest first
+func (s *Store) ListUploads(ctx context.Context, userID uuid.UUID) ([]*model.Upload, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+uploadColumns+` FROM uploads WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uploads := []*model.Upload{}
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, rows.Err()
+}
+
+// CreateTransactions stores new transactions in a single database transaction
+func (s *Store) CreateTransactions(ctx context.Context, txs []model.Transaction) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := insertTransactions(ctx, dbTx, txs); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// insertTransactions inserts transactions as part of dbTx
+func insertTransactions(ctx context.Context, dbTx *sql.Tx, txs []model.Transaction) error {
+	stmt, err := dbTx.PrepareContext(ctx, `
+		INSERT INTO transactions (id, upload_id, user_id, transaction_date, description, amount,
+			transaction_type, category, confidence, is_manual, raw_data, created_at, channel,
+			counterparty, bank_code, account_number, session_ref, linked_transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, tx := range txs {
+		if _, err := stmt.ExecContext(ctx, tx.ID, nullUUID(tx.UploadID), tx.UserID, tx.TransactionDate,
+			tx.Description, tx.Amount, tx.TransactionType, string(tx.Category), tx.Confidence,
+			tx.IsManual, tx.RawData, tx.CreatedAt, tx.Channel, tx.Counterparty, tx.BankCode,
+			tx.AccountNumber, tx.SessionRef, linkedID(tx.LinkedTransactionID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const transactionColumns = `id, upload_id, user_id, transaction_date, description, amount,
+	transaction_type, category, confidence, is_manual, raw_data, created_at, channel,
+	counterparty, bank_code, account_number, session_ref, linked_transaction_id`
+
+func scanTransaction(row interface{ Scan(...any) error }) (model.Transaction, error) {
+	var tx model.Transaction
+	var uploadID, linked uuid.NullUUID
+	var category string
+	err := row.Scan(&tx.ID, &uploadID, &tx.UserID, &tx.TransactionDate, &tx.Description, &tx.Amount,
+		&tx.TransactionType, &category, &tx.Confidence, &tx.IsManual, &tx.RawData, &tx.CreatedAt,
+		&tx.Channel, &tx.Counterparty, &tx.BankCode, &tx.AccountNumber, &tx.SessionRef, &linked)
+	tx.UploadID = uploadID.UUID
+	if linked.Valid {
+		tx.LinkedTransactionID = &linked.UUID
+	}
+	tx.Category = model.Category(category)
+	return tx, err
+}
+
+// GetTransaction returns one of the user's transactions
+func (s *Store) GetTransaction(ctx context.Context, userID, id uuid.UUID) (*model.Transaction, error) {
+	tx, err := scanTransaction(s.db.QueryRowContext(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE id = $1 AND user_id = $2`, id, userID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &tx, nil
+}
+
+// transactionWhere builds the WHERE clause and arguments selecting the user's transactions matching the filter
+func transactionWhere(userID uuid.UUID, filter repository.TransactionFilter) (string, []any) {
+	conditions := []string{"user_id = $1"}
+	args := []any{userID}
+
+	if filter.UploadID != uuid.Nil {
+		args = append(args, filter.UploadID)
+		conditions = append(conditions, fmt.Sprintf("upload_id = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("transaction_date >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("transaction_date <= $%d", len(args)))
+	}
+	if filter.Category != "" {
+		// Underscores in category names are LIKE wildcards, so escape them
+		subcategories := strings.ReplaceAll(string(filter.Category), "_", `\_`) + ".%"
+		args = append(args, string(filter.Category), subcategories)
+		conditions = append(conditions, fmt.Sprintf("(category = $%d OR category LIKE $%d)", len(args)-1, len(args)))
+	}
+	if filter.MinConfidence != nil {
+		args = append(args, *filter.MinConfidence)
+		conditions = append(conditions, fmt.Sprintf("confidence >= $%d", len(args)))
+	}
+	if filter.Counterparty != "" {
+		args = append(args, filter.Counterparty)
+		conditions = append(conditions, fmt.Sprintf("counterparty = $%d", len(args)))
+	}
+	if filter.MaxConfidence != nil {
+		args = append(args, *filter.MaxConfidence)
+		conditions = append(conditions, fmt.Sprintf("confidence <= $%d", len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// ListTransactions returns one page of the user's transactions matching the filter, ordered by date
+func (s *Store) ListTransactions(ctx context.Context, userID uuid.UUID, filter repository.TransactionFilter) ([]model.Transaction, int, error) {
+	where, args := transactionWhere(userID, filter)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM transactions WHERE `+where, args...).
+		Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE ` + where +
+		` ORDER BY transaction_date, created_at, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	txs := []model.Transaction{}
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, total, rows.Err()
+}
+
+// ListLabelledTransactions returns manually categorized transactions of all users, newest first
+func (s *Store) ListLabelledTransactions(ctx context.Context, limit int) ([]model.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions
+		WHERE is_manual AND category <> $1 ORDER BY created_at DESC, id`
+	args := []any{string(model.CategoryUncategorized)}
+	if limit > 0 {
+		args = append(args, limit)
+		query += ` LIMIT $2`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []model.Transaction{}
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, rows.Err()
+}
+
+// ListCounterparties groups the user's matching transactions by counterparty, largest total first
+func (s *Store) ListCounterparties(ctx context.Context, userID uuid.UUID, filter repository.TransactionFilter) ([]model.CounterpartySummary, error) {
+	where, args := transactionWhere(userID, filter)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT counterparty, count(*),
+			coalesce(sum(amount) FILTER (WHERE transaction_type = 'credit'), 0),
+			coalesce(sum(amount) FILTER (WHERE transaction_type <> 'credit'), 0),
+			string_agg(DISTINCT category, ',' ORDER BY category), min(transaction_date), max(transaction_date)
+		FROM transactions WHERE `+where+` AND counterparty <> ''
+		GROUP BY counterparty
+		ORDER BY sum(amount) DESC, counterparty`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []model.CounterpartySummary{}
+	for rows.Next() {
+		var summary model.CounterpartySummary
+		var categories string
+		if err := rows.Scan(&summary.Counterparty, &summary.TransactionCount, &summary.TotalCredit,
+			&summary.TotalDebit, &categories, &summary.FirstSeen, &summary.LastSeen); err != nil {
+			return nil, err
+		}
+		summary.Categories = []model.Category{}
+		for _, category := range strings.Split(categories, ",") {
+			summary.Categories = append(summary.Categories, model.Category(category))
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
+
+// UpdateTransaction replaces a stored transaction
+func (s *Store) UpdateTransaction(ctx context.Context, tx *model.Transaction) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := updateTransaction(ctx, dbTx, tx); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// updateTransaction replaces a stored transaction as part of dbTx
+func updateTransaction(ctx context.Context, dbTx *sql.Tx, tx *model.Transaction) error {
+	result, err := dbTx.ExecContext(ctx, `
+		UPDATE transactions SET transaction_date = $3, description = $4, amount = $5,
+			transaction_type = $6, category = $7, confidence = $8, is_manual = $9, raw_data = $10,
+			channel = $11, counterparty = $12, bank_code = $13, account_number = $14, session_ref = $15,
+			linked_transaction_id = $16
+		WHERE id = $1 AND user_id = $2`,
+		tx.ID, tx.UserID, tx.TransactionDate, tx.Description, tx.Amount, tx.TransactionType,
+		string(tx.Category), tx.Confidence, tx.IsManual, tx.RawData, tx.Channel, tx.Counterparty,
+		tx.BankCode, tx.AccountNumber, tx.SessionRef, linkedID(tx.LinkedTransactionID))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteTransaction removes one of the user's transactions; its history is removed by cascade
+func (s *Store) DeleteTransaction(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM transactions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// EditTransactions stores edited transactions with their edit history,
+// classifications and learned rules in a single database transaction
+func (s *Store) EditTransactions(ctx context.Context, txs []model.Transaction, edits []model.TransactionEdit, classifications []model.Classification, rules []model.UserRule) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	for i := range txs {
+		if err := updateTransaction(ctx, dbTx, &txs[i]); err != nil {
+			return err
+		}
+	}
+	if err := insertTransactionEdits(ctx, dbTx, edits); err != nil {
+		return err
+	}
+	if err := insertClassifications(ctx, dbTx, classifications); err != nil {
+		return err
+	}
+	for i := range rules {
+		if err := upsertUserRule(ctx, dbTx, &rules[i]); err != nil {
+			return err
+		}
+	}
+
+	return dbTx.Commit()
+}
+
+// insertTransactionEdits appends to the edit history of transactions as part of dbTx
+func insertTransactionEdits(ctx context.Context, dbTx *sql.Tx, edits []model.TransactionEdit) error {
+	stmt, err := dbTx.PrepareContext(ctx, `
+		INSERT INTO transaction_edits (id, transaction_id, user_id, field, old_value, new_value, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, edit := range edits {
+		if _, err := stmt.ExecContext(ctx, edit.ID, edit.TransactionID, edit.UserID, edit.Field,
+			edit.OldValue, edit.NewValue, edit.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTransactionEdits returns the edit history of a transaction, oldest first
+func (s *Store) ListTransactionEdits(ctx context.Context, userID, transactionID uuid.UUID) ([]model.TransactionEdit, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, transaction_id, user_id, field, old_value, new_value, created_at
+		FROM transaction_edits WHERE transaction_id = $1 AND user_id = $2
+		ORDER BY created_at`, transactionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []model.TransactionEdit{}
+	for rows.Next() {
+		var edit model.TransactionEdit
+		if err := rows.Scan(&edit.ID, &edit.TransactionID, &edit.UserID, &edit.Field, &edit.OldValue,
+			&edit.NewValue, &edit.CreatedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	return edits, rows.Err()
+}
+
+// SaveClassifications appends to the classification history of transactions
+func (s *Store) SaveClassifications(ctx context.Context, classifications []model.Classification) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := insertClassifications(ctx, dbTx, classifications); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// insertClassifications inserts classifications as part of dbTx
+func insertClassifications(ctx context.Context, dbTx *sql.Tx, classifications []model.Classification) error {
+	stmt, err := dbTx.PrepareContext(ctx, `
+		INSERT INTO classifications (id, transaction_id, user_id, category, confidence, method, rule,
+			explanation, alternatives, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range classifications {
+		explanation, alternatives, err := marshalEvidence(c.Explanation, c.Alternatives)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, c.ID, c.TransactionID, c.UserID, string(c.Category),
+			c.Confidence, c.Method, c.Rule, explanation, alternatives, c.CreatedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListClassifications returns the classification history of a transaction, oldest first
+func (s *Store) ListClassifications(ctx context.Context, userID, transactionID uuid.UUID) ([]model.Classification, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, transaction_id, user_id, category, confidence, method, rule, explanation, alternatives, created_at
+		FROM classifications WHERE transaction_id = $1 AND user_id = $2
+		ORDER BY created_at`, transactionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classifications := []model.Classification{}
+	for rows.Next() {
+		var c model.Classification
+		var category string
+		var explanation, alternatives []byte
+		if err := rows.Scan(&c.ID, &c.TransactionID, &c.UserID, &category, &c.Confidence,
+			&c.Method, &c.Rule, &explanation, &alternatives, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.Category = model.Category(category)
+		if err := unmarshalEvidence(explanation, alternatives, &c.Explanation, &c.Alternatives); err != nil {
+			return nil, err
+		}
+		classifications = append(classifications, c)
+	}
+	return classifications, rows.Err()
+}
+
+// SaveUserRule stores a learned rule, or updates the category and hit count
+// of the existing rule with the same key and transaction type
+func (s *Store) SaveUserRule(ctx context.Context, rule *model.UserRule) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := upsertUserRule(ctx, dbTx, rule); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// upsertUserRule stores or updates a learned rule as part of dbTx
+func upsertUserRule(ctx context.Context, dbTx *sql.Tx, rule *model.UserRule) error {
+	_, err := dbTx.ExecContext(ctx, `
+		INSERT INTO user_rules (id, user_id, key, transaction_type, category, hits, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, key, transaction_type) DO UPDATE SET
+			category = EXCLUDED.category, hits = user_rules.hits + 1,
+			updated_at = EXCLUDED.updated_at`,
+		rule.ID, rule.UserID, rule.Key, rule.TransactionType, string(rule.Category), rule.Hits,
+		rule.CreatedAt, rule.UpdatedAt)
+	return err
+}
+
+// ListUserRules returns the user's learned rules, oldest first
+func (s *Store) ListUserRules(ctx context.Context, userID uuid.UUID) ([]model.UserRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, key, transaction_type, category, hits, created_at, updated_at
+		FROM user_rules WHERE user_id = $1
+		ORDER BY created_at, key`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []model.UserRule{}
+	for rows.Next() {
+		var rule model.UserRule
+		var category string
+		if err := rows.Scan(&rule.ID, &rule.UserID, &rule.Key, &rule.TransactionType, &category,
+			&rule.Hits, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rule.Category = model.Category(category)
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteUserRule removes one of the user's learned rules
+func (s *Store) DeleteUserRule(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM user_rules WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// GetCachedClassification returns a cached classification by key
+func (s *Store) GetCachedClassification(ctx context.Context, key string) (*model.CachedClassification, error) {
+	entry := &model.CachedClassification{}
+	var category string
+	var explanation, alternatives []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT key, version, category, confidence, method, rule, explanation, alternatives, created_at, expires_at
+		FROM classification_cache WHERE key = $1`, key).
+		Scan(&entry.Key, &entry.Version, &category, &entry.Result.Confidence, &entry.Result.Method,
+			&entry.Result.Rule, &explanation, &alternatives, &entry.CreatedAt, &entry.ExpiresAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	entry.Result.Category = model.Category(category)
+	if err := unmarshalEvidence(explanation, alternatives, &entry.Result.Explanation, &entry.Result.Alternatives); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SaveCachedClassification stores a cached classification, replacing any with the same key
+func (s *Store) SaveCachedClassification(ctx context.Context, entry *model.CachedClassification) error {
+	explanation, alternatives, err := marshalEvidence(entry.Result.Explanation, entry.Result.Alternatives)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO classification_cache (key, version, category, confidence, method, rule, explanation,
+			alternatives, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (key) DO UPDATE SET
+			version = EXCLUDED.version, category = EXCLUDED.category, confidence = EXCLUDED.confidence,
+			method = EXCLUDED.method, rule = EXCLUDED.rule, explanation = EXCLUDED.explanation,
+			alternatives = EXCLUDED.alternatives, created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at`,
+		entry.Key, entry.Version, string(entry.Result.Category), entry.Result.Confidence, entry.Result.Method,
+		entry.Result.Rule, explanation, alternatives, entry.CreatedAt, entry.ExpiresAt)
+	return err
+}
+
+// marshalEvidence encodes a classification's explanation and alternatives
+// for JSONB columns, using NULL when absent
+func marshalEvidence(explanation *model.Explanation, alternatives []model.Alternative) (interface{}, interface{}, error) {
+	var explanationJSON, alternativesJSON interface{}
+	if explanation != nil {
+		data, err := json.Marshal(explanation)
+		if err != nil {
+			return nil, nil, err
+		}
+		explanationJSON = data
+	}
+	if len(alternatives) > 0 {
+		data, err := json.Marshal(alternatives)
+		if err != nil {
+			return nil, nil, err
+		}
+		alternativesJSON = data
+	}
+	return explanationJSON, alternativesJSON, nil
+}
+
+// unmarshalEvidence decodes explanation and alternatives columns, leaving NULLs empty
+func unmarshalEvidence(explanationJSON, alternativesJSON []byte, explanation **model.Explanation, alternatives *[]model.Alternative) error {
+	if len(explanationJSON) > 0 {
+		if err := json.Unmarshal(explanationJSON, explanation); err != nil {
+			return err
+		}
+	}
+	if len(alternativesJSON) > 0 {
+		if err := json.Unmarshal(alternativesJSON, alternatives); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteStaleClassifications removes cached classifications from other versions or expired by now
+func (s *Store) DeleteStaleClassifications(ctx context.Context, version string, now time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM classification_cache WHERE version <> $1 OR expires_at <= $2`, version, now)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
+
+// ClearClassificationCache removes every cached classification
+func (s *Store) ClearClassificationCache(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM classification_cache`)
+	return err
+}
+
+// SaveAIAudit records a request sent to an AI provider
+func (s *Store) SaveAIAudit(ctx context.Context, entry *model.AIAuditEntry) error {
+	redactions, err := json.Marshal(entry.Redactions)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO ai_audit_log (id, user_id, provider, prompt, transaction_count, redactions, status, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		entry.ID, nullUUID(entry.UserID), entry.Provider, entry.Prompt, entry.TransactionCount, redactions,
+		entry.Status, entry.Error, entry.CreatedAt)
+	return err
+}
+
+// ListAIAudit returns matching AI audit entries, newest first
+func (s *Store) ListAIAudit(ctx context.Context, filter repository.AIAuditFilter) ([]model.AIAuditEntry, error) {
+	query := `
+		SELECT id, user_id, provider, prompt, transaction_count, redactions, status, error, created_at
+		FROM ai_audit_log`
+	var args []any
+	if filter.UserID != uuid.Nil {
+		args = append(args, filter.UserID)
+		query += ` WHERE user_id = $1`
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.AIAuditEntry{}
+	for rows.Next() {
+		var entry model.AIAuditEntry
+		var userID uuid.NullUUID
+		var redactions []byte
+		if err := rows.Scan(&entry.ID, &userID, &entry.Provider, &entry.Prompt, &entry.TransactionCount,
+			&redactions, &entry.Status, &entry.Error, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.UserID = userID.UUID
+		if err := json.Unmarshal(redactions, &entry.Redactions); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveReport stores a report, replacing any report for the same user and year
+func (s *Store) SaveReport(ctx context.Context, report *model.TaxReport) error {
+	breakdown, err := json.Marshal(report.Breakdown)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO tax_reports (id, user_id, tax_year, total_income, employment_income, freelance_income,
+			rental_income, investment_income, crypto_income, other_income, rent_relief, pension_deduction,
+			nhis_deduction, nhf_deduction, total_reliefs, taxable_income, pit_amount, cgt_amount, total_tax,
+			breakdown, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		ON CONFLICT (user_id, tax_year) DO UPDATE SET
+			id = EXCLUDED.id, total_income = EXCLUDED.total_income,
+			employment_income = EXCLUDED.employment_income, freelance_income = EXCLUDED.freelance_income,
+			rental_income = EXCLUDED.rental_income, investment_income = EXCLUDED.investment_income,
+			crypto_income = EXCLUDED.crypto_income, other_income = EXCLUDED.other_income,
+			rent_relief = EXCLUDED.rent_relief, pension_deduction = EXCLUDED.pension_deduction,
+			nhis_deduction = EXCLUDED.nhis_deduction, nhf_deduction = EXCLUDED.nhf_deduction,
+			total_reliefs = EXCLUDED.total_reliefs, taxable_income = EXCLUDED.taxable_income,
+			pit_amount = EXCLUDED.pit_amount, cgt_amount = EXCLUDED.cgt_amount,
+			total_tax = EXCLUDED.total_tax, breakdown = EXCLUDED.breakdown,
+			updated_at = EXCLUDED.updated_at`,
+		report.ID, report.UserID, report.TaxYear, report.TotalIncome, report.EmploymentIncome,
+		report.FreelanceIncome, report.RentalIncome, report.InvestmentIncome, report.CryptoIncome,
+		report.OtherIncome, report.RentRelief, report.PensionDeduction, report.NHISDeduction,
+		report.NHFDeduction, report.TotalReliefs, report.TaxableIncome, report.PITAmount,
+		report.CGTAmount, report.TotalTax, breakdown, report.CreatedAt, report.UpdatedAt)
+	return err
+}
+
+const reportColumns = `id, user_id, tax_year, total_income, employment_income, freelance_income,
+	rental_income, investment_income, crypto_income, other_income, rent_relief, pension_deduction,
+	nhis_deduction, nhf_deduction, total_reliefs, taxable_income, pit_amount, cgt_amount, total_tax,
+	breakdown, created_at, updated_at`
+
+func scanReport(row interface{ Scan(...any) error }) (*model.TaxReport, error) {
+	var report model.TaxReport
+	var breakdown []byte
+	err := row.Scan(&report.ID, &report.UserID, &report.TaxYear, &report.TotalIncome,
+		&report.EmploymentIncome, &report.FreelanceIncome, &report.RentalIncome,
+		&report.InvestmentIncome, &report.CryptoIncome, &report.OtherIncome, &report.RentRelief,
+		&report.PensionDeduction, &report.NHISDeduction, &report.NHFDeduction, &report.TotalReliefs,
+		&report.TaxableIncome, &report.PITAmount, &report.CGTAmount, &report.TotalTax, &breakdown,
+		&report.CreatedAt, &report.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(breakdown) > 0 {
+		if err := json.Unmarshal(breakdown, &report.Breakdown); err != nil {
+			return nil, fmt.Errorf("invalid report breakdown: %w", err)
+		}
+	}
+	return &report, nil
+}
+
+// GetReportByYear returns the user's report for a tax year
+func (s *Store) GetReportByYear(ctx context.Context, userID uuid.UUID, taxYear int) (*model.TaxReport, error) {
+	report, err := scanReport(s.db.QueryRowContext(ctx,
+		`SELECT `+reportColumns+` FROM tax_reports WHERE user_id = $1 AND tax_year = $2`, userID, taxYear))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return report, nil
+}
+
+// ListReports returns the user's reports ordered by tax year
+func (s *Store) ListReports(ctx context.Context, userID uuid.UUID) ([]*model.TaxReport, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+reportColumns+` FROM tax_reports WHERE user_id = $1 ORDER BY tax_year`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*model.TaxReport{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// CreateJob stores a new job with its input
+func (s *Store) CreateJob(ctx context.Context, job *model.Job) error {
+	reliefs, err := json.Marshal(job.Reliefs)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, user_id, status, stage, progress, processed, total, filename, bank_format,
+			tax_year, reliefs, upload_id, report_id, error, input, created_at, updated_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+		job.ID, job.UserID, job.Status, job.Stage, job.Progress, job.Processed, job.Total, job.Filename,
+		job.BankFormat, job.TaxYear, reliefs, linkedID(job.UploadID), linkedID(job.ReportID), job.Error,
+		job.Input, job.CreatedAt, job.UpdatedAt, job.FinishedAt)
+	return err
+}
+
+const jobColumns = `id, user_id, status, stage, progress, processed, total, filename, bank_format,
+	tax_year, reliefs, upload_id, report_id, error, created_at, updated_at, finished_at`
+
+func scanJob(row interface{ Scan(...any) error }, extra ...any) (*model.Job, error) {
+	var job model.Job
+	var reliefs []byte
+	var uploadID, reportID uuid.NullUUID
+	var finishedAt sql.NullTime
+	dest := []any{&job.ID, &job.UserID, &job.Status, &job.Stage, &job.Progress, &job.Processed, &job.Total,
+		&job.Filename, &job.BankFormat, &job.TaxYear, &reliefs, &uploadID, &reportID, &job.Error,
+		&job.CreatedAt, &job.UpdatedAt, &finishedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(reliefs, &job.Reliefs); err != nil {
+		return nil, fmt.Errorf("invalid job reliefs: %w", err)
+	}
+	if uploadID.Valid {
+		job.UploadID = &uploadID.UUID
+	}
+	if reportID.Valid {
+		job.ReportID = &reportID.UUID
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// GetJob returns one of the user's jobs
+func (s *Store) GetJob(ctx context.Context, userID, id uuid.UUID) (*model.Job, error) {
+	var input []byte
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+jobColumns+`, input FROM jobs WHERE id = $1 AND user_id = $2`, id, userID)
+	job, err := scanJob(row, &input)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	job.Input = input
+	return job, nil
+}
+
+// ListJobs returns the user's jobs, newest first
+func (s *Store) ListJobs(ctx context.Context, userID uuid.UUID) ([]*model.Job, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+jobColumns+` FROM jobs WHERE user_id = $1 ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*model.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpdateJob stores a job's progress, keeping its input until it has finished
+func (s *Store) UpdateJob(ctx context.Context, job *model.Job) error {
+	reliefs, err := json.Marshal(job.Reliefs)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE jobs SET status = $3, stage = $4, progress = $5, processed = $6, total = $7, bank_format = $8,
+			tax_year = $9, reliefs = $10, upload_id = $11, report_id = $12, error = $13, updated_at = $14,
+			finished_at = $15, input = CASE WHEN $16 THEN NULL ELSE input END
+		WHERE id = $1 AND user_id = $2`,
+		job.ID, job.UserID, job.Status, job.Stage, job.Progress, job.Processed, job.Total, job.BankFormat,
+		job.TaxYear, reliefs, linkedID(job.UploadID), linkedID(job.ReportID), job.Error, job.UpdatedAt,
+		job.FinishedAt, job.Finished())
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ListUnfinishedJobs returns the queued and running jobs of all users, oldest first, without their input
+func (s *Store) ListUnfinishedJobs(ctx context.Context) ([]*model.Job, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+jobColumns+` FROM jobs
+		WHERE status IN ('queued', 'running') ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*model.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// CreateWebhook stores a new webhook
+func (s *Store) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (id, user_id, url, events, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		webhook.ID, webhook.UserID, webhook.URL, events, webhook.Secret, webhook.CreatedAt)
+	return err
+}
+
+const webhookColumns = `id, user_id, url, events, secret, created_at`
+
+func scanWebhook(row interface{ Scan(...any) error }) (*model.Webhook, error) {
+	var webhook model.Webhook
+	var events []byte
+	if err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return nil, fmt.Errorf("invalid webhook events: %w", err)
+	}
+	return &webhook, nil
+}
+
+// GetWebhook returns one of the user's webhooks
+func (s *Store) GetWebhook(ctx context.Context, userID, id uuid.UUID) (*model.Webhook, error) {
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the user's webhooks, oldest first
+func (s *Store) ListWebhooks(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes one of the user's webhooks and its deliveries
+func (s *Store) DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// CreateWebhookDelivery stores a new delivery
+func (s *Store) CreateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, webhook_id, user_id, event, payload, status, attempts, response_status,
+			error, replay_of, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		delivery.ID, delivery.WebhookID, delivery.UserID, delivery.Event, []byte(delivery.Payload), delivery.Status,
+		delivery.Attempts, delivery.ResponseStatus, delivery.Error, linkedID(delivery.ReplayOf),
+		delivery.NextAttemptAt, delivery.CreatedAt, delivery.UpdatedAt)
+	return err
+}
+
+// UpdateWebhookDelivery stores the outcome of an attempt at a delivery
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET status = $3, attempts = $4, response_status = $5, error = $6,
+			next_attempt_at = $7, updated_at = $8
+		WHERE id = $1 AND user_id = $2`,
+		delivery.ID, delivery.UserID, delivery.Status, delivery.Attempts, delivery.ResponseStatus,
+		delivery.Error, delivery.NextAttemptAt, delivery.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+const deliveryColumns = `id, webhook_id, user_id, event, payload, status, attempts, response_status, error,
+	replay_of, next_attempt_at, created_at, updated_at`
+
+func scanDelivery(row interface{ Scan(...any) error }) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	var payload []byte
+	var replayOf uuid.NullUUID
+	var nextAttemptAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.UserID, &delivery.Event, &payload,
+		&delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.Error, &replayOf,
+		&nextAttemptAt, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	if replayOf.Valid {
+		delivery.ReplayOf = &replayOf.UUID
+	}
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDelivery returns one of the user's deliveries
+func (s *Store) GetWebhookDelivery(ctx context.Context, userID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	delivery, err := scanDelivery(s.db.QueryRowContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1 AND user_id = $2`, id, userID))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return delivery, nil
+}
+
+// ListWebhookDeliveries returns the user's matching deliveries, newest first
+func (s *Store) ListWebhookDeliveries(ctx context.Context, userID uuid.UUID, filter repository.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE user_id = $1`
+	args := []any{userID}
+	if filter.WebhookID != uuid.Nil {
+		args = append(args, filter.WebhookID)
+		query += fmt.Sprintf(` AND webhook_id = $%d`, len(args))
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	return s.queryDeliveries(ctx, query, args...)
+}
+
+// ClaimDueWebhookDeliveries claims pending deliveries due by now until the
+// given time. Rows being claimed by another caller are skipped rather than
+// waited for.
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]model.WebhookDelivery, error) {
+	due := `SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at, id`
+	args := []any{now, until}
+	if limit > 0 {
+		args = append(args, limit)
+		due += ` LIMIT $3`
+	}
+	return s.queryDeliveries(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (`+due+` FOR UPDATE SKIP LOCKED)
+		RETURNING `+deliveryColumns, args...)
+}
+
+// queryDeliveries runs a query selecting deliveryColumns
+func (s *Store) queryDeliveries(ctx context.Context, query string, args ...any) ([]model.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
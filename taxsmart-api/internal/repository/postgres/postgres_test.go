@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/taxsmart/taxsmart-api/internal/repository/repotest"
+)
+
+// TestStore runs against a real database when TEST_DATABASE_URL is set
+func TestStore(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	store, err := New(context.Background(), databaseURL)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer store.Close()
+
+	repotest.Run(t, store)
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// Repository provides persistence for everything a user stores with us
+type Repository interface {
+	UserRepository
+	UploadRepository
+	TransactionRepository
+	ClassificationRepository
+	UserRuleRepository
+	ClassificationCacheRepository
+	AIAuditRepository
+	ReportRepository
+	JobRepository
+	WebhookRepository
+	Close() error
+}
+
+// UserRepository stores users
+type UserRepository interface {
+	// EnsureUser creates the user if it does not exist yet
+	EnsureUser(ctx context.Context, user *model.User) error
+	GetUser(ctx context.Context, id uuid.UUID) (*model.User, error)
+	// UpdateUserSettings stores the user's preferences, such as ExternalAIDisabled
+	UpdateUserSettings(ctx context.Context, user *model.User) error
+}
+
+// UploadRepository stores uploaded statements
+type UploadRepository interface {
+	// CreateUpload stores a new upload with its transactions and their
+	// classifications atomically, so a failed import leaves nothing behind
+	CreateUpload(ctx context.Context, upload *model.Upload, txs []model.Transaction, classifications []model.Classification) error
+	GetUpload(ctx context.Context, userID, id uuid.UUID) (*model.Upload, error)
+	ListUploads(ctx context.Context, userID uuid.UUID) ([]*model.Upload, error)
+}
+
+// TransactionFilter narrows a transaction listing. Zero values are ignored.
+type TransactionFilter struct {
+	UploadID      uuid.UUID
+	From          time.Time
+	To            time.Time
+	Category      model.Category // Matches its subcategories too
+	Counterparty  string
+	MinConfidence *float64
+	MaxConfidence *float64
+	Limit         int // 0 returns every match
+	Offset        int
+}
+
+// TransactionRepository stores a user's transactions and their edit history
+type TransactionRepository interface {
+	CreateTransactions(ctx context.Context, txs []model.Transaction) error
+	GetTransaction(ctx context.Context, userID, id uuid.UUID) (*model.Transaction, error)
+	// ListTransactions returns one page of matches, ordered by date, and the total number of matches
+	ListTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter) ([]model.Transaction, int, error)
+	UpdateTransaction(ctx context.Context, tx *model.Transaction) error
+	DeleteTransaction(ctx context.Context, userID, id uuid.UUID) error
+	// EditTransactions stores edited transactions together with their edit
+	// history, manual classifications and the rules learned from them
+	// atomically, so a failed edit leaves nothing behind
+	EditTransactions(ctx context.Context, txs []model.Transaction, edits []model.TransactionEdit, classifications []model.Classification, rules []model.UserRule) error
+	ListTransactionEdits(ctx context.Context, userID, transactionID uuid.UUID) ([]model.TransactionEdit, error)
+	// ListCounterparties groups the transactions matching the filter by counterparty,
+	// largest total first. Transactions without a counterparty and paging are ignored.
+	ListCounterparties(ctx context.Context, userID uuid.UUID, filter TransactionFilter) ([]model.CounterpartySummary, error)
+	// ListLabelledTransactions returns manually categorized transactions of
+	// all users, most recently created first, up to limit when it is positive
+	ListLabelledTransactions(ctx context.Context, limit int) ([]model.Transaction, error)
+}
+
+// ClassificationRepository stores the classification history of transactions
+type ClassificationRepository interface {
+	SaveClassifications(ctx context.Context, classifications []model.Classification) error
+	ListClassifications(ctx context.Context, userID, transactionID uuid.UUID) ([]model.Classification, error)
+}
+
+// UserRuleRepository stores classification rules learned from a user's
+// corrections. A rule is identified by its user, key and transaction type;
+// saving it again updates the category and counts another hit.
+type UserRuleRepository interface {
+	SaveUserRule(ctx context.Context, rule *model.UserRule) error
+	ListUserRules(ctx context.Context, userID uuid.UUID) ([]model.UserRule, error)
+	DeleteUserRule(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// ClassificationCacheRepository is the persistent tier of the classification
+// cache. Entries are shared by all users and replaced when saved again under
+// the same key.
+type ClassificationCacheRepository interface {
+	GetCachedClassification(ctx context.Context, key string) (*model.CachedClassification, error)
+	SaveCachedClassification(ctx context.Context, entry *model.CachedClassification) error
+	// DeleteStaleClassifications removes entries from other versions and
+	// entries expired by now, returning how many were removed
+	DeleteStaleClassifications(ctx context.Context, version string, now time.Time) (int, error)
+	ClearClassificationCache(ctx context.Context) error
+}
+
+// AIAuditFilter narrows an AI audit listing. Zero values are ignored.
+type AIAuditFilter struct {
+	UserID uuid.UUID
+	Limit  int // 0 returns every entry
+}
+
+// AIAuditRepository stores the log of requests sent to AI providers
+type AIAuditRepository interface {
+	SaveAIAudit(ctx context.Context, entry *model.AIAuditEntry) error
+	// ListAIAudit returns matching entries, newest first
+	ListAIAudit(ctx context.Context, filter AIAuditFilter) ([]model.AIAuditEntry, error)
+}
+
+// ReportRepository stores calculated tax reports. A user has at most one
+// report per tax year; saving a report for the same year replaces it.
+type ReportRepository interface {
+	SaveReport(ctx context.Context, report *model.TaxReport) error
+	GetReportByYear(ctx context.Context, userID uuid.UUID, taxYear int) (*model.TaxReport, error)
+	ListReports(ctx context.Context, userID uuid.UUID) ([]*model.TaxReport, error)
+}
+
+// JobRepository stores background processing jobs. A job's input is only
+// written when it is created, and dropped once the job has finished.
+type JobRepository interface {
+	CreateJob(ctx context.Context, job *model.Job) error
+	GetJob(ctx context.Context, userID, id uuid.UUID) (*model.Job, error)
+	// ListJobs returns the user's jobs, newest first, without their input
+	ListJobs(ctx context.Context, userID uuid.UUID) ([]*model.Job, error)
+	UpdateJob(ctx context.Context, job *model.Job) error
+	// ListUnfinishedJobs returns the queued and running jobs of all users,
+	// oldest first, without their input
+	ListUnfinishedJobs(ctx context.Context) ([]*model.Job, error)
+}
+
+// WebhookDeliveryFilter narrows a webhook delivery listing. Zero values are ignored.
+type WebhookDeliveryFilter struct {
+	WebhookID uuid.UUID
+	Limit     int // 0 returns every delivery
+}
+
+// WebhookRepository stores the webhooks users subscribe and the log of
+// deliveries to them. Deleting a webhook deletes its deliveries.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *model.Webhook) error
+	GetWebhook(ctx context.Context, userID, id uuid.UUID) (*model.Webhook, error)
+	// ListWebhooks returns the user's webhooks, oldest first
+	ListWebhooks(ctx context.Context, userID uuid.UUID) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, id uuid.UUID) error
+	CreateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, userID, id uuid.UUID) (*model.WebhookDelivery, error)
+	// ListWebhookDeliveries returns the user's matching deliveries, newest first
+	ListWebhookDeliveries(ctx context.Context, userID uuid.UUID, filter WebhookDeliveryFilter) ([]model.WebhookDelivery, error)
+	// ClaimDueWebhookDeliveries claims the pending deliveries of all users due
+	// by now, longest due first and up to limit when it is positive, by moving
+	// their next attempt to until. Other callers cannot claim them again before
+	// then, so a delivery is sent by one dispatcher at a time.
+	ClaimDueWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]model.WebhookDelivery, error)
+}
@@ -0,0 +1,612 @@
+// Package repotest provides a conformance suite that every repository implementation must pass
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+)
+
+// Run exercises the repository against the behaviour handlers rely on.
+// Every run uses fresh IDs, so it is safe against a shared database.
+func Run(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	user := &model.User{ID: uuid.New(), AuthUID: "uid-" + uuid.NewString(), CreatedAt: now}
+	other := &model.User{ID: uuid.New(), AuthUID: "uid-" + uuid.NewString(), CreatedAt: now}
+	for _, u := range []*model.User{user, other, user} {
+		if err := repo.EnsureUser(ctx, u); err != nil {
+			t.Fatalf("EnsureUser failed: %v", err)
+		}
+	}
+	if got, err := repo.GetUser(ctx, user.ID); err != nil || got.AuthUID != user.AuthUID {
+		t.Fatalf("GetUser returned %+v, %v", got, err)
+	}
+
+	t.Run("Uploads and transactions", func(t *testing.T) {
+		upload := &model.Upload{ID: uuid.New(), UserID: user.ID, Filename: "jan.csv", BankFormat: "gtbank", TransactionCount: 2, CreatedAt: now}
+		txs := []model.Transaction{
+			{ID: uuid.New(), UploadID: upload.ID, UserID: user.ID, TransactionDate: now.AddDate(0, 0, -1), Description: "SALARY", Amount: 500_000, TransactionType: "credit", Category: model.CategoryEmployment, Confidence: 0.85, CreatedAt: now},
+			{ID: uuid.New(), UploadID: upload.ID, UserID: user.ID, TransactionDate: now, Description: "POS/SHOPRITE", Amount: 12_500.5, TransactionType: "debit", Category: model.CategoryExpense, Confidence: 0.7, CreatedAt: now},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now.AddDate(0, -2, 0), Description: "CASH GIFT", Amount: 20_000, TransactionType: "credit", Category: model.CategoryOtherIncome, IsManual: true, CreatedAt: now},
+		}
+		imported := []model.Classification{{ID: uuid.New(), TransactionID: txs[0].ID, UserID: user.ID, Category: model.CategoryEmployment, Confidence: 0.85, Method: "rules", CreatedAt: now}}
+		if err := repo.CreateUpload(ctx, upload, txs[:2], imported); err != nil {
+			t.Fatalf("CreateUpload failed: %v", err)
+		}
+		if err := repo.CreateTransactions(ctx, txs[2:]); err != nil {
+			t.Fatalf("CreateTransactions failed: %v", err)
+		}
+		if history, err := repo.ListClassifications(ctx, user.ID, txs[0].ID); err != nil || len(history) != 1 || history[0].Method != "rules" {
+			t.Errorf("Expected the upload's classification to be stored, got %+v, %v", history, err)
+		}
+
+		uploads, err := repo.ListUploads(ctx, user.ID)
+		if err != nil || len(uploads) != 1 || uploads[0].Filename != "jan.csv" {
+			t.Fatalf("ListUploads returned %+v, %v", uploads, err)
+		}
+		if _, err := repo.GetUpload(ctx, other.ID, upload.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's upload, got %v", err)
+		}
+
+		byUpload, _, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{UploadID: upload.ID})
+		if err != nil || len(byUpload) != 2 {
+			t.Fatalf("Expected 2 transactions for the upload, got %d, %v", len(byUpload), err)
+		}
+		if byUpload[0].Description != "SALARY" || byUpload[1].Amount != 12_500.5 {
+			t.Errorf("Unexpected transactions %+v", byUpload)
+		}
+
+		all, total, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{From: now.AddDate(0, -3, 0)})
+		if err != nil || total != 3 || len(all) != 3 || !all[0].IsManual || all[0].UploadID != uuid.Nil {
+			t.Fatalf("Expected 3 transactions starting with the manual one, got %+v, %v", all, err)
+		}
+
+		minConfidence := 0.8
+		confident, total, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{MinConfidence: &minConfidence})
+		if err != nil || total != 1 || confident[0].Description != "SALARY" {
+			t.Errorf("Expected only the salary above 0.8 confidence, got %+v, %v", confident, err)
+		}
+
+		page, total, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{Limit: 2, Offset: 1})
+		if err != nil || total != 3 || len(page) != 2 || page[0].Description != "SALARY" {
+			t.Errorf("Expected the second page to start with the salary, got %+v (total %d), %v", page, total, err)
+		}
+
+		expenses, total, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{Category: model.CategoryExpense})
+		if err != nil || total != 1 || expenses[0].Category != model.CategoryExpense {
+			t.Errorf("Expected a single expense, got %+v, %v", expenses, err)
+		}
+
+		updated := byUpload[1]
+		updated.Category = model.CategoryRentExpense
+		updated.IsManual = true
+		if err := repo.UpdateTransaction(ctx, &updated); err != nil {
+			t.Fatalf("UpdateTransaction failed: %v", err)
+		}
+		got, err := repo.GetTransaction(ctx, user.ID, updated.ID)
+		if err != nil || got.Category != model.CategoryRentExpense || !got.IsManual {
+			t.Errorf("GetTransaction returned %+v, %v", got, err)
+		}
+		if _, err := repo.GetTransaction(ctx, other.ID, updated.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's transaction, got %v", err)
+		}
+
+		explanation := &model.Explanation{Summary: "matched", Pattern: "RENT", Field: "description", Matched: "RENT", Span: &model.Span{Start: 4, End: 8}}
+		alternatives := []model.Alternative{{Category: model.CategoryRentExpense, Score: 0.6}}
+		classifications := []model.Classification{
+			{ID: uuid.New(), TransactionID: updated.ID, UserID: user.ID, Category: model.CategoryExpense, Confidence: 0.7, Method: "rules",
+				Rule: "default/rent", Explanation: explanation, Alternatives: alternatives, CreatedAt: now},
+			{ID: uuid.New(), TransactionID: updated.ID, UserID: user.ID, Category: model.CategoryRentExpense, Confidence: 1, Method: "manual", CreatedAt: now.Add(time.Second)},
+		}
+		if err := repo.SaveClassifications(ctx, classifications); err != nil {
+			t.Fatalf("SaveClassifications failed: %v", err)
+		}
+		history, err := repo.ListClassifications(ctx, user.ID, updated.ID)
+		if err != nil || len(history) != 2 || history[1].Method != "manual" {
+			t.Fatalf("ListClassifications returned %+v, %v", history, err)
+		}
+		if history[0].Rule != "default/rent" || !reflect.DeepEqual(history[0].Explanation, explanation) || !reflect.DeepEqual(history[0].Alternatives, alternatives) {
+			t.Errorf("Expected the evidence to round-trip, got %+v", history[0])
+		}
+		if history[1].Explanation != nil || history[1].Alternatives != nil {
+			t.Errorf("Expected no evidence for a manual classification, got %+v", history[1])
+		}
+
+		edited := updated
+		edited.Category = model.CategoryRentExpense
+		edited.Description = "RENT JAN"
+		edits := []model.TransactionEdit{
+			{ID: uuid.New(), TransactionID: updated.ID, UserID: user.ID, Field: "description", OldValue: updated.Description, NewValue: "RENT JAN", CreatedAt: now},
+		}
+		manual := []model.Classification{
+			{ID: uuid.New(), TransactionID: updated.ID, UserID: user.ID, Category: model.CategoryRentExpense, Confidence: 1, Method: "manual", CreatedAt: now.Add(2 * time.Second)},
+		}
+		learned := []model.UserRule{
+			{ID: uuid.New(), UserID: user.ID, Key: "RENT JAN", TransactionType: updated.TransactionType, Category: model.CategoryRentExpense, Hits: 1, CreatedAt: now, UpdatedAt: now},
+		}
+		if err := repo.EditTransactions(ctx, []model.Transaction{edited}, edits, manual, learned); err != nil {
+			t.Fatalf("EditTransactions failed: %v", err)
+		}
+		if got, err := repo.GetTransaction(ctx, user.ID, updated.ID); err != nil || got.Description != "RENT JAN" {
+			t.Errorf("GetTransaction returned %+v, %v, want the edit stored", got, err)
+		}
+		if got, err := repo.ListTransactionEdits(ctx, user.ID, updated.ID); err != nil || len(got) != 1 || got[0].NewValue != "RENT JAN" {
+			t.Errorf("ListTransactionEdits returned %+v, %v", got, err)
+		}
+		if got, err := repo.ListClassifications(ctx, user.ID, updated.ID); err != nil || len(got) != 3 {
+			t.Errorf("ListClassifications returned %+v, %v, want the manual classification added", got, err)
+		}
+
+		// An edit naming another user's transaction stores none of its parts
+		foreign := edited
+		foreign.ID = uuid.New()
+		unsaved := []model.TransactionEdit{{ID: uuid.New(), TransactionID: updated.ID, UserID: user.ID, Field: "amount", OldValue: "1", NewValue: "2", CreatedAt: now}}
+		unlearned := []model.UserRule{{ID: uuid.New(), UserID: user.ID, Key: "NEVER STORED", TransactionType: "debit", Category: model.CategoryExpense, Hits: 1, CreatedAt: now, UpdatedAt: now}}
+		edited.Amount = 1
+		if err := repo.EditTransactions(ctx, []model.Transaction{edited, foreign}, unsaved, nil, unlearned); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound editing a missing transaction, got %v", err)
+		}
+		if got, err := repo.GetTransaction(ctx, user.ID, updated.ID); err != nil || got.Amount == 1 {
+			t.Errorf("GetTransaction returned %+v, %v, want the failed edit not stored", got, err)
+		}
+		if got, err := repo.ListTransactionEdits(ctx, user.ID, updated.ID); err != nil || len(got) != 1 {
+			t.Errorf("ListTransactionEdits returned %+v, %v, want the failed edit's history not stored", got, err)
+		}
+		rules, err := repo.ListUserRules(ctx, user.ID)
+		if err != nil || len(rules) != 1 || rules[0].Key != "RENT JAN" {
+			t.Errorf("ListUserRules returned %+v, %v, want only the first edit's rule", rules, err)
+		}
+		for _, rule := range rules {
+			if err := repo.DeleteUserRule(ctx, user.ID, rule.ID); err != nil {
+				t.Fatalf("DeleteUserRule failed: %v", err)
+			}
+		}
+
+		if err := repo.DeleteTransaction(ctx, other.ID, updated.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting another user's transaction, got %v", err)
+		}
+		if err := repo.DeleteTransaction(ctx, user.ID, updated.ID); err != nil {
+			t.Fatalf("DeleteTransaction failed: %v", err)
+		}
+		if _, err := repo.GetTransaction(ctx, user.ID, updated.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+	})
+
+	t.Run("Counterparties", func(t *testing.T) {
+		acme := model.Narration{Channel: model.ChannelNIP, Counterparty: "ACME LTD", BankCode: "GTB", AccountNumber: "0123456789"}
+		txs := []model.Transaction{
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now.AddDate(0, -1, 0), Description: "NIP/GTB/ACME LTD/SALARY", Amount: 300_000, TransactionType: "credit", Category: model.CategoryEmployment, CreatedAt: now, Narration: acme},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "NIP/GTB/ACME LTD/BONUS", Amount: 50_000, TransactionType: "credit", Category: model.CategoryOtherIncome, CreatedAt: now, Narration: acme},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "TRF TO ACME LTD", Amount: 10_000, TransactionType: "debit", Category: model.CategoryExpense, CreatedAt: now, Narration: acme},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "POS/SHOPRITE", Amount: 5_000, TransactionType: "debit", Category: model.CategoryExpense, CreatedAt: now, Narration: model.Narration{Channel: model.ChannelPOS, Counterparty: "SHOPRITE"}},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "NIP/GTB/ACME LTD/LOAN", Amount: 80_000, TransactionType: "credit", Category: model.CategoryLoan, CreatedAt: now, Narration: acme},
+		}
+		if err := repo.CreateTransactions(ctx, txs); err != nil {
+			t.Fatalf("CreateTransactions failed: %v", err)
+		}
+
+		got, err := repo.GetTransaction(ctx, user.ID, txs[0].ID)
+		if err != nil || got.Narration != acme {
+			t.Errorf("Expected the narration fields to round-trip, got %+v, %v", got, err)
+		}
+
+		summaries, err := repo.ListCounterparties(ctx, user.ID, repository.TransactionFilter{})
+		if err != nil || len(summaries) != 2 {
+			t.Fatalf("Expected 2 counterparties, got %+v, %v", summaries, err)
+		}
+		first := summaries[0]
+		if first.Counterparty != "ACME LTD" || first.TransactionCount != 4 || first.TotalCredit != 430_000 || first.TotalDebit != 10_000 {
+			t.Errorf("Unexpected ACME summary %+v", first)
+		}
+		if len(first.Categories) != 4 || first.Categories[0] != model.CategoryEmployment {
+			t.Errorf("Expected sorted categories, got %v", first.Categories)
+		}
+		if !first.FirstSeen.Equal(txs[0].TransactionDate) || !first.LastSeen.Equal(now) {
+			t.Errorf("Unexpected first and last seen %v %v", first.FirstSeen, first.LastSeen)
+		}
+
+		credits, _, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{Counterparty: "ACME LTD", Category: model.CategoryEmployment})
+		if err != nil || len(credits) != 1 || credits[0].ID != txs[0].ID {
+			t.Errorf("Expected the ACME salary, got %+v, %v", credits, err)
+		}
+
+		// A category filter matches its subcategories
+		transfers, _, err := repo.ListTransactions(ctx, user.ID, repository.TransactionFilter{Category: model.CategoryTransfer})
+		if err != nil || len(transfers) != 1 || transfers[0].Category != model.CategoryLoan {
+			t.Errorf("Expected the ACME loan under transfers, got %+v, %v", transfers, err)
+		}
+
+		for _, tx := range txs {
+			if err := repo.DeleteTransaction(ctx, user.ID, tx.ID); err != nil {
+				t.Fatalf("DeleteTransaction failed: %v", err)
+			}
+		}
+	})
+
+	t.Run("Labelled transactions", func(t *testing.T) {
+		// Created in the future so they come first even in a shared database
+		later := now.AddDate(1, 0, 0)
+		txs := []model.Transaction{
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "UPWORK ESCROW", Amount: 90_000, TransactionType: "credit", Category: model.CategoryFreelance, IsManual: true, CreatedAt: later},
+			{ID: uuid.New(), UserID: other.ID, TransactionDate: now, Description: "RENT JAN", Amount: 150_000, TransactionType: "debit", Category: model.CategoryRentExpense, IsManual: true, CreatedAt: later.Add(time.Second)},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "TRF FROM TUNDE", Amount: 5_000, TransactionType: "credit", Category: model.CategoryUncategorized, IsManual: true, CreatedAt: later.Add(2 * time.Second)},
+			{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "SALARY", Amount: 400_000, TransactionType: "credit", Category: model.CategoryEmployment, CreatedAt: later.Add(3 * time.Second)},
+		}
+		if err := repo.CreateTransactions(ctx, txs); err != nil {
+			t.Fatalf("CreateTransactions failed: %v", err)
+		}
+
+		labelled, err := repo.ListLabelledTransactions(ctx, 2)
+		if err != nil || len(labelled) != 2 {
+			t.Fatalf("Expected 2 labelled transactions, got %+v, %v", labelled, err)
+		}
+		if labelled[0].ID != txs[1].ID || labelled[1].ID != txs[0].ID {
+			t.Errorf("Expected the manual categorized transactions of both users, newest first, got %+v", labelled)
+		}
+
+		for _, tx := range txs {
+			if err := repo.DeleteTransaction(ctx, tx.UserID, tx.ID); err != nil {
+				t.Fatalf("DeleteTransaction failed: %v", err)
+			}
+		}
+	})
+
+	t.Run("Linked transactions", func(t *testing.T) {
+		debit := model.Transaction{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "TRF TO ADA", Amount: 20_000, TransactionType: "debit", Category: model.CategoryTransfer, CreatedAt: now}
+		reversal := model.Transaction{ID: uuid.New(), UserID: user.ID, TransactionDate: now, Description: "RVSL TRF TO ADA", Amount: 20_000, TransactionType: "credit", Category: model.CategoryReversal, CreatedAt: now, LinkedTransactionID: &debit.ID}
+		// The credit comes first, as in a statement listed newest first
+		if err := repo.CreateTransactions(ctx, []model.Transaction{reversal, debit}); err != nil {
+			t.Fatalf("CreateTransactions failed: %v", err)
+		}
+
+		got, err := repo.GetTransaction(ctx, user.ID, reversal.ID)
+		if err != nil || got.LinkedTransactionID == nil || *got.LinkedTransactionID != debit.ID {
+			t.Fatalf("Expected the reversal linked to its debit, got %+v, %v", got, err)
+		}
+		if got, err := repo.GetTransaction(ctx, user.ID, debit.ID); err != nil || got.LinkedTransactionID != nil {
+			t.Errorf("Expected the debit unlinked, got %+v, %v", got, err)
+		}
+
+		if err := repo.DeleteTransaction(ctx, user.ID, debit.ID); err != nil {
+			t.Fatalf("DeleteTransaction failed: %v", err)
+		}
+		if got, err := repo.GetTransaction(ctx, user.ID, reversal.ID); err != nil || got.LinkedTransactionID != nil {
+			t.Errorf("Expected the link cleared with its debit, got %+v, %v", got, err)
+		}
+		if err := repo.DeleteTransaction(ctx, user.ID, reversal.ID); err != nil {
+			t.Fatalf("DeleteTransaction failed: %v", err)
+		}
+	})
+
+	t.Run("User rules", func(t *testing.T) {
+		rule := &model.UserRule{ID: uuid.New(), UserID: user.ID, Key: "ACME LTD", TransactionType: "credit", Category: model.CategoryFreelance, Hits: 1, CreatedAt: now, UpdatedAt: now}
+		if err := repo.SaveUserRule(ctx, rule); err != nil {
+			t.Fatalf("SaveUserRule failed: %v", err)
+		}
+
+		// Learning the same key again updates the category and counts a hit
+		relearned := &model.UserRule{ID: uuid.New(), UserID: user.ID, Key: "ACME LTD", TransactionType: "credit", Category: model.CategoryEmployment, Hits: 1, CreatedAt: now.Add(time.Second), UpdatedAt: now.Add(time.Second)}
+		if err := repo.SaveUserRule(ctx, relearned); err != nil {
+			t.Fatalf("SaveUserRule failed: %v", err)
+		}
+
+		rules, err := repo.ListUserRules(ctx, user.ID)
+		if err != nil || len(rules) != 1 {
+			t.Fatalf("Expected 1 rule, got %+v, %v", rules, err)
+		}
+		if rules[0].ID != rule.ID || rules[0].Category != model.CategoryEmployment || rules[0].Hits != 2 {
+			t.Errorf("Expected the rule to be updated in place, got %+v", rules[0])
+		}
+
+		if others, err := repo.ListUserRules(ctx, other.ID); err != nil || len(others) != 0 {
+			t.Errorf("Expected no rules for another user, got %+v, %v", others, err)
+		}
+		if err := repo.DeleteUserRule(ctx, other.ID, rule.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting another user's rule, got %v", err)
+		}
+		if err := repo.DeleteUserRule(ctx, user.ID, rule.ID); err != nil {
+			t.Fatalf("DeleteUserRule failed: %v", err)
+		}
+		if rules, _ := repo.ListUserRules(ctx, user.ID); len(rules) != 0 {
+			t.Errorf("Expected no rules after delete, got %+v", rules)
+		}
+	})
+
+	t.Run("Classification cache", func(t *testing.T) {
+		version := "v-" + uuid.NewString()
+		current := &model.CachedClassification{
+			Key: uuid.NewString(), Version: version, CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+			Result: model.ClassificationResult{
+				Category: model.CategoryEmployment, Confidence: 0.9, Method: "ai",
+				Explanation:  &model.Explanation{Summary: "Classified by openai/gpt-4o-mini", Rationale: "SALARY names an employer"},
+				Alternatives: []model.Alternative{{Category: model.CategoryFreelance, Score: 0.2}},
+			},
+		}
+		expired := &model.CachedClassification{Key: uuid.NewString(), Version: version, CreatedAt: now, ExpiresAt: now.Add(-time.Hour)}
+		outdated := &model.CachedClassification{Key: uuid.NewString(), Version: "old-" + version, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		for _, entry := range []*model.CachedClassification{current, expired, outdated} {
+			if err := repo.SaveCachedClassification(ctx, entry); err != nil {
+				t.Fatalf("SaveCachedClassification failed: %v", err)
+			}
+		}
+
+		// Saving again under the same key replaces the entry
+		current.Result.Category = model.CategoryFreelance
+		if err := repo.SaveCachedClassification(ctx, current); err != nil {
+			t.Fatalf("SaveCachedClassification failed: %v", err)
+		}
+		got, err := repo.GetCachedClassification(ctx, current.Key)
+		if err != nil {
+			t.Fatalf("GetCachedClassification failed: %v", err)
+		}
+		if got.Version != version || !reflect.DeepEqual(got.Result, current.Result) || !got.ExpiresAt.Equal(current.ExpiresAt) {
+			t.Errorf("Expected the replaced entry to round-trip, got %+v", got)
+		}
+
+		removed, err := repo.DeleteStaleClassifications(ctx, version, now)
+		if err != nil || removed < 2 {
+			t.Errorf("Expected the expired and outdated entries to be removed, got %d, %v", removed, err)
+		}
+		for _, key := range []string{expired.Key, outdated.Key} {
+			if _, err := repo.GetCachedClassification(ctx, key); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("Expected ErrNotFound for a stale entry, got %v", err)
+			}
+		}
+
+		if err := repo.ClearClassificationCache(ctx); err != nil {
+			t.Fatalf("ClearClassificationCache failed: %v", err)
+		}
+		if _, err := repo.GetCachedClassification(ctx, current.Key); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after clearing, got %v", err)
+		}
+	})
+
+	t.Run("User settings", func(t *testing.T) {
+		settings := *other
+		settings.ExternalAIDisabled = true
+		if err := repo.UpdateUserSettings(ctx, &settings); err != nil {
+			t.Fatalf("UpdateUserSettings failed: %v", err)
+		}
+		got, err := repo.GetUser(ctx, other.ID)
+		if err != nil || !got.ExternalAIDisabled {
+			t.Errorf("Expected external AI to be disabled, got %+v, %v", got, err)
+		}
+
+		unknown := &model.User{ID: uuid.New()}
+		if err := repo.UpdateUserSettings(ctx, unknown); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for an unknown user, got %v", err)
+		}
+	})
+
+	t.Run("AI audit", func(t *testing.T) {
+		for i, count := range []int{3, 5} {
+			entry := &model.AIAuditEntry{
+				ID: uuid.New(), UserID: user.ID, Provider: "openai/gpt-4o-mini", Prompt: "1. [NAME_1]",
+				TransactionCount: count, Redactions: map[string]int{"NAME": 1}, Status: "ok",
+				CreatedAt: now.Add(time.Duration(i) * time.Minute),
+			}
+			if err := repo.SaveAIAudit(ctx, entry); err != nil {
+				t.Fatalf("SaveAIAudit failed: %v", err)
+			}
+		}
+		if err := repo.SaveAIAudit(ctx, &model.AIAuditEntry{ID: uuid.New(), UserID: other.ID, Status: "error", Error: "quota", CreatedAt: now}); err != nil {
+			t.Fatalf("SaveAIAudit failed: %v", err)
+		}
+
+		entries, err := repo.ListAIAudit(ctx, repository.AIAuditFilter{UserID: user.ID})
+		if err != nil || len(entries) != 2 {
+			t.Fatalf("Expected 2 entries for the user, got %d, %v", len(entries), err)
+		}
+		if entries[0].TransactionCount != 5 || entries[0].Redactions["NAME"] != 1 || entries[0].Prompt != "1. [NAME_1]" {
+			t.Errorf("Expected the newest entry first with its details, got %+v", entries[0])
+		}
+
+		if limited, err := repo.ListAIAudit(ctx, repository.AIAuditFilter{UserID: user.ID, Limit: 1}); err != nil || len(limited) != 1 {
+			t.Errorf("Expected the limit to apply, got %d, %v", len(limited), err)
+		}
+	})
+
+	t.Run("Reports", func(t *testing.T) {
+		for _, year := range []int{2026, 2025} {
+			report := &model.TaxReport{
+				ID: uuid.New(), UserID: user.ID, TaxYear: year, TotalIncome: float64(year), TotalTax: 100,
+				Breakdown: &model.TaxBreakdown{IncomeByCategory: map[string]float64{"employment_income": float64(year)}},
+				CreatedAt: now, UpdatedAt: now,
+			}
+			if err := repo.SaveReport(ctx, report); err != nil {
+				t.Fatalf("SaveReport failed: %v", err)
+			}
+		}
+
+		// Saving again for the same year replaces the report
+		replacement := &model.TaxReport{ID: uuid.New(), UserID: user.ID, TaxYear: 2026, TotalIncome: 9_000, CreatedAt: now, UpdatedAt: now}
+		if err := repo.SaveReport(ctx, replacement); err != nil {
+			t.Fatalf("SaveReport failed: %v", err)
+		}
+
+		reports, err := repo.ListReports(ctx, user.ID)
+		if err != nil || len(reports) != 2 {
+			t.Fatalf("Expected 2 reports, got %d, %v", len(reports), err)
+		}
+		if reports[0].TaxYear != 2025 || reports[1].TotalIncome != 9_000 {
+			t.Errorf("Unexpected reports %+v %+v", reports[0], reports[1])
+		}
+		if reports[0].Breakdown == nil || reports[0].Breakdown.IncomeByCategory["employment_income"] != 2025 {
+			t.Errorf("Expected the breakdown to round-trip, got %+v", reports[0].Breakdown)
+		}
+
+		// Changing a returned breakdown leaves the stored report alone
+		reports[0].Breakdown.IncomeByCategory["employment_income"] = 0
+		if found, err := repo.GetReportByYear(ctx, user.ID, 2025); err != nil || found.Breakdown.IncomeByCategory["employment_income"] != 2025 {
+			t.Errorf("Expected the stored breakdown to be unchanged, got %+v, %v", found, err)
+		}
+
+		if _, err := repo.GetReportByYear(ctx, other.ID, 2026); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's report, got %v", err)
+		}
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		older := &model.Job{
+			ID: uuid.New(), UserID: user.ID, Status: model.JobQueued, Filename: "feb.csv", TaxYear: 2026,
+			Reliefs: model.ReliefInput{AnnualRent: 1_200_000}, Input: []byte("date,amount\n"),
+			CreatedAt: now.Add(-time.Minute), UpdatedAt: now.Add(-time.Minute),
+		}
+		newer := &model.Job{ID: uuid.New(), UserID: user.ID, Status: model.JobQueued, Filename: "mar.csv", Input: []byte("x"), CreatedAt: now, UpdatedAt: now}
+		for _, job := range []*model.Job{older, newer} {
+			if err := repo.CreateJob(ctx, job); err != nil {
+				t.Fatalf("CreateJob failed: %v", err)
+			}
+		}
+
+		got, err := repo.GetJob(ctx, user.ID, older.ID)
+		if err != nil || string(got.Input) != "date,amount\n" || got.Reliefs.AnnualRent != 1_200_000 || got.UploadID != nil {
+			t.Fatalf("GetJob returned %+v, %v", got, err)
+		}
+		if _, err := repo.GetJob(ctx, other.ID, older.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's job, got %v", err)
+		}
+
+		// Progress keeps the input so the job can be resumed
+		older.Status, older.Stage, older.Progress, older.Processed, older.Total = model.JobRunning, model.StageClassify, 50, 10, 20
+		older.Input = nil
+		if err := repo.UpdateJob(ctx, older); err != nil {
+			t.Fatalf("UpdateJob failed: %v", err)
+		}
+		unfinished, err := repo.ListUnfinishedJobs(ctx)
+		if err != nil {
+			t.Fatalf("ListUnfinishedJobs failed: %v", err)
+		}
+		var mine []*model.Job
+		for _, job := range unfinished {
+			if job.UserID == user.ID {
+				mine = append(mine, job)
+			}
+		}
+		if len(mine) != 2 || mine[0].ID != older.ID || mine[0].Stage != model.StageClassify || mine[0].Input != nil {
+			t.Fatalf("Expected both jobs, oldest first without their input, got %+v", mine)
+		}
+		if got, err := repo.GetJob(ctx, user.ID, older.ID); err != nil || string(got.Input) != "date,amount\n" {
+			t.Fatalf("Expected the unfinished job to keep its input, got %+v, %v", got, err)
+		}
+
+		// Finishing drops the input
+		reportID := uuid.New()
+		finishedAt := now.Add(time.Minute)
+		older.Status, older.Progress, older.ReportID, older.FinishedAt = model.JobSucceeded, 100, &reportID, &finishedAt
+		if err := repo.UpdateJob(ctx, older); err != nil {
+			t.Fatalf("UpdateJob failed: %v", err)
+		}
+		got, err = repo.GetJob(ctx, user.ID, older.ID)
+		if err != nil || got.Status != model.JobSucceeded || got.ReportID == nil || *got.ReportID != reportID ||
+			got.FinishedAt == nil || !got.FinishedAt.Equal(finishedAt) || len(got.Input) != 0 {
+			t.Errorf("Expected the finished job without its input, got %+v, %v", got, err)
+		}
+
+		jobs, err := repo.ListJobs(ctx, user.ID)
+		if err != nil || len(jobs) != 2 || jobs[0].ID != newer.ID || jobs[0].Input != nil {
+			t.Fatalf("ListJobs returned %+v, %v", jobs, err)
+		}
+		if err := repo.UpdateJob(ctx, &model.Job{ID: newer.ID, UserID: other.ID}); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound updating another user's job, got %v", err)
+		}
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		webhook := &model.Webhook{
+			ID: uuid.New(), UserID: user.ID, URL: "https://partner.example/hooks", Events: []string{model.EventReportReady},
+			Secret: "whsec_test", CreatedAt: now,
+		}
+		if err := repo.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatalf("CreateWebhook failed: %v", err)
+		}
+		webhooks, err := repo.ListWebhooks(ctx, user.ID)
+		if err != nil || len(webhooks) != 1 || webhooks[0].Secret != "whsec_test" || !reflect.DeepEqual(webhooks[0].Events, webhook.Events) {
+			t.Fatalf("ListWebhooks returned %+v, %v", webhooks, err)
+		}
+		if _, err := repo.GetWebhook(ctx, other.ID, webhook.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's webhook, got %v", err)
+		}
+
+		due := now.Add(-time.Minute)
+		later := now.Add(time.Hour)
+		deliveries := []*model.WebhookDelivery{
+			{ID: uuid.New(), WebhookID: webhook.ID, UserID: user.ID, Event: model.EventReportReady, Payload: []byte(`{"tax_year": 2026}`),
+				Status: model.DeliveryPending, NextAttemptAt: &due, CreatedAt: now.Add(-time.Minute), UpdatedAt: now},
+			{ID: uuid.New(), WebhookID: webhook.ID, UserID: user.ID, Event: model.EventReportReady, Payload: []byte(`{"tax_year": 2025}`),
+				Status: model.DeliveryPending, NextAttemptAt: &later, CreatedAt: now, UpdatedAt: now},
+		}
+		for _, delivery := range deliveries {
+			if err := repo.CreateWebhookDelivery(ctx, delivery); err != nil {
+				t.Fatalf("CreateWebhookDelivery failed: %v", err)
+			}
+		}
+
+		// mine keeps the claimed deliveries of this test's webhook
+		mine := func(claimed []model.WebhookDelivery) []model.WebhookDelivery {
+			var own []model.WebhookDelivery
+			for _, delivery := range claimed {
+				if delivery.WebhookID == webhook.ID {
+					own = append(own, delivery)
+				}
+			}
+			return own
+		}
+		lease := now.Add(time.Minute)
+		claimed, err := repo.ClaimDueWebhookDeliveries(ctx, now, lease, 0)
+		if err != nil {
+			t.Fatalf("ClaimDueWebhookDeliveries failed: %v", err)
+		}
+		if got := mine(claimed); len(got) != 1 || got[0].ID != deliveries[0].ID || !got[0].NextAttemptAt.Equal(lease) {
+			t.Fatalf("Expected only the due delivery, claimed until %s, got %+v", lease, got)
+		}
+		if claimed, err := repo.ClaimDueWebhookDeliveries(ctx, now, lease, 0); err != nil || len(mine(claimed)) != 0 {
+			t.Errorf("Expected a claimed delivery not to be claimed again, got %+v, %v", mine(claimed), err)
+		}
+		if claimed, err := repo.ClaimDueWebhookDeliveries(ctx, lease, lease.Add(time.Minute), 0); err != nil || len(mine(claimed)) != 1 {
+			t.Errorf("Expected the delivery to be claimable once its claim runs out, got %+v, %v", mine(claimed), err)
+		}
+
+		delivered := deliveries[0]
+		delivered.Status, delivered.Attempts, delivered.ResponseStatus, delivered.NextAttemptAt = model.DeliverySucceeded, 2, 204, nil
+		if err := repo.UpdateWebhookDelivery(ctx, delivered); err != nil {
+			t.Fatalf("UpdateWebhookDelivery failed: %v", err)
+		}
+		got, err := repo.GetWebhookDelivery(ctx, user.ID, delivered.ID)
+		if err != nil || got.Status != model.DeliverySucceeded || got.Attempts != 2 || got.NextAttemptAt != nil {
+			t.Fatalf("GetWebhookDelivery returned %+v, %v", got, err)
+		}
+		var payload map[string]int
+		if err := json.Unmarshal(got.Payload, &payload); err != nil || payload["tax_year"] != 2026 {
+			t.Errorf("Expected the payload to round-trip, got %s, %v", got.Payload, err)
+		}
+		if _, err := repo.GetWebhookDelivery(ctx, other.ID, delivered.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's delivery, got %v", err)
+		}
+
+		listed, err := repo.ListWebhookDeliveries(ctx, user.ID, repository.WebhookDeliveryFilter{WebhookID: webhook.ID, Limit: 1})
+		if err != nil || len(listed) != 1 || listed[0].ID != deliveries[1].ID {
+			t.Fatalf("Expected the newest delivery, got %+v, %v", listed, err)
+		}
+
+		if err := repo.DeleteWebhook(ctx, other.ID, webhook.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting another user's webhook, got %v", err)
+		}
+		if err := repo.DeleteWebhook(ctx, user.ID, webhook.ID); err != nil {
+			t.Fatalf("DeleteWebhook failed: %v", err)
+		}
+		if _, err := repo.GetWebhookDelivery(ctx, user.ID, delivered.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("Expected the webhook's deliveries to be deleted with it, got %v", err)
+		}
+	})
+}
@@ -1,184 +1,476 @@
 package classifier
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+
 	"github.com/taxsmart/taxsmart-api/internal/model"
 )
 
+// AIConfig configures the AI classifier. Zero values fall back to defaults.
+type AIConfig struct {
+	Provider          string  // "gemini", "openai", "anthropic" or "openai-compatible"
+	APIKey            string  // Optional for OpenAI-compatible servers
+	Model             string  // Overrides the provider's default model
+	BaseURL           string  // Overrides the provider's API endpoint
+	BatchSize         int     // Transactions per request
+	Concurrency       int     // Requests in flight at once
+	RequestsPerSecond float64 // Token bucket refill rate shared by all requests
+	MaxRetries        int     // Retries after a 429 or 5xx response
+}
+
+// Enabled reports whether the config names a usable AI provider: any
+// provider with an API key, or a self-hosted OpenAI-compatible server
+func (cfg AIConfig) Enabled() bool {
+	return cfg.APIKey != "" || (cfg.Provider == ProviderOpenAICompatible && cfg.BaseURL != "")
+}
+
+// AI classifier defaults
+const (
+	DefaultAIBatchSize         = 50
+	DefaultAIConcurrency       = 4
+	DefaultAIRequestsPerSecond = 2
+	DefaultAIMaxRetries        = 3
+)
+
+// PromptVersion identifies the classification prompt. Bump it whenever the
+// prompt or categories change so cached AI answers are not reused.
+const PromptVersion = "2026-10-5"
+
 // AIClassifier classifies transactions using AI APIs
 type AIClassifier struct {
-	provider   string
-	apiKey     string
-	httpClient *http.Client
+	provider    LLMProvider
+	batchSize   int
+	concurrency int
+	maxRetries  int
+	limiter     *rate.Limiter
+	httpClient  *http.Client
+	backoff     func(attempt int) time.Duration
+	audit       AIAuditStore
+	now         func() time.Time
+	recording   *AIRecording // Answers are kept here when set
+	replay      bool         // Answer from the recording instead of the provider
+}
+
+// AIAuditStore records what was sent to the AI provider
+type AIAuditStore interface {
+	SaveAIAudit(ctx context.Context, entry *model.AIAuditEntry) error
 }
 
-// NewAIClassifier creates a new AI classifier
-func NewAIClassifier(provider, apiKey string) *AIClassifier {
+// NewAIClassifier creates a new AI classifier for the configured provider.
+// Every request is recorded in audit, which may be nil to keep no log.
+func NewAIClassifier(cfg AIConfig, audit AIAuditStore) (*AIClassifier, error) {
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultAIBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultAIConcurrency
+	}
+	if cfg.RequestsPerSecond <= 0 {
+		cfg.RequestsPerSecond = DefaultAIRequestsPerSecond
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultAIMaxRetries
+	}
+
 	return &AIClassifier{
-		provider: provider,
-		apiKey:   apiKey,
+		provider:    provider,
+		batchSize:   cfg.BatchSize,
+		concurrency: cfg.Concurrency,
+		maxRetries:  cfg.MaxRetries,
+		limiter:     rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), cfg.Concurrency),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: 60 * time.Second,
 		},
-	}
+		backoff: exponentialBackoff,
+		audit:   audit,
+		now:     time.Now,
+	}, nil
+}
+
+// Version identifies the provider and prompt behind the classifier's answers
+func (c *AIClassifier) Version() string {
+	return c.provider.Name() + "/" + PromptVersion
 }
 
 // IsAvailable returns true if AI classification is configured
 func (c *AIClassifier) IsAvailable() bool {
-	return c.apiKey != ""
+	return c != nil && c.provider != nil
 }
 
-// Classify uses AI to classify a transaction
-func (c *AIClassifier) Classify(ctx context.Context, description string, txType string, amount float64) (model.ClassificationResult, error) {
+// Classify uses AI to classify a transaction. Pass uuid.Nil for anonymous requests.
+func (c *AIClassifier) Classify(ctx context.Context, userID uuid.UUID, tx model.ParsedTransaction) (model.ClassificationResult, error) {
+	results, err := c.ClassifyBatch(ctx, userID, []model.ParsedTransaction{tx})
+	if err != nil {
+		return model.ClassificationResult{}, err
+	}
+	return results[0], nil
+}
+
+// ClassifyBatch classifies transactions in batches of up to BatchSize per
+// request, with up to Concurrency requests in flight. Transactions the model
+// skipped come back uncategorized with zero confidence. The first failed
+// batch cancels the rest and its error is returned. Personal data is masked
+// before it is sent, and each request is audited against userID.
+func (c *AIClassifier) ClassifyBatch(ctx context.Context, userID uuid.UUID, transactions []model.ParsedTransaction) ([]model.ClassificationResult, error) {
 	if !c.IsAvailable() {
-		return model.ClassificationResult{}, fmt.Errorf("AI classifier not configured")
+		return nil, fmt.Errorf("AI classifier not configured")
+	}
+	if c.replay {
+		return c.recording.replay(transactions), nil
 	}
 
-	prompt := fmt.Sprintf(`Classify this Nigerian bank transaction into one of these categories:
-- employment_income: Salary, wages, payroll from employer
-- freelance_income: Payments from freelance platforms or clients
-- rental_income: Rent received from tenants
-- investment_income: Dividends, investment returns
-- crypto_income: Cryptocurrency trading/sales
-- interest_income: Bank interest
-- other_income: Other income sources
-- expense: General expenses
-- rent_expense: Rent payments to landlord
-- transfer: Money transfers between accounts
-- uncategorized: Cannot determine
+	results := make([]model.ClassificationResult, len(transactions))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-Transaction: "%s"
-Type: %s
-Amount: %.2f NGN
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		slots    = make(chan struct{}, c.concurrency)
+	)
+	for start := 0; start < len(transactions); start += c.batchSize {
+		end := min(start+c.batchSize, len(transactions))
 
-Respond with ONLY a JSON object like: {"category": "category_name", "confidence": 0.85}`, description, txType, amount)
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
 
-	var result model.ClassificationResult
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-slots }()
 
-	switch c.provider {
-	case "gemini":
-		result, _ = c.classifyWithGemini(ctx, prompt)
-	case "openai":
-		result, _ = c.classifyWithOpenAI(ctx, prompt)
-	default:
-		return model.ClassificationResult{}, fmt.Errorf("unsupported AI provider: %s", c.provider)
+			batch, err := c.classifyChunk(ctx, userID, transactions[start:end])
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			copy(results[start:end], batch)
+		}(start, end)
 	}
+	wg.Wait()
 
-	result.Method = "ai"
-	return result, nil
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.recording != nil {
+		c.recording.record(transactions, results)
+	}
+	return results, nil
 }
 
-func (c *AIClassifier) classifyWithGemini(ctx context.Context, prompt string) (model.ClassificationResult, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent?key=%s", c.apiKey)
+// classifyChunk sends one batched prompt and maps the answers back by index.
+// Answers with an unknown category, an index outside the batch or a
+// confidence outside [0, 1] are ignored; if none are usable the output is
+// reported as invalid.
+func (c *AIClassifier) classifyChunk(ctx context.Context, userID uuid.UUID, transactions []model.ParsedTransaction) (results []model.ClassificationResult, err error) {
+	redactor := NewRedactor()
+	prompt := batchPrompt(transactions, redactor)
+	defer func() {
+		c.recordAudit(ctx, userID, prompt, len(transactions), redactor, err)
+	}()
 
-	requestBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.1,
-			"maxOutputTokens": 100,
-		},
+	text, err := c.complete(ctx, CompletionRequest{
+		Prompt:    prompt,
+		MaxTokens: 100 + 100*len(transactions),
+		Schema:    batchSchema(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	jsonBody, err := json.Marshal(requestBody)
+	answers, err := parseBatchAnswer(text)
 	if err != nil {
-		return model.ClassificationResult{}, err
+		return nil, c.invalidOutput(err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return model.ClassificationResult{}, err
+	results = make([]model.ClassificationResult, len(transactions))
+	for i := range results {
+		results[i] = model.ClassificationResult{Category: model.CategoryUncategorized, Method: "ai"}
+	}
+	valid := 0
+	for _, answer := range answers {
+		i := answer.Index - 1
+		category := model.Category(answer.Category)
+		if i < 0 || i >= len(results) || !category.IsValid() || answer.Confidence < 0 || answer.Confidence > 1 {
+			continue
+		}
+		results[i] = model.ClassificationResult{
+			Category:     category,
+			Confidence:   answer.Confidence,
+			Method:       "ai",
+			Explanation:  aiExplanation(c.provider.Name(), restoreRationale(redactor, answer.Rationale)),
+			Alternatives: rankAlternatives(category, answer.alternatives()),
+		}
+		valid++
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if valid == 0 && len(transactions) > 0 {
+		return nil, c.invalidOutput(fmt.Errorf("no valid results for %d transactions", len(transactions)))
+	}
+	return results, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// recordAudit logs a request sent to the provider. Audit failures do not fail classification.
+func (c *AIClassifier) recordAudit(ctx context.Context, userID uuid.UUID, prompt string, count int, redactor *Redactor, err error) {
+	if c.audit == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	entry := &model.AIAuditEntry{
+		ID:               uuid.New(),
+		UserID:           userID,
+		Provider:         c.provider.Name(),
+		Prompt:           prompt,
+		TransactionCount: count,
+		Redactions:       redactor.Counts(),
+		Status:           "ok",
+		CreatedAt:        c.now(),
+	}
 	if err != nil {
-		return model.ClassificationResult{}, err
+		entry.Status = "error"
+		entry.Error = err.Error()
 	}
-	defer resp.Body.Close()
+	c.audit.SaveAIAudit(context.WithoutCancel(ctx), entry)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return model.ClassificationResult{}, err
+// batchPrompt asks for a category for every numbered transaction, with
+// personal data masked by the redactor
+func batchPrompt(transactions []model.ParsedTransaction, redactor *Redactor) string {
+	var list strings.Builder
+	for i, tx := range transactions {
+		fmt.Fprintf(&list, "%d. %s | Type: %s | Amount: %.2f NGN\n", i+1, describeForPrompt(tx, redactor), tx.Type, tx.Amount)
 	}
 
-	return c.parseAIResponse(body)
+	return fmt.Sprintf(`Classify each of these Nigerian bank transactions into one of these categories.
+Use an indented subcategory when the transaction clearly fits it, or else its parent:
+%s
+Personal details are masked with placeholders such as [NAME_1] or [PHONE_1]; a
+[NAME_n] counterparty is a private individual.
+
+Transactions:
+%s
+Respond with ONLY a JSON object with one result per transaction. Give a
+rationale of at most one sentence naming the words that decided the category,
+and up to 3 other plausible categories with their confidence, like:
+{"results": [{"index": 1, "category": "category_name", "confidence": 0.85,
+"rationale": "...", "alternatives": [{"category": "other_name", "confidence": 0.1}]}]}`, categoryGuide(), list.String())
 }
 
-func (c *AIClassifier) classifyWithOpenAI(ctx context.Context, prompt string) (model.ClassificationResult, error) {
-	url := "https://api.openai.com/v1/chat/completions"
+// categoryGuide lists the taxonomy for the prompt, indenting subcategories under their parents
+func categoryGuide() string {
+	var guide strings.Builder
+	for _, node := range model.Taxonomy {
+		indent := ""
+		if node.Parent != "" {
+			indent = "  "
+		}
+		fmt.Fprintf(&guide, "%s- %s: %s\n", indent, node.Category, node.Description)
+	}
+	return guide.String()
+}
 
-	requestBody := map[string]interface{}{
-		"model": "gpt-4o-mini",
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"temperature": 0.1,
-		"max_tokens":  100,
+// describeForPrompt presents the parsed narration fields rather than the raw
+// description, leaving out account numbers and session references and
+// masking other personal data, including the counterparty when it is a person
+func describeForPrompt(tx model.ParsedTransaction, redactor *Redactor) string {
+	var names []string
+	if IsPersonalName(tx.Counterparty) {
+		names = append(names, tx.Counterparty)
 	}
 
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return model.ClassificationResult{}, err
+	narration := tx.Description
+	for _, identifier := range []string{tx.AccountNumber, tx.SessionRef} {
+		if identifier != "" {
+			narration = strings.ReplaceAll(narration, identifier, "")
+		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return model.ClassificationResult{}, err
+	narration = redactor.Redact(strings.Join(strings.Fields(narration), " "), names...)
+	parts := []string{fmt.Sprintf("Transaction: %q", narration)}
+	if tx.Counterparty != "" {
+		parts = append(parts, "Counterparty: "+redactor.Redact(tx.Counterparty, names...))
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if tx.Channel != "" {
+		parts = append(parts, "Channel: "+tx.Channel)
+	}
+	if tx.BankCode != "" {
+		parts = append(parts, "Counterparty bank: "+tx.BankCode)
+	}
+	return strings.Join(parts, " | ")
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return model.ClassificationResult{}, err
+// batchSchema is the JSON schema of the model's answer
+func batchSchema() map[string]interface{} {
+	categories := make([]string, len(model.Categories))
+	for i, category := range model.Categories {
+		categories[i] = string(category)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return model.ClassificationResult{}, err
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"results": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"index":      map[string]interface{}{"type": "integer"},
+						"category":   map[string]interface{}{"type": "string", "enum": categories},
+						"confidence": map[string]interface{}{"type": "number"},
+						"rationale":  map[string]interface{}{"type": "string"},
+						"alternatives": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"category":   map[string]interface{}{"type": "string", "enum": categories},
+									"confidence": map[string]interface{}{"type": "number"},
+								},
+								"required":             []string{"category", "confidence"},
+								"additionalProperties": false,
+							},
+						},
+					},
+					"required":             []string{"index", "category", "confidence", "rationale", "alternatives"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"results"},
+		"additionalProperties": false,
 	}
+}
 
-	return c.parseAIResponse(body)
+// batchAnswer is one entry of the model's structured output
+type batchAnswer struct {
+	Index        int            `json:"index"`
+	Category     string         `json:"category"`
+	Confidence   float64        `json:"confidence"`
+	Rationale    string         `json:"rationale,omitempty"`
+	Alternatives []answerOption `json:"alternatives,omitempty"`
 }
 
-func (c *AIClassifier) parseAIResponse(body []byte) (model.ClassificationResult, error) {
-	// Try to extract JSON from response
-	var result struct {
-		Category   string  `json:"category"`
-		Confidence float64 `json:"confidence"`
-	}
+// answerOption is an alternative category the model considered
+type answerOption struct {
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
+
+// maxRationaleLength caps the rationale kept from the model's answer
+const maxRationaleLength = 300
 
-	// Simple JSON extraction - find the JSON object in the response
-	start := bytes.Index(body, []byte("{"))
-	end := bytes.LastIndex(body, []byte("}"))
-	if start != -1 && end != -1 && end > start {
-		jsonData := body[start : end+1]
-		if err := json.Unmarshal(jsonData, &result); err == nil {
-			return model.ClassificationResult{
-				Category:   model.Category(result.Category),
-				Confidence: result.Confidence,
-				Method:     "ai",
-			}, nil
+// alternatives returns the answer's alternatives with usable confidences
+func (a batchAnswer) alternatives() []model.Alternative {
+	var alternatives []model.Alternative
+	for _, option := range a.Alternatives {
+		if option.Confidence < 0 || option.Confidence > 1 {
+			continue
 		}
+		alternatives = append(alternatives, model.Alternative{Category: model.Category(option.Category), Score: option.Confidence})
+	}
+	return alternatives
+}
+
+// restoreRationale shortens the model's rationale and puts back masked
+// names. Other personal data stays masked, as results are cached and
+// shared between users whose narrations normalise to the same words, which
+// include names but not numbers or emails.
+func restoreRationale(redactor *Redactor, rationale string) string {
+	rationale = strings.TrimSpace(rationale)
+	if runes := []rune(rationale); len(runes) > maxRationaleLength {
+		rationale = string(runes[:maxRationaleLength]) + "…"
+	}
+	return redactor.Restore(rationale, RedactName)
+}
+
+// parseBatchAnswer decodes the model's JSON output, allowing for a code fence around it
+func parseBatchAnswer(text string) ([]batchAnswer, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	var answer struct {
+		Results *[]batchAnswer `json:"results"`
 	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &answer); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+	if answer.Results == nil {
+		return nil, fmt.Errorf("AI response has no results")
+	}
+	return *answer.Results, nil
+}
 
-	return model.ClassificationResult{
-		Category:   model.CategoryUncategorized,
-		Confidence: 0,
-		Method:     "ai",
-	}, fmt.Errorf("failed to parse AI response")
+// complete sends a prompt to the provider, waiting for the rate limiter
+// before every attempt and retrying 429 and 5xx responses with backoff, and
+// returns the model's answer. Failures are reported as *AIError, except
+// cancellation by the caller.
+func (c *AIClassifier) complete(ctx context.Context, completion CompletionRequest) (string, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		req, err := c.provider.NewRequest(ctx, completion)
+		if err != nil {
+			return "", err
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", c.transportError(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", c.transportError(err)
+		}
+
+		if resp.StatusCode < 300 {
+			text, err := c.provider.DecodeResponse(body)
+			if err != nil {
+				return "", c.invalidOutput(err)
+			}
+			return text, nil
+		}
+		if !retryable(resp.StatusCode) || attempt >= c.maxRetries {
+			return "", c.statusError(resp.StatusCode, body)
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > wait {
+			wait = retryAfter
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return "", err
+		}
+	}
 }
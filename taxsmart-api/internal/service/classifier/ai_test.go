@@ -0,0 +1,237 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+var promptLine = regexp.MustCompile(`(?m)^(\d+)\. `)
+
+// stubOpenAI serves chat completions, answering every numbered transaction in
+// the prompt with the given category. Requests are counted in calls.
+func stubOpenAI(t *testing.T, category string, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		var results []batchAnswer
+		for _, m := range promptLine.FindAllStringSubmatch(req.Messages[0].Content, -1) {
+			var index int
+			fmt.Sscan(m[1], &index)
+			results = append(results, batchAnswer{Index: index, Category: category, Confidence: 0.9})
+		}
+		writeCompletion(w, map[string]interface{}{"results": results})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeCompletion(w http.ResponseWriter, answer interface{}) {
+	content, _ := json.Marshal(answer)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": map[string]string{"content": string(content)}},
+		},
+	})
+}
+
+func newTestAI(t *testing.T, baseURL string, batchSize int) *AIClassifier {
+	t.Helper()
+	ai, err := NewAIClassifier(AIConfig{
+		Provider:          "openai",
+		APIKey:            "test-key",
+		BaseURL:           baseURL,
+		BatchSize:         batchSize,
+		Concurrency:       2,
+		RequestsPerSecond: 1000,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewAIClassifier() error = %v", err)
+	}
+	ai.backoff = func(int) time.Duration { return 0 }
+	return ai
+}
+
+func testTransactions(n int) []model.ParsedTransaction {
+	txs := make([]model.ParsedTransaction, n)
+	for i := range txs {
+		txs[i] = model.ParsedTransaction{Description: fmt.Sprintf("UNKNOWN PARTY %d", i), Amount: 1000, Type: "credit"}
+	}
+	return txs
+}
+
+func TestAIClassifier_ClassifyBatch(t *testing.T) {
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryFreelance), &calls)
+
+	results, err := newTestAI(t, server.URL, 10).ClassifyBatch(context.Background(), uuid.Nil, testTransactions(25))
+	if err != nil {
+		t.Fatalf("ClassifyBatch() error = %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	if len(results) != 25 {
+		t.Fatalf("results = %d, want 25", len(results))
+	}
+	for i, result := range results {
+		if result.Category != model.CategoryFreelance || result.Method != "ai" {
+			t.Errorf("results[%d] = %+v", i, result)
+		}
+	}
+}
+
+func TestAIClassifier_MissingAnswers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, map[string]interface{}{"results": []batchAnswer{
+			{Index: 2, Category: string(model.CategoryRental), Confidence: 0.8},
+			{Index: 9, Category: string(model.CategoryExpense), Confidence: 0.8},
+		}})
+	}))
+	defer server.Close()
+
+	results, err := newTestAI(t, server.URL, 10).ClassifyBatch(context.Background(), uuid.Nil, testTransactions(3))
+	if err != nil {
+		t.Fatalf("ClassifyBatch() error = %v", err)
+	}
+	want := []model.Category{model.CategoryUncategorized, model.CategoryRental, model.CategoryUncategorized}
+	for i, category := range want {
+		if results[i].Category != category {
+			t.Errorf("results[%d].Category = %s, want %s", i, results[i].Category, category)
+		}
+	}
+	if results[0].Confidence != 0 {
+		t.Errorf("skipped transaction confidence = %v, want 0", results[0].Confidence)
+	}
+}
+
+func TestAIClassifier_Retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		status    int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"rate limited then ok", 2, http.StatusTooManyRequests, false, 3},
+		{"server error then ok", 1, http.StatusBadGateway, false, 2},
+		{"retries exhausted", 10, http.StatusServiceUnavailable, true, 4},
+		{"client error not retried", 10, http.StatusUnauthorized, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.status)
+					return
+				}
+				writeCompletion(w, map[string]interface{}{"results": []batchAnswer{
+					{Index: 1, Category: string(model.CategoryExpense), Confidence: 0.9},
+				}})
+			}))
+			defer server.Close()
+
+			_, err := newTestAI(t, server.URL, 10).ClassifyBatch(context.Background(), uuid.Nil, testTransactions(1))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClassifyBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAIClassifier_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := newTestAI(t, server.URL, 5).ClassifyBatch(ctx, uuid.Nil, testTransactions(20))
+	if err == nil {
+		t.Fatal("ClassifyBatch() error = nil, want cancellation error")
+	}
+}
+
+func TestDescribeForPrompt_OmitsIdentifiers(t *testing.T) {
+	tx := model.ParsedTransaction{
+		Description: "NIP/FBN/JOHN DOE/0123456789/000013240101120000123456789012",
+		Narration: model.Narration{
+			Channel:       model.ChannelNIP,
+			Counterparty:  "JOHN DOE",
+			AccountNumber: "0123456789",
+			SessionRef:    "000013240101120000123456789012",
+		},
+	}
+
+	redactor := NewRedactor()
+	got := describeForPrompt(tx, redactor)
+	for _, identifier := range []string{tx.AccountNumber, tx.SessionRef, tx.Counterparty} {
+		if strings.Contains(got, identifier) {
+			t.Errorf("describeForPrompt() = %q, contains %q", got, identifier)
+		}
+	}
+	if !strings.Contains(got, "Counterparty: [NAME_1]") {
+		t.Errorf("describeForPrompt() = %q, missing masked counterparty", got)
+	}
+	if restored := redactor.Restore("[NAME_1]"); restored != tx.Counterparty {
+		t.Errorf("Restore() = %q, want %q", restored, tx.Counterparty)
+	}
+}
+
+func TestClassifier_SendsOnlyLowConfidenceToAI(t *testing.T) {
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryFreelance), &calls)
+
+	c := NewClassifier(newTestAI(t, server.URL, 10), nil, nil)
+
+	txs := []model.ParsedTransaction{
+		{Description: "SALARY PAYMENT JAN 2024", Amount: 500000, Type: "credit"},
+		{Description: "UNKNOWN PARTY", Amount: 80000, Type: "credit"},
+	}
+	results := c.ClassifyBatch(context.Background(), uuid.Nil, txs)
+
+	if calls.Load() != 1 {
+		t.Fatalf("requests = %d, want 1", calls.Load())
+	}
+	if results[0].Category != model.CategoryEmployment || results[0].Method != "rules" {
+		t.Errorf("results[0] = %+v, want rule-based employment income", results[0])
+	}
+	if results[1].Category != model.CategoryFreelance || results[1].Method != "ai" {
+		t.Errorf("results[1] = %+v, want AI freelance income", results[1])
+	}
+}
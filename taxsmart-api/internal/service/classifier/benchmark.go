@@ -0,0 +1,182 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// Baseline is a stored evaluation that later runs of a classifier are compared with
+type Baseline struct {
+	MacroF1     float64                    `json:"macro_f1"`
+	F1          map[model.Category]float64 `json:"f1"`
+	Predictions map[string]model.Category  `json:"predictions"` // Transaction key to predicted category
+}
+
+// NewBaseline records an evaluation of predictions for the samples as a baseline
+func NewBaseline(samples []LabelledTransaction, predictions []model.ClassificationResult, report EvaluationReport) Baseline {
+	baseline := Baseline{
+		MacroF1:     report.MacroF1,
+		F1:          make(map[model.Category]float64),
+		Predictions: make(map[string]model.Category, len(samples)),
+	}
+	for _, m := range report.Categories {
+		if m.Support > 0 {
+			baseline.F1[m.Category] = m.F1
+		}
+	}
+	for i, sample := range samples {
+		baseline.Predictions[recordingKey(sample.ParsedTransaction)] = predictions[i].Category
+	}
+	return baseline
+}
+
+// LoadBaselines reads baselines written by SaveBaselines, keyed by the classifier they measure
+func LoadBaselines(path string) (map[string]Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var baselines map[string]Baseline
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return nil, fmt.Errorf("invalid baseline: %w", err)
+	}
+	return baselines, nil
+}
+
+// SaveBaselines writes baselines as indented JSON
+func SaveBaselines(path string, baselines map[string]Baseline) error {
+	data, err := json.MarshalIndent(baselines, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Comparison shows where an evaluation fell short of its baseline
+type Comparison struct {
+	MacroF1Before float64             `json:"macro_f1_before"`
+	MacroF1After  float64             `json:"macro_f1_after"`
+	Categories    []F1Change          `json:"categories"`   // Categories whose F1 fell
+	Transactions  []TransactionChange `json:"transactions"` // Transactions the baseline got right and the evaluation gets wrong
+	Tolerance     float64             `json:"tolerance"`    // F1 drop allowed before the comparison fails
+}
+
+// F1Change is a category's F1 in the baseline and the evaluation
+type F1Change struct {
+	Category model.Category `json:"category"`
+	Before   float64        `json:"before"`
+	After    float64        `json:"after"`
+}
+
+// TransactionChange is a transaction whose prediction got worse
+type TransactionChange struct {
+	Transaction string         `json:"transaction"`
+	Label       model.Category `json:"label"`
+	Before      model.Category `json:"before"`
+	After       model.Category `json:"after"`
+}
+
+// Compare lists the regressions of an evaluation from the baseline. Only
+// categories labelled in both are compared, and only transactions in both,
+// so the dataset can grow without every new row counting against it.
+func (b Baseline) Compare(samples []LabelledTransaction, predictions []model.ClassificationResult, report EvaluationReport, tolerance float64) Comparison {
+	comparison := Comparison{MacroF1Before: b.MacroF1, MacroF1After: report.MacroF1, Tolerance: tolerance}
+	for _, m := range report.Categories {
+		before, ok := b.F1[m.Category]
+		if ok && m.Support > 0 && m.F1 < before {
+			comparison.Categories = append(comparison.Categories, F1Change{Category: m.Category, Before: before, After: m.F1})
+		}
+	}
+	for i, sample := range samples {
+		key := recordingKey(sample.ParsedTransaction)
+		before, ok := b.Predictions[key]
+		if ok && before == sample.Label && predictions[i].Category != sample.Label {
+			comparison.Transactions = append(comparison.Transactions, TransactionChange{
+				Transaction: key,
+				Label:       sample.Label,
+				Before:      before,
+				After:       predictions[i].Category,
+			})
+		}
+	}
+	return comparison
+}
+
+// Failed reports whether macro F1 or any category's F1 fell by more than the tolerance
+func (c Comparison) Failed() bool {
+	if c.MacroF1Before-c.MacroF1After > c.Tolerance {
+		return true
+	}
+	for _, change := range c.Categories {
+		if change.Before-change.After > c.Tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTable writes the regressions as aligned text
+func (c Comparison) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "macro F1 %.3f -> %.3f\n", c.MacroF1Before, c.MacroF1After)
+	if len(c.Categories) == 0 && len(c.Transactions) == 0 {
+		_, err := fmt.Fprintln(w, "no regressions")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(c.Categories) > 0 {
+		fmt.Fprintln(tw, "\ncategory\tf1 before\tf1 after\t")
+		for _, change := range c.Categories {
+			fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t\n", change.Category, change.Before, change.After)
+		}
+	}
+	if len(c.Transactions) > 0 {
+		fmt.Fprintln(tw, "\ntransaction\tlabel\tnow\t")
+		for _, change := range c.Transactions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", change.Transaction, change.Label, change.After)
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteConfusion writes the confusion matrix with a row per label and a
+// column per predicted category
+func (r EvaluationReport) WriteConfusion(w io.Writer) error {
+	seen := make(map[model.Category]bool)
+	for label, row := range r.Confusion {
+		seen[label] = true
+		for predicted := range row {
+			seen[predicted] = true
+		}
+	}
+	var categories []model.Category
+	for _, category := range model.Categories {
+		if seen[category] {
+			categories = append(categories, category)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "label \\ predicted\t")
+	for _, category := range categories {
+		fmt.Fprintf(tw, "%s\t", category)
+	}
+	fmt.Fprintln(tw)
+	for _, label := range categories {
+		row := r.Confusion[label]
+		if row == nil {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t", label)
+		for _, predicted := range categories {
+			fmt.Fprintf(tw, "%d\t", row[predicted])
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
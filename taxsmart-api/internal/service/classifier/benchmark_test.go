@@ -0,0 +1,110 @@
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestAIRecordingReplay(t *testing.T) {
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryFreelance), &calls)
+	ai := newTestAI(t, server.URL, 10)
+	recording := NewAIRecording(ai.Version())
+	ai.RecordTo(recording)
+
+	txs := testTransactions(3)
+	if _, err := ai.ClassifyBatch(context.Background(), uuid.Nil, txs); err != nil {
+		t.Fatalf("ClassifyBatch() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	if err := SaveAIRecording(path, recording); err != nil {
+		t.Fatalf("SaveAIRecording() error = %v", err)
+	}
+	loaded, err := LoadAIRecording(path)
+	if err != nil {
+		t.Fatalf("LoadAIRecording() error = %v", err)
+	}
+
+	// Whitespace in descriptions does not matter, and unrecorded transactions are skipped
+	replayTxs := []model.ParsedTransaction{
+		{Description: "UNKNOWN  PARTY 1", Amount: 1000, Type: "credit"},
+		{Description: "NEVER SEEN", Amount: 1000, Type: "credit"},
+	}
+	results, err := NewReplayAIClassifier(loaded).ClassifyBatch(context.Background(), uuid.Nil, replayTxs)
+	if err != nil {
+		t.Fatalf("replay ClassifyBatch() error = %v", err)
+	}
+	if results[0].Category != model.CategoryFreelance || results[0].Confidence != 0.9 || results[0].Method != "ai" {
+		t.Errorf("replayed %+v, want the recorded freelance answer", results[0])
+	}
+	if results[1].Category != model.CategoryUncategorized {
+		t.Errorf("replayed %s for an unrecorded transaction, want uncategorized", results[1].Category)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("provider called %d times, want 1 while recording and none on replay", calls.Load())
+	}
+}
+
+func TestBaselineCompare(t *testing.T) {
+	samples := []LabelledTransaction{
+		{ParsedTransaction: model.ParsedTransaction{Description: "SALARY", Type: "credit"}, Label: model.CategoryEmployment},
+		{ParsedTransaction: model.ParsedTransaction{Description: "UPWORK", Type: "credit"}, Label: model.CategoryFreelance},
+		{ParsedTransaction: model.ParsedTransaction{Description: "NETFLIX", Type: "debit"}, Label: model.CategoryExpense},
+	}
+	predict := func(categories ...model.Category) []model.ClassificationResult {
+		results := make([]model.ClassificationResult, len(categories))
+		for i, category := range categories {
+			results[i] = model.ClassificationResult{Category: category}
+		}
+		return results
+	}
+
+	before := predict(model.CategoryEmployment, model.CategoryFreelance, model.CategoryUncategorized)
+	baseline := NewBaseline(samples, before, Evaluate(samples, before))
+
+	tests := []struct {
+		name         string
+		after        []model.ClassificationResult
+		categories   int
+		transactions int
+		failed       bool
+	}{
+		{"unchanged", before, 0, 0, false},
+		{"improved", predict(model.CategoryEmployment, model.CategoryFreelance, model.CategoryExpense), 0, 0, false},
+		{"regressed", predict(model.CategoryEmployment, model.CategoryUncategorized, model.CategoryUncategorized), 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison := baseline.Compare(samples, tt.after, Evaluate(samples, tt.after), 0.005)
+			if len(comparison.Categories) != tt.categories || len(comparison.Transactions) != tt.transactions {
+				t.Errorf("Compare() = %d category and %d transaction regressions, want %d and %d",
+					len(comparison.Categories), len(comparison.Transactions), tt.categories, tt.transactions)
+			}
+			if comparison.Failed() != tt.failed {
+				t.Errorf("Failed() = %v, want %v", comparison.Failed(), tt.failed)
+			}
+		})
+	}
+}
+
+func TestWriteConfusion(t *testing.T) {
+	samples := []LabelledTransaction{{Label: model.CategoryEmployment}, {Label: model.CategoryEmployment}}
+	predictions := []model.ClassificationResult{{Category: model.CategoryEmployment}, {Category: model.CategoryFreelance}}
+
+	var out bytes.Buffer
+	if err := Evaluate(samples, predictions).WriteConfusion(&out); err != nil {
+		t.Fatalf("WriteConfusion() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "freelance_income") || strings.Fields(lines[1])[1] != "1" {
+		t.Errorf("unexpected confusion matrix:\n%s", out.String())
+	}
+}
@@ -0,0 +1,250 @@
+package classifier
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// Classification cache defaults
+const (
+	DefaultCacheSize = 10000
+	DefaultCacheTTL  = 30 * 24 * time.Hour
+)
+
+// CacheStore is the persistent tier of the classification cache
+type CacheStore interface {
+	GetCachedClassification(ctx context.Context, key string) (*model.CachedClassification, error)
+	SaveCachedClassification(ctx context.Context, entry *model.CachedClassification) error
+	// DeleteStaleClassifications removes entries from other versions and entries expired by now
+	DeleteStaleClassifications(ctx context.Context, version string, now time.Time) (int, error)
+	ClearClassificationCache(ctx context.Context) error
+}
+
+// CacheStats reports how often the classification cache saved a lookup
+type CacheStats struct {
+	Hits       int64   `json:"hits"`
+	MemoryHits int64   `json:"memory_hits"`
+	StoreHits  int64   `json:"store_hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+	Entries    int     `json:"entries"` // Entries held in memory
+	Version    string  `json:"version"`
+}
+
+// Cache remembers global classification results for narrations that recur
+// across months and users, so they are not sent to the AI again. Recently
+// used entries are kept in memory and every entry is written through to the
+// store. Entries expire after the TTL and are dropped when the version, which
+// identifies the rule packs and AI prompt, changes.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	store   CacheStore
+	version string
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+	now     func() time.Time
+
+	memoryHits, storeHits, misses int64
+}
+
+// NewCache creates a classification cache holding up to size entries in
+// memory. store may be nil for a memory-only cache.
+func NewCache(size int, ttl time.Duration, store CacheStore) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		store:   store,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// CacheKey builds the cache key for a transaction: a hash of the version, the
+// transaction type, the order of magnitude of the amount and the normalised
+// narration. It returns false when the narration has nothing identifying to
+// key on, such as a bare reference number.
+func CacheKey(version string, tx model.ParsedTransaction) (string, bool) {
+	tokens := NormaliseNarration(tx.Description)
+	if len(tokens) == 0 {
+		return "", false
+	}
+	raw := fmt.Sprintf("%s|%s|%d|%s", version, strings.ToLower(tx.Type), amountBucket(tx.Amount), strings.Join(tokens, " "))
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// amountBucket groups amounts by order of magnitude, so ₦4,500 and ₦7,000
+// share a bucket but ₦5,000 and ₦500,000 do not
+func amountBucket(amount float64) int {
+	amount = math.Abs(amount)
+	if amount < 1 {
+		return 0
+	}
+	return int(math.Floor(math.Log10(amount))) + 1
+}
+
+// Get returns the cached result for a transaction, checking memory before the store
+func (c *Cache) Get(ctx context.Context, version string, tx model.ParsedTransaction) (model.ClassificationResult, bool) {
+	if c == nil {
+		return model.ClassificationResult{}, false
+	}
+	key, ok := CacheKey(version, tx)
+	if !ok {
+		return model.ClassificationResult{}, false
+	}
+
+	c.mu.Lock()
+	c.setVersion(ctx, version)
+	now := c.now()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*model.CachedClassification)
+		if now.Before(entry.ExpiresAt) {
+			c.order.MoveToFront(elem)
+			c.memoryHits++
+			c.mu.Unlock()
+			return entry.Result, true
+		}
+		c.remove(elem)
+	}
+	c.mu.Unlock()
+
+	if c.store != nil {
+		entry, err := c.store.GetCachedClassification(ctx, key)
+		if err == nil && entry.Version == version && now.Before(entry.ExpiresAt) {
+			c.mu.Lock()
+			c.add(entry)
+			c.storeHits++
+			c.mu.Unlock()
+			return entry.Result, true
+		}
+	}
+
+	c.mu.Lock()
+	c.misses++
+	c.mu.Unlock()
+	return model.ClassificationResult{}, false
+}
+
+// Put caches a result for a transaction. Store failures are ignored; the
+// result stays cached in memory.
+func (c *Cache) Put(ctx context.Context, version string, tx model.ParsedTransaction, result model.ClassificationResult) {
+	if c == nil {
+		return
+	}
+	key, ok := CacheKey(version, tx)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	c.setVersion(ctx, version)
+	now := c.now()
+	entry := &model.CachedClassification{
+		Key:       key,
+		Version:   version,
+		Result:    result,
+		CreatedAt: now,
+		ExpiresAt: now.Add(c.ttl),
+	}
+	c.add(entry)
+	c.mu.Unlock()
+
+	if c.store != nil {
+		stored := *entry
+		c.store.SaveCachedClassification(ctx, &stored)
+	}
+}
+
+// Clear drops every cached result from memory and the store and resets the statistics
+func (c *Cache) Clear(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.memoryHits, c.storeHits, c.misses = 0, 0, 0
+	c.mu.Unlock()
+
+	if c.store != nil {
+		return c.store.ClearClassificationCache(ctx)
+	}
+	return nil
+}
+
+// Stats returns the cache hit counts since it was created or last cleared
+func (c *Cache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Hits:       c.memoryHits + c.storeHits,
+		MemoryHits: c.memoryHits,
+		StoreHits:  c.storeHits,
+		Misses:     c.misses,
+		Entries:    c.order.Len(),
+		Version:    c.version,
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// setVersion invalidates the cache when the rule packs or prompt change.
+// Callers must hold the lock.
+func (c *Cache) setVersion(ctx context.Context, version string) {
+	if version == c.version {
+		return
+	}
+	c.version = version
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+
+	if c.store != nil {
+		go c.store.DeleteStaleClassifications(context.WithoutCancel(ctx), version, c.now())
+	}
+}
+
+// add stores an entry in memory, evicting the least recently used entry when
+// full. Callers must hold the lock.
+func (c *Cache) add(entry *model.CachedClassification) {
+	if elem, ok := c.entries[entry.Key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.Key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry from memory. Callers must hold the lock.
+func (c *Cache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*model.CachedClassification).Key)
+}
@@ -0,0 +1,149 @@
+package classifier
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+)
+
+func TestCacheKey(t *testing.T) {
+	base := model.ParsedTransaction{Description: "SALARY JAN 2024 ACME LTD", Amount: 450000, Type: "credit"}
+	baseKey, ok := CacheKey("v1", base)
+	if !ok {
+		t.Fatal("CacheKey() ok = false for an identifying narration")
+	}
+
+	tests := []struct {
+		name     string
+		version  string
+		tx       model.ParsedTransaction
+		wantSame bool
+	}{
+		{"next month", "v1", model.ParsedTransaction{Description: "SALARY FEB 2024 ACME LTD", Amount: 470000, Type: "credit"}, true},
+		{"case and spacing", "v1", model.ParsedTransaction{Description: "salary  jan 2024 acme ltd", Amount: 450000, Type: "CREDIT"}, true},
+		{"other type", "v1", model.ParsedTransaction{Description: "SALARY JAN 2024 ACME LTD", Amount: 450000, Type: "debit"}, false},
+		{"other amount bucket", "v1", model.ParsedTransaction{Description: "SALARY JAN 2024 ACME LTD", Amount: 4500000, Type: "credit"}, false},
+		{"other version", "v2", base, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := CacheKey(tt.version, tt.tx)
+			if !ok {
+				t.Fatal("CacheKey() ok = false")
+			}
+			if (key == baseKey) != tt.wantSame {
+				t.Errorf("same key = %v, want %v", key == baseKey, tt.wantSame)
+			}
+		})
+	}
+
+	if _, ok := CacheKey("v1", model.ParsedTransaction{Description: "TRF 1234567890", Type: "credit"}); ok {
+		t.Error("CacheKey() ok = true for a narration with nothing identifying")
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := NewCache(2, time.Hour, nil)
+	result := model.ClassificationResult{Category: model.CategoryExpense, Confidence: 0.9, Method: "ai"}
+
+	a := model.ParsedTransaction{Description: "AIRTIME PURCHASE", Amount: 1000, Type: "debit"}
+	b := model.ParsedTransaction{Description: "DSTV SUBSCRIPTION", Amount: 1000, Type: "debit"}
+	c := model.ParsedTransaction{Description: "SHOPRITE LEKKI", Amount: 1000, Type: "debit"}
+
+	cache.Put(ctx, "v1", a, result)
+	cache.Put(ctx, "v1", b, result)
+	cache.Get(ctx, "v1", a) // a is now the most recently used
+	cache.Put(ctx, "v1", c, result)
+
+	if _, ok := cache.Get(ctx, "v1", b); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	for _, tx := range []model.ParsedTransaction{a, c} {
+		if _, ok := cache.Get(ctx, "v1", tx); !ok {
+			t.Errorf("expected %q to be cached", tx.Description)
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 3 || stats.Misses != 1 || stats.Entries != 2 || stats.HitRate != 0.75 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestCache_ExpiryAndVersion(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewCache(10, time.Hour, nil)
+	cache.now = func() time.Time { return now }
+
+	tx := model.ParsedTransaction{Description: "AIRTIME PURCHASE", Amount: 1000, Type: "debit"}
+	cache.Put(ctx, "v1", tx, model.ClassificationResult{Category: model.CategoryExpense, Confidence: 0.9})
+
+	if _, ok := cache.Get(ctx, "v2", tx); ok {
+		t.Error("expected a miss after the version changed")
+	}
+
+	cache.Put(ctx, "v2", tx, model.ClassificationResult{Category: model.CategoryExpense, Confidence: 0.9})
+	now = now.Add(2 * time.Hour)
+	if _, ok := cache.Get(ctx, "v2", tx); ok {
+		t.Error("expected a miss after the entry expired")
+	}
+}
+
+func TestCache_PersistentTier(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	tx := model.ParsedTransaction{Description: "SALARY JAN ACME LTD", Amount: 450000, Type: "credit"}
+	want := model.ClassificationResult{Category: model.CategoryEmployment, Confidence: 0.9, Method: "ai"}
+
+	NewCache(10, time.Hour, store).Put(ctx, "v1", tx, want)
+
+	// A fresh cache, as after a restart, finds the entry in the store
+	restarted := NewCache(10, time.Hour, store)
+	got, ok := restarted.Get(ctx, "v1", tx)
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Get() = %+v, %v, want %+v", got, ok, want)
+	}
+	if stats := restarted.Stats(); stats.StoreHits != 1 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want one store hit promoted to memory", stats)
+	}
+}
+
+func TestClassifier_CachesAIResults(t *testing.T) {
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryFreelance), &calls)
+
+	c := NewClassifier(newTestAI(t, server.URL, 10), nil, NewCache(100, time.Hour, nil))
+
+	for _, month := range []string{"JAN", "FEB", "MAR"} {
+		tx := model.ParsedTransaction{Description: "KOLA ADEYEMI PROJECT " + month, Amount: 250000, Type: "credit"}
+		result := c.Classify(context.Background(), uuid.Nil, tx)
+		if result.Category != model.CategoryFreelance {
+			t.Errorf("%s: Category = %s, want %s", month, result.Category, model.CategoryFreelance)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("AI requests = %d, want 1", got)
+	}
+	if stats := c.Cache().Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits and 1 miss", stats)
+	}
+
+	// Changing the rules invalidates cached results
+	if err := c.Rules().AddPack(fintechPack()); err != nil {
+		t.Fatalf("AddPack() error = %v", err)
+	}
+	c.Classify(context.Background(), uuid.Nil, model.ParsedTransaction{Description: "KOLA ADEYEMI PROJECT APR", Amount: 250000, Type: "credit"})
+	if got := calls.Load(); got != 2 {
+		t.Errorf("AI requests after a rule change = %d, want 2", got)
+	}
+}
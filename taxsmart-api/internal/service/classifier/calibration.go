@@ -0,0 +1,205 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// Vote sources, named after the methods of the results they cast
+const (
+	SourceUserRule = "user_rule"
+	SourceRules    = "rules"
+	SourceAI       = "ai"
+)
+
+// CalibrationBins is the number of equal-width confidence bins fitted per source
+const CalibrationBins = 10
+
+// calibrationPrior is the weight, in samples, of a bin's raw confidence when
+// smoothing its observed accuracy, so sparse bins stay near face value
+const calibrationPrior = 2.0
+
+// defaultWeights are used for sources a calibration does not cover. A
+// learned rule is the user's own correction and decides whenever it matches
+// (see combine), so its weight is only reported in the explanation.
+var defaultWeights = map[string]float64{
+	SourceUserRule: 2,
+	SourceRules:    1,
+	SourceLocal:    1,
+	SourceAI:       1,
+}
+
+// Calibration maps each source's self-reported confidence to the accuracy
+// observed on labelled data, and weights the sources' votes
+type Calibration struct {
+	FittedAt time.Time                    `json:"fitted_at"`
+	Samples  int                          `json:"samples"`
+	Sources  map[string]SourceCalibration `json:"sources"`
+}
+
+// SourceCalibration is the fitted calibration of one source
+type SourceCalibration struct {
+	Weight   float64   `json:"weight"`   // Vote weight; the source's smoothed accuracy when it votes
+	Accuracy []float64 `json:"accuracy"` // Calibrated confidence per bin, non-decreasing
+	Counts   []int     `json:"counts"`   // Labelled votes seen per bin
+}
+
+// Calibrate returns the calibrated confidence of a vote. Without a fitted
+// table for the source the confidence is taken at face value.
+func (c *Calibration) Calibrate(source string, confidence float64) float64 {
+	if c == nil {
+		return confidence
+	}
+	sc, ok := c.Sources[source]
+	if !ok || len(sc.Accuracy) != CalibrationBins {
+		return confidence
+	}
+	return sc.Accuracy[calibrationBin(confidence)]
+}
+
+// Weight returns the vote weight of a source
+func (c *Calibration) Weight(source string) float64 {
+	if c != nil {
+		if sc, ok := c.Sources[source]; ok && sc.Weight > 0 {
+			return sc.Weight
+		}
+	}
+	if weight, ok := defaultWeights[source]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Validate checks that a calibration is usable
+func (c *Calibration) Validate() error {
+	for source, sc := range c.Sources {
+		if sc.Weight < 0 || math.IsNaN(sc.Weight) || math.IsInf(sc.Weight, 0) {
+			return fmt.Errorf("source %s: weight must be a non-negative number", source)
+		}
+		if sc.Accuracy != nil && len(sc.Accuracy) != CalibrationBins {
+			return fmt.Errorf("source %s: expected %d accuracy bins, got %d", source, CalibrationBins, len(sc.Accuracy))
+		}
+		for _, accuracy := range sc.Accuracy {
+			if accuracy < 0 || accuracy > 1 {
+				return fmt.Errorf("source %s: accuracy must be between 0 and 1", source)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadCalibration reads a calibration written by SaveCalibration
+func LoadCalibration(path string) (*Calibration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var calibration Calibration
+	if err := json.Unmarshal(data, &calibration); err != nil {
+		return nil, fmt.Errorf("invalid calibration: %w", err)
+	}
+	if err := calibration.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid calibration: %w", err)
+	}
+	return &calibration, nil
+}
+
+// SaveCalibration writes a calibration as indented JSON
+func SaveCalibration(path string, calibration *Calibration) error {
+	data, err := json.MarshalIndent(calibration, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// calibrationObservation is one labelled vote: its raw confidence and whether it was right
+type calibrationObservation struct {
+	confidence float64
+	correct    bool
+}
+
+// fitSource fits the calibration of one source from its labelled votes.
+// Each bin's accuracy is smoothed towards the bin's midpoint, then bins are
+// pooled until accuracy never falls as confidence rises.
+func fitSource(observations []calibrationObservation) SourceCalibration {
+	counts := make([]int, CalibrationBins)
+	correct := make([]int, CalibrationBins)
+	total := 0
+	for _, o := range observations {
+		bin := calibrationBin(o.confidence)
+		counts[bin]++
+		total++
+		if o.correct {
+			correct[bin]++
+		}
+	}
+
+	accuracy := make([]float64, CalibrationBins)
+	weights := make([]float64, CalibrationBins)
+	allCorrect := 0
+	for bin := range accuracy {
+		midpoint := (float64(bin) + 0.5) / CalibrationBins
+		accuracy[bin] = (float64(correct[bin]) + calibrationPrior*midpoint) / (float64(counts[bin]) + calibrationPrior)
+		weights[bin] = float64(counts[bin]) + calibrationPrior
+		allCorrect += correct[bin]
+	}
+
+	return SourceCalibration{
+		Weight:   (float64(allCorrect) + 1) / (float64(total) + 2),
+		Accuracy: poolAdjacentViolators(accuracy, weights),
+		Counts:   counts,
+	}
+}
+
+// poolAdjacentViolators returns the weighted least-squares non-decreasing fit to values
+func poolAdjacentViolators(values, weights []float64) []float64 {
+	type block struct {
+		value, weight float64
+		size          int
+	}
+	blocks := make([]block, 0, len(values))
+	for i, value := range values {
+		blocks = append(blocks, block{value: value, weight: weights[i], size: 1})
+		for len(blocks) > 1 && blocks[len(blocks)-2].value > blocks[len(blocks)-1].value {
+			a, b := blocks[len(blocks)-2], blocks[len(blocks)-1]
+			weight := a.weight + b.weight
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{
+				value:  (a.value*a.weight + b.value*b.weight) / weight,
+				weight: weight,
+				size:   a.size + b.size,
+			})
+		}
+	}
+
+	fitted := make([]float64, 0, len(values))
+	for _, b := range blocks {
+		for i := 0; i < b.size; i++ {
+			fitted = append(fitted, b.value)
+		}
+	}
+	return fitted
+}
+
+// calibrationBin returns the bin of a confidence in [0, 1]
+func calibrationBin(confidence float64) int {
+	bin := int(confidence * CalibrationBins)
+	if bin < 0 {
+		return 0
+	}
+	if bin >= CalibrationBins {
+		return CalibrationBins - 1
+	}
+	return bin
+}
+
+// castsVote reports whether a stage's result is a vote for a category
+func castsVote(result model.ClassificationResult) bool {
+	return result.Category != model.CategoryUncategorized && result.Category.IsValid()
+}
@@ -0,0 +1,231 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestFitSource(t *testing.T) {
+	var observations []calibrationObservation
+	// Overconfident at 0.9: right half the time. Underconfident at 0.3: always right.
+	for i := 0; i < 20; i++ {
+		observations = append(observations,
+			calibrationObservation{confidence: 0.9, correct: i%2 == 0},
+			calibrationObservation{confidence: 0.3, correct: true},
+		)
+	}
+
+	sc := fitSource(observations)
+	if len(sc.Accuracy) != CalibrationBins || sc.Counts[9] != 20 || sc.Counts[3] != 20 {
+		t.Fatalf("fitSource() = %+v", sc)
+	}
+	for bin := 1; bin < CalibrationBins; bin++ {
+		if sc.Accuracy[bin] < sc.Accuracy[bin-1] {
+			t.Errorf("Accuracy decreases at bin %d: %v", bin, sc.Accuracy)
+		}
+	}
+	// The violating bins are pooled to their weighted mean
+	if got := sc.Accuracy[9]; got < 0.6 || got > 0.8 {
+		t.Errorf("calibrated 0.9 = %.3f, want about 0.7", got)
+	}
+	if got := sc.Weight; got < 0.7 || got > 0.8 {
+		t.Errorf("Weight = %.3f, want the smoothed accuracy of 0.75", got)
+	}
+}
+
+func TestCalibration_Defaults(t *testing.T) {
+	var none *Calibration
+	if got := none.Calibrate(SourceAI, 0.83); got != 0.83 {
+		t.Errorf("Calibrate() without a calibration = %v, want face value", got)
+	}
+	if none.Weight(SourceUserRule) <= none.Weight(SourceRules) {
+		t.Error("expected learned rules to outweigh rule packs by default")
+	}
+
+	partial := &Calibration{Sources: map[string]SourceCalibration{SourceRules: {Weight: 0.5}}}
+	if got := partial.Calibrate(SourceRules, 0.4); got != 0.4 {
+		t.Errorf("Calibrate() without bins = %v, want face value", got)
+	}
+	if got := partial.Weight(SourceRules); got != 0.5 {
+		t.Errorf("Weight() = %v, want 0.5", got)
+	}
+}
+
+func TestCalibration_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	want := &Calibration{Samples: 40, Sources: map[string]SourceCalibration{SourceRules: fitSource([]calibrationObservation{{0.9, true}})}}
+	if err := SaveCalibration(path, want); err != nil {
+		t.Fatalf("SaveCalibration() error = %v", err)
+	}
+	got, err := LoadCalibration(path)
+	if err != nil {
+		t.Fatalf("LoadCalibration() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Sources, want.Sources) || got.Samples != want.Samples {
+		t.Errorf("LoadCalibration() = %+v, want %+v", got, want)
+	}
+
+	invalid := &Calibration{Sources: map[string]SourceCalibration{SourceAI: {Accuracy: []float64{0.5}}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Validate() accepted a calibration with the wrong number of bins")
+	}
+}
+
+func TestClassifier_Combine(t *testing.T) {
+	rules := func(category model.Category, confidence float64) model.ClassificationResult {
+		return model.ClassificationResult{Category: category, Confidence: confidence, Method: SourceRules}
+	}
+	ai := func(category model.Category, confidence float64) model.ClassificationResult {
+		return model.ClassificationResult{Category: category, Confidence: confidence, Method: SourceAI}
+	}
+	local := func(category model.Category, confidence float64) model.ClassificationResult {
+		return model.ClassificationResult{Category: category, Confidence: confidence, Method: SourceLocal}
+	}
+	learned := model.ClassificationResult{Category: model.CategoryFreelance, Confidence: UserRuleConfidence, Method: SourceUserRule}
+
+	tests := []struct {
+		name           string
+		thresholds     Thresholds
+		calibration    *Calibration
+		votes          []model.ClassificationResult
+		wantCategory   model.Category
+		wantMethod     string
+		wantConfidence float64
+	}{
+		{
+			name:           "confident AI outvotes a weak rule",
+			votes:          []model.ClassificationResult{rules(model.CategoryTransfer, 0.5), ai(model.CategoryFreelance, 0.9)},
+			wantCategory:   model.CategoryFreelance,
+			wantMethod:     SourceAI,
+			wantConfidence: 0.9,
+		},
+		{
+			name:           "AI below its vote threshold is ignored",
+			votes:          []model.ClassificationResult{rules(model.CategoryTransfer, 0.5), ai(model.CategoryFreelance, 0.65)},
+			wantCategory:   model.CategoryTransfer,
+			wantMethod:     SourceRules,
+			wantConfidence: 0.5,
+		},
+		{
+			name:           "agreeing votes average their confidence",
+			votes:          []model.ClassificationResult{rules(model.CategoryEmployment, 0.7), ai(model.CategoryEmployment, 0.9)},
+			wantCategory:   model.CategoryEmployment,
+			wantMethod:     SourceAI,
+			wantConfidence: 0.8,
+		},
+		{
+			name:           "learned rule outvotes a confident rule",
+			votes:          []model.ClassificationResult{learned, rules(model.CategoryEmployment, 0.95)},
+			wantCategory:   model.CategoryFreelance,
+			wantMethod:     SourceUserRule,
+			wantConfidence: UserRuleConfidence,
+		},
+		{
+			name: "down-weighted learned rule beats agreeing rules and local model",
+			calibration: &Calibration{Sources: map[string]SourceCalibration{
+				SourceUserRule: {Weight: 0.1, Accuracy: []float64{0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2, 0.2}},
+			}},
+			thresholds:     Thresholds{AI: 0.8, AIVote: 0.7, Accepted: 0.6},
+			votes:          []model.ClassificationResult{learned, rules(model.CategoryEmployment, 0.95), local(model.CategoryEmployment, 0.9)},
+			wantCategory:   model.CategoryFreelance,
+			wantMethod:     SourceUserRule,
+			wantConfidence: 0.2,
+		},
+		{
+			name: "calibrated AI confidence decides its vote",
+			calibration: &Calibration{Sources: map[string]SourceCalibration{
+				SourceAI: {Weight: 1, Accuracy: []float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.6, 0.6, 0.6}},
+			}},
+			votes:          []model.ClassificationResult{rules(model.CategoryTransfer, 0.5), ai(model.CategoryFreelance, 0.9)},
+			wantCategory:   model.CategoryTransfer,
+			wantMethod:     SourceRules,
+			wantConfidence: 0.5,
+		},
+		{
+			name: "down-weighted AI loses",
+			calibration: &Calibration{Sources: map[string]SourceCalibration{
+				SourceAI: {Weight: 0.1},
+			}},
+			votes:          []model.ClassificationResult{rules(model.CategoryTransfer, 0.5), ai(model.CategoryFreelance, 0.9)},
+			wantCategory:   model.CategoryTransfer,
+			wantMethod:     SourceRules,
+			wantConfidence: 0.5,
+		},
+		{
+			name:           "below the accepted threshold",
+			thresholds:     Thresholds{AI: 0.8, AIVote: 0.7, Accepted: 0.6},
+			votes:          []model.ClassificationResult{rules(model.CategoryTransfer, 0.5)},
+			wantCategory:   model.CategoryUncategorized,
+			wantMethod:     SourceRules,
+			wantConfidence: 0.5,
+		},
+		{
+			name:         "no votes",
+			votes:        []model.ClassificationResult{rules(model.CategoryUncategorized, 0)},
+			wantCategory: model.CategoryUncategorized,
+			wantMethod:   SourceRules,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClassifier(nil, nil, nil)
+			if tt.thresholds != (Thresholds{}) {
+				if err := c.SetThresholds(tt.thresholds); err != nil {
+					t.Fatalf("SetThresholds() error = %v", err)
+				}
+			}
+			c.SetCalibration(tt.calibration)
+
+			got := c.combine(tt.votes)
+			if got.Category != tt.wantCategory || got.Method != tt.wantMethod || !approxEqual(got.Confidence, tt.wantConfidence) {
+				t.Errorf("combine() = %s/%s/%.3f, want %s/%s/%.3f", got.Category, got.Method, got.Confidence,
+					tt.wantCategory, tt.wantMethod, tt.wantConfidence)
+			}
+			for _, alternative := range got.Alternatives {
+				if alternative.Category == got.Category {
+					t.Errorf("Alternatives %v include the chosen category", got.Alternatives)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifier_ThresholdsControlAICalls(t *testing.T) {
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryFreelance), &calls)
+	c := NewClassifier(newTestAI(t, server.URL, 10), nil, nil)
+
+	// The default pack matches SALARY confidently, so the AI is not asked...
+	tx := model.ParsedTransaction{Description: "SALARY JAN ACME LTD", Amount: 450000, Type: "credit"}
+	c.Classify(context.Background(), uuid.Nil, tx)
+	if calls.Load() != 0 {
+		t.Fatalf("AI requests = %d, want 0", calls.Load())
+	}
+
+	// ...unless the threshold asks for more certainty than any rule gives
+	if err := c.SetThresholds(Thresholds{AI: 1, AIVote: 0.7}); err != nil {
+		t.Fatalf("SetThresholds() error = %v", err)
+	}
+	result := c.Classify(context.Background(), uuid.Nil, tx)
+	if calls.Load() != 1 {
+		t.Errorf("AI requests = %d, want 1", calls.Load())
+	}
+	if result.Explanation == nil || len(result.Explanation.Votes) != 2 {
+		t.Errorf("Explanation = %+v, want both votes recorded", result.Explanation)
+	}
+
+	if err := c.SetThresholds(Thresholds{AI: 1.5}); err == nil {
+		t.Error("SetThresholds() accepted a threshold above 1")
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -2,50 +2,336 @@ package classifier
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
 )
 
-// Classifier combines AI and rule-based classification
-type Classifier struct {
-	ai    *AIClassifier
-	rules *RuleEngine
+// Default confidence thresholds for the hybrid classifier
+const (
+	// RuleConfidenceThreshold is the local confidence at which a transaction is not sent to the AI
+	RuleConfidenceThreshold = 0.8
+	// AIConfidenceThreshold is the calibrated AI confidence above which the AI's answer is counted as a vote
+	AIConfidenceThreshold = 0.7
+)
+
+// Thresholds tune when the hybrid classifier asks the AI and which answers it accepts
+type Thresholds struct {
+	AI       float64 `json:"ai"`       // Consult the AI when the local ensemble confidence is below this
+	AIVote   float64 `json:"ai_vote"`  // Count an AI answer only when its calibrated confidence exceeds this
+	Accepted float64 `json:"accepted"` // Leave transactions uncategorized below this ensemble confidence
+}
+
+// DefaultThresholds returns the thresholds used unless configured otherwise
+func DefaultThresholds() Thresholds {
+	return Thresholds{AI: RuleConfidenceThreshold, AIVote: AIConfidenceThreshold}
 }
 
-// NewClassifier creates a new hybrid classifier
-func NewClassifier(aiProvider, aiAPIKey string) *Classifier {
-	var ai *AIClassifier
-	if aiAPIKey != "" {
-		ai = NewAIClassifier(aiProvider, aiAPIKey)
+// Validate checks that every threshold is between 0 and 1
+func (t Thresholds) Validate() error {
+	for name, value := range map[string]float64{"ai": t.AI, "ai_vote": t.AIVote, "accepted": t.Accepted} {
+		if value < 0 || value > 1 {
+			return fmt.Errorf("threshold %s must be between 0 and 1", name)
+		}
 	}
+	return nil
+}
+
+// Classifier classifies by a user's learned rules when one matches, and
+// otherwise combines rule packs and AI classification by weighted,
+// calibrated voting
+type Classifier struct {
+	ai          *AIClassifier
+	rules       *RuleEngine
+	local       *LocalModel
+	users       UserStore
+	cache       *Cache
+	thresholds  Thresholds
+	calibration *Calibration
+	aiErrors    aiErrorCounter
+}
 
+// UserStore provides the per-user data the classifier uses: learned rules
+// and the setting that keeps a user's transactions away from external AI
+type UserStore interface {
+	UserRuleStore
+	GetUser(ctx context.Context, id uuid.UUID) (*model.User, error)
+}
+
+// NewClassifier creates a new hybrid classifier. ai may be nil to classify
+// with rules only, users may be nil, in which case no learned rules or
+// settings are applied, and cache may be nil to send every uncertain
+// transaction to the AI.
+func NewClassifier(ai *AIClassifier, users UserStore, cache *Cache) *Classifier {
 	return &Classifier{
-		ai:    ai,
-		rules: NewRuleEngine(),
+		ai:         ai,
+		rules:      NewRuleEngine(),
+		users:      users,
+		cache:      cache,
+		thresholds: DefaultThresholds(),
 	}
 }
 
-// Classify classifies a transaction using AI with rule-based fallback
-func (c *Classifier) Classify(ctx context.Context, description string, txType string, amount float64) model.ClassificationResult {
-	// Try AI first if available
-	if c.ai != nil && c.ai.IsAvailable() {
-		result, err := c.ai.Classify(ctx, description, txType, amount)
-		if err == nil && result.Confidence > 0.7 {
-			return result
-		}
+// SetThresholds replaces the confidence thresholds. Call it before classifying.
+func (c *Classifier) SetThresholds(thresholds Thresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return err
 	}
+	c.thresholds = thresholds
+	return nil
+}
+
+// Thresholds returns the confidence thresholds in use
+func (c *Classifier) Thresholds() Thresholds {
+	return c.thresholds
+}
+
+// SetLocalModel installs a statistical model that votes between the rule
+// packs and the AI; nil removes it. Call it before classifying.
+func (c *Classifier) SetLocalModel(local *LocalModel) {
+	c.local = local
+}
+
+// LocalModel returns the local statistical model, or nil
+func (c *Classifier) LocalModel() *LocalModel {
+	return c.local
+}
 
-	// Fallback to rules
-	return c.rules.Classify(description, txType)
+// SetCalibration replaces the vote calibration; nil takes confidences at
+// face value with default weights. Call it before classifying.
+func (c *Classifier) SetCalibration(calibration *Calibration) {
+	c.calibration = calibration
 }
 
-// ClassifyBatch classifies multiple transactions
-func (c *Classifier) ClassifyBatch(ctx context.Context, transactions []model.ParsedTransaction) []model.ClassificationResult {
+// Calibration returns the vote calibration in use, or nil
+func (c *Classifier) Calibration() *Calibration {
+	return c.calibration
+}
+
+// AIErrors returns the AI requests that failed since the classifier was created, by kind
+func (c *Classifier) AIErrors() AIErrorStats {
+	return c.aiErrors.snapshot()
+}
+
+// Classify classifies a single transaction. Pass uuid.Nil for anonymous requests.
+func (c *Classifier) Classify(ctx context.Context, userID uuid.UUID, tx model.ParsedTransaction) model.ClassificationResult {
+	return c.ClassifyBatch(ctx, userID, []model.ParsedTransaction{tx})[0]
+}
+
+// ClassifyBatch classifies transactions for a user. A matching learned user
+// rule decides outright. Otherwise rule packs and the local model, when
+// installed, vote first; only transactions whose combined confidence is
+// below the AI threshold get an AI vote, in batches, from the cache or,
+// unless the user has turned external AI off, from the provider. If the AI
+// fails or the context is cancelled, the local votes decide; AI failures are
+// counted in AIErrors. Each result explains its evidence and offers the
+// categories other votes suggested as alternatives.
+func (c *Classifier) ClassifyBatch(ctx context.Context, userID uuid.UUID, transactions []model.ParsedTransaction) []model.ClassificationResult {
 	results := make([]model.ClassificationResult, len(transactions))
+	votes := make([][]model.ClassificationResult, len(transactions))
+	userRules := c.loadUserRules(ctx, userID)
 
+	var pending []int
+	var pendingTxs []model.ParsedTransaction
 	for i, tx := range transactions {
-		results[i] = c.Classify(ctx, tx.Description, tx.Type, tx.Amount)
+		tx = parser.EnrichNarration(tx)
+		if rule := MatchUserRule(userRules, tx.Description, tx.Type); rule != nil {
+			votes[i] = append(votes[i], userRuleResult(rule))
+		}
+		votes[i] = append(votes[i], c.rules.ClassifyTransaction(tx))
+		if c.local != nil {
+			votes[i] = append(votes[i], c.local.Classify(tx))
+		}
+
+		results[i] = c.combine(votes[i])
+		if results[i].Confidence < c.thresholds.AI && results[i].Method != SourceUserRule {
+			pending = append(pending, i)
+			pendingTxs = append(pendingTxs, tx)
+		}
 	}
 
+	if len(pending) == 0 || c.ai == nil || !c.ai.IsAvailable() {
+		return results
+	}
+
+	aiVotes, err := c.aiVotes(ctx, userID, pendingTxs, c.externalAIAllowed(ctx, userID))
+	if err != nil && ctx.Err() == nil {
+		c.aiErrors.record(err, time.Now())
+	}
+	for j, i := range pending {
+		if aiVotes[j].Method != "" {
+			results[i] = c.combine(append(votes[i], aiVotes[j]))
+		}
+	}
 	return results
 }
+
+// aiVotes returns the AI's answer for each transaction, from the cache or,
+// when calls are allowed, from the provider. Transactions without an answer
+// get a zero result.
+func (c *Classifier) aiVotes(ctx context.Context, userID uuid.UUID, transactions []model.ParsedTransaction, callAI bool) ([]model.ClassificationResult, error) {
+	votes := make([]model.ClassificationResult, len(transactions))
+	version := c.cacheVersion()
+
+	var uncached []int
+	var uncachedTxs []model.ParsedTransaction
+	for i, tx := range transactions {
+		if cached, ok := c.cache.Get(ctx, version, tx); ok {
+			votes[i] = cached
+			continue
+		}
+		uncached = append(uncached, i)
+		uncachedTxs = append(uncachedTxs, tx)
+	}
+	if len(uncached) == 0 || !callAI {
+		return votes, nil
+	}
+
+	aiResults, err := c.ai.ClassifyBatch(ctx, userID, uncachedTxs)
+	if err != nil {
+		return votes, err
+	}
+	for j, i := range uncached {
+		votes[i] = aiResults[j]
+		c.cache.Put(ctx, version, uncachedTxs[j], aiResults[j])
+	}
+	return votes, nil
+}
+
+// combine tallies the stages' votes. Each category scores the sum of its
+// voters' weighted, calibrated confidence; the best-scoring category wins
+// with the weighted mean confidence of the votes it received. The result
+// keeps the method, rule and evidence of its strongest voter. A learned user
+// rule is the user's own correction, so when one votes it is the only vote
+// counted, whatever its calibrated weight, and the others are alternatives.
+func (c *Classifier) combine(votes []model.ClassificationResult) model.ClassificationResult {
+	type tally struct {
+		score, weight float64
+		lead          model.ClassificationResult
+		leadScore     float64
+	}
+	tallies := make(map[model.Category]*tally)
+	var order []model.Category
+	var counted []model.Vote
+	var candidates []model.Alternative
+	overruled := slices.ContainsFunc(votes, func(vote model.ClassificationResult) bool {
+		return vote.Method == SourceUserRule && castsVote(vote)
+	})
+
+	for _, vote := range votes {
+		candidates = append(candidates, vote.Alternatives...)
+		if !castsVote(vote) {
+			continue
+		}
+		confidence := c.calibration.Calibrate(vote.Method, vote.Confidence)
+		if (overruled && vote.Method != SourceUserRule) || (vote.Method == SourceAI && confidence <= c.thresholds.AIVote) {
+			candidates = append(candidates, model.Alternative{Category: vote.Category, Score: confidence})
+			continue
+		}
+		weight := c.calibration.Weight(vote.Method)
+		counted = append(counted, model.Vote{Source: vote.Method, Category: vote.Category, Confidence: confidence, Weight: weight})
+
+		t, ok := tallies[vote.Category]
+		if !ok {
+			t = &tally{}
+			tallies[vote.Category] = t
+			order = append(order, vote.Category)
+		}
+		t.score += weight * confidence
+		t.weight += weight
+		if weight*confidence > t.leadScore || t.lead.Method == "" {
+			t.lead, t.leadScore = vote, weight*confidence
+		}
+	}
+
+	if len(order) == 0 {
+		// No votes: report the rule engine's "no match", which is always cast
+		var result model.ClassificationResult
+		for _, vote := range votes {
+			if vote.Method == SourceRules {
+				result = vote
+			}
+		}
+		result.Alternatives = rankAlternatives(result.Category, candidates)
+		return result
+	}
+
+	// Ties go to the earlier voter: rule packs, the local model, then the AI
+	sort.SliceStable(order, func(i, j int) bool {
+		return tallies[order[i]].score > tallies[order[j]].score
+	})
+	winner := tallies[order[0]]
+	result := winner.lead
+	if winner.weight > 0 {
+		result.Confidence = winner.score / winner.weight
+	}
+	for _, category := range order[1:] {
+		t := tallies[category]
+		candidates = append(candidates, model.Alternative{Category: category, Score: t.score / t.weight})
+	}
+
+	explanation := model.Explanation{}
+	if result.Explanation != nil {
+		explanation = *result.Explanation
+	}
+	explanation.Votes = counted
+	result.Explanation = &explanation
+
+	if result.Confidence < c.thresholds.Accepted && !overruled {
+		candidates = append(candidates, model.Alternative{Category: result.Category, Score: result.Confidence})
+		result.Category = model.CategoryUncategorized
+		explanation.Summary = fmt.Sprintf("No category reached the %.2f confidence needed; best was: %s", c.thresholds.Accepted, explanation.Summary)
+	}
+	result.Alternatives = rankAlternatives(result.Category, candidates)
+	return result
+}
+
+// Cache returns the classification cache, or nil when caching is disabled
+func (c *Classifier) Cache() *Cache {
+	return c.cache
+}
+
+// cacheVersion identifies the rule packs and AI prompt behind cached AI
+// votes, so they are invalidated when either changes
+func (c *Classifier) cacheVersion() string {
+	return c.rules.Fingerprint() + "/" + c.ai.Version()
+}
+
+// Rules returns the rule engine so rule packs can be managed at runtime
+func (c *Classifier) Rules() *RuleEngine {
+	return c.rules
+}
+
+// externalAIAllowed reports whether the user's transactions may be sent to
+// the AI provider. Users not stored yet have the default setting; any other
+// lookup failure keeps the transactions local.
+func (c *Classifier) externalAIAllowed(ctx context.Context, userID uuid.UUID) bool {
+	if c.users == nil || userID == uuid.Nil {
+		return true
+	}
+	user, err := c.users.GetUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return true
+	}
+	return err == nil && !user.ExternalAIDisabled
+}
+
+// loadUserRules returns the user's learned rules. Lookup failures fall back
+// to global classification rather than failing the request.
+func (c *Classifier) loadUserRules(ctx context.Context, userID uuid.UUID) []model.UserRule {
+	if c.users == nil || userID == uuid.Nil {
+		return nil
+	}
+	rules, err := c.users.ListUserRules(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	return rules
+}
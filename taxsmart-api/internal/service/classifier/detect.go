@@ -0,0 +1,339 @@
+package classifier
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// MethodDetector is the method of results from the credit detector
+const MethodDetector = "detector"
+
+// DefaultMatchWindow is how long after a debit its reversal or refund may arrive
+const DefaultMatchWindow = 45 * 24 * time.Hour
+
+// Detector confidences
+const (
+	pairedConfidence   = 0.95 // Reversal or refund paired with its debit
+	referenceOnly      = 0.9  // Credit sharing its debit's amount and reference, without a keyword
+	loanConfidence     = 0.9  // Disbursement from a known lender
+	unpairedConfidence = 0.8  // Reversal or refund keyword with no debit found
+)
+
+// refundWords mark a credit returning money for a purchase
+var refundWords = [][]string{{"REFUND"}, {"REFUNDED"}, {"CHARGEBACK"}, {"CHARGE", "BACK"}, {"CHG", "BACK"}}
+
+// reversalWords mark a credit returning money for a failed or duplicate
+// transaction. REV on its own is also a title ("REV FATHER OKEKE"), so it
+// only counts next to the channel it reverses.
+var reversalWords = [][]string{
+	{"REVERSAL"}, {"REVERSED"}, {"RVSL"},
+	{"REV", "NIP"}, {"NIP", "REV"}, {"REV", "TRF"}, {"TRF", "REV"}, {"REV", "POS"}, {"POS", "REV"}, {"REV", "WEB"}, {"WEB", "REV"},
+}
+
+// loanPhrases say outright that a credit is a loan
+var loanPhrases = [][]string{{"LOAN", "DISBURSEMENT"}, {"LOAN", "DISB"}}
+
+// lenderNames name digital lenders. Employers and shops share some of the
+// names ("CARBON ENERGY LTD"), so a name only marks a loan within
+// loanMarkerReach words of a loan marker.
+var lenderNames = [][]string{
+	{"CARBON"}, {"FAIRMONEY"}, {"FAIR", "MONEY"}, {"RENMONEY"}, {"PALMCREDIT"}, {"PALM", "CREDIT"},
+	{"AELLA"}, {"QUICKCHECK"}, {"KIAKIA"}, {"LIDYA"}, {"MIGO"}, {"OKASH"},
+}
+
+// loanMarkers are the words that, next to a lender's name, mark a disbursement
+var loanMarkers = map[string]bool{"LOAN": true, "DISB": true, "DISBURSEMENT": true, "DISBURSED": true}
+
+// loanMarkerReach is how many words apart a lender's name and a loan marker may be
+const loanMarkerReach = 3
+
+// CreditDetector recognises credits that return or lend money rather than
+// earn it: reversals of failed transfers, refunds and chargebacks, each
+// paired with the debit it undoes, and loan disbursements from digital
+// lenders. Their results replace whatever the classifier guessed.
+type CreditDetector struct {
+	Window time.Duration // How long after a debit its reversal or refund may arrive
+}
+
+// NewCreditDetector creates a detector with the default match window
+func NewCreditDetector() *CreditDetector {
+	return &CreditDetector{Window: DefaultMatchWindow}
+}
+
+// Detection is a credit in a batch the detector recognised
+type Detection struct {
+	Index    int                        // Position of the credit in the batch
+	Result   model.ClassificationResult // Replaces the credit's classification
+	Linked   int                        // Position in the batch of the debit it undoes, or -1
+	LinkedID uuid.UUID                  // Stored debit it undoes, when that is not in the batch
+}
+
+// debitCandidate is a debit a reversal or refund may undo
+type debitCandidate struct {
+	index  int // Position in the batch, or -1 for a stored debit
+	id     uuid.UUID
+	date   time.Time
+	amount float64
+	refs   []string        // Explicit references: statement reference and session ref
+	words  map[string]bool // Long numbers in the description, other than account numbers
+}
+
+// Detect finds the credits in a batch the detector recognises, given their
+// classification results and the user's stored transactions around the
+// batch's dates. Debits already linked from a stored credit are not paired
+// again, and credits a learned rule classified are left to the user's rule.
+func (d *CreditDetector) Detect(transactions []model.ParsedTransaction, results []model.ClassificationResult, stored []model.Transaction) []Detection {
+	linked := make(map[uuid.UUID]bool)
+	for _, tx := range stored {
+		if tx.LinkedTransactionID != nil {
+			linked[*tx.LinkedTransactionID] = true
+		}
+	}
+
+	var debits []debitCandidate
+	for _, tx := range stored {
+		if tx.TransactionType == "debit" && !linked[tx.ID] {
+			debits = append(debits, newCandidate(-1, tx.ID, tx.TransactionDate, tx.Amount, tx.Description, tx.AccountNumber, tx.SessionRef))
+		}
+	}
+	for i, tx := range transactions {
+		if tx.Type == "debit" {
+			debits = append(debits, newCandidate(i, uuid.Nil, tx.Date, tx.Amount, tx.Description, tx.AccountNumber, tx.SessionRef, tx.Reference))
+		}
+	}
+	used := make([]bool, len(debits))
+
+	var detections []Detection
+	for i, tx := range transactions {
+		if tx.Type != "credit" || results[i].Method == SourceUserRule {
+			continue
+		}
+		n := newNarration(tx)
+
+		category := model.CategoryReversal
+		keyword, field, span, found := findWords(n, refundWords)
+		if found {
+			category = model.CategoryRefund
+		} else {
+			keyword, field, span, found = findWords(n, reversalWords)
+		}
+
+		match, byReference := d.pair(tx, debits, used, found)
+		var result model.ClassificationResult
+		detection := Detection{Index: i, Linked: -1}
+		switch {
+		case match >= 0 && (found || byReference):
+			used[match] = true
+			detection.Linked = debits[match].index
+			detection.LinkedID = debits[match].id
+			confidence := pairedConfidence
+			if !found {
+				confidence = referenceOnly
+			}
+			summary := fmt.Sprintf("Returns the debit of %.2f on %s%s", debits[match].amount,
+				debits[match].date.Format("2006-01-02"), pairReason(byReference))
+			explanation := &model.Explanation{Summary: summary}
+			if found {
+				explanation = keywordExplanation(n, keyword, field, span, "")
+				explanation.Summary = summary
+			}
+			result = model.ClassificationResult{Category: category, Confidence: confidence, Explanation: explanation}
+		case found:
+			result = model.ClassificationResult{
+				Category:    category,
+				Confidence:  unpairedConfidence,
+				Explanation: keywordExplanation(n, keyword, field, span, "a "+string(category)+", though no matching debit was found"),
+			}
+		default:
+			keyword, field, span, found = findLoan(n)
+			if !found {
+				continue
+			}
+			result = model.ClassificationResult{
+				Category:    model.CategoryLoan,
+				Confidence:  loanConfidence,
+				Explanation: keywordExplanation(n, keyword, field, span, "a loan disbursement, which is not income"),
+			}
+		}
+
+		result.Method = MethodDetector
+		detection.Result = withAlternatives(result, results[i])
+		detections = append(detections, detection)
+	}
+	return detections
+}
+
+// pair finds the unused debit a credit most likely undoes: the same amount,
+// dated no later than the credit and within the window. A shared reference
+// wins over the nearest date. Long numbers shared by the descriptions only
+// count as a reference when the credit names itself a reversal or refund,
+// since a plain payment from the same account carries the same numbers. It
+// returns -1 when no debit qualifies.
+func (d *CreditDetector) pair(tx model.ParsedTransaction, debits []debitCandidate, used []bool, keyword bool) (int, bool) {
+	if tx.Date.IsZero() {
+		return -1, false
+	}
+	credit := newCandidate(-1, uuid.Nil, tx.Date, tx.Amount, tx.Description, tx.AccountNumber, tx.SessionRef, tx.Reference)
+
+	best, bestByReference := -1, false
+	for j, debit := range debits {
+		if used[j] || math.Abs(debit.amount-tx.Amount) >= 0.005 {
+			continue
+		}
+		if debit.date.After(tx.Date) || tx.Date.Sub(debit.date) > d.Window {
+			continue
+		}
+		byReference := sharesReference(credit, debit, keyword)
+		switch {
+		case best < 0,
+			byReference && !bestByReference,
+			byReference == bestByReference && debit.date.After(debits[best].date):
+			best, bestByReference = j, byReference
+		}
+	}
+	return best, bestByReference
+}
+
+// newCandidate prepares a transaction for pairing, collecting its references
+// and the long numbers in its description that are neither its amount nor
+// an account number, whether parsed or NUBAN-shaped
+func newCandidate(index int, id uuid.UUID, date time.Time, amount float64, description, accountNumber string, refs ...string) debitCandidate {
+	candidate := debitCandidate{index: index, id: id, date: date, amount: amount, words: make(map[string]bool)}
+	for _, ref := range refs {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			candidate.refs = append(candidate.refs, strings.ToUpper(ref))
+		}
+	}
+	amountText := fmt.Sprintf("%.0f", amount)
+	for _, token := range tokenize(strings.ToUpper(description)) {
+		if len(token) >= 6 && len(token) != 10 && isDigits(token) && token != amountText && token != accountNumber {
+			candidate.words[token] = true
+		}
+	}
+	return candidate
+}
+
+// sharesReference reports whether a credit and a debit carry the same
+// explicit reference, one's reference appears in the other's description or,
+// when numbers is set, the same long number appears in both descriptions
+func sharesReference(credit, debit debitCandidate, numbers bool) bool {
+	for _, ref := range credit.refs {
+		for _, other := range debit.refs {
+			if ref == other {
+				return true
+			}
+		}
+		if debit.words[ref] {
+			return true
+		}
+	}
+	for word := range credit.words {
+		if numbers && debit.words[word] {
+			return true
+		}
+		for _, ref := range debit.refs {
+			if word == ref {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pairReason describes how a pairing was made
+func pairReason(byReference bool) string {
+	if byReference {
+		return ", matched by amount and reference"
+	}
+	return ", matched by amount and date"
+}
+
+// findWords looks for the first of a list of word sequences in the
+// description, then the counterparty
+func findWords(n narration, list [][]string) (keyword, field string, span [2]int, found bool) {
+	for _, f := range []struct {
+		name string
+		text matchText
+	}{{FieldDescription, n.description}, {FieldCounterparty, n.counterparty}} {
+		for _, words := range list {
+			if at := indexSequence(f.text.tokens, words); at >= 0 {
+				span := [2]int{f.text.bounds[at][0], f.text.bounds[at+len(words)-1][1]}
+				return strings.Join(words, " "), f.name, span, true
+			}
+		}
+	}
+	return "", "", [2]int{}, false
+}
+
+// findLoan looks for a phrase saying a credit is a loan, or a lender's name
+// next to a loan marker in the same field, in the description then the
+// counterparty
+func findLoan(n narration) (keyword, field string, span [2]int, found bool) {
+	if keyword, field, span, found = findWords(n, loanPhrases); found {
+		return keyword, field, span, true
+	}
+	for _, f := range []struct {
+		name string
+		text matchText
+	}{{FieldDescription, n.description}, {FieldCounterparty, n.counterparty}} {
+		tokens := f.text.tokens
+		for _, words := range lenderNames {
+			for at := 0; at+len(words) <= len(tokens); at++ {
+				if !slices.Equal(tokens[at:at+len(words)], words) {
+					continue
+				}
+				end := at + len(words) - 1
+				for k := max(0, at-loanMarkerReach); k <= min(len(tokens)-1, end+loanMarkerReach); k++ {
+					if (k < at || k > end) && loanMarkers[tokens[k]] {
+						first, last := min(at, k), max(end, k)
+						span := [2]int{f.text.bounds[first][0], f.text.bounds[last][1]}
+						return strings.Join(words, " ") + " " + tokens[k], f.name, span, true
+					}
+				}
+			}
+		}
+	}
+	return "", "", [2]int{}, false
+}
+
+// matchedText returns the text at span in a narration field, in its original
+// case when upper-casing kept its length
+func matchedText(n narration, field string, span [2]int) string {
+	text := n.description
+	if field == FieldCounterparty {
+		text = n.counterparty
+	}
+	source := text.raw
+	if len(text.upper) != len(text.raw) {
+		source = text.upper
+	}
+	return source[span[0]:span[1]]
+}
+
+// keywordExplanation describes a keyword match marking what a credit is
+func keywordExplanation(n narration, keyword, field string, span [2]int, marks string) *model.Explanation {
+	matched := matchedText(n, field, span)
+	return &model.Explanation{
+		Summary: fmt.Sprintf("%q marks %s", matched, marks),
+		Pattern: keyword,
+		Field:   field,
+		Matched: matched,
+		Span:    &model.Span{Start: span[0], End: span[1]},
+	}
+}
+
+// isDigits reports whether s is all ASCII digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
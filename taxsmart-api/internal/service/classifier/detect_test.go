@@ -0,0 +1,230 @@
+package classifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestCreditDetector_Detect(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	guess := model.ClassificationResult{Category: model.CategoryOtherIncome, Confidence: 0.6, Method: "ai"}
+	storedDebit := model.Transaction{
+		ID: uuid.New(), TransactionDate: day(1), Description: "POS/SHOPRITE LEKKI 40017733", Amount: 18_250, TransactionType: "debit",
+	}
+
+	tests := []struct {
+		name         string
+		transactions []model.ParsedTransaction
+		stored       []model.Transaction
+		ruled        bool // The credit was classified by a learned rule
+		want         model.Category
+		linked       int
+		linkedID     uuid.UUID
+	}{
+		{
+			name: "reversal paired with a debit in the batch",
+			transactions: []model.ParsedTransaction{
+				{Date: day(3), Description: "RVSL NIP TRF TO ADA OKAFOR", Amount: 25_000, Type: "credit"},
+				{Date: day(2), Description: "NIP TRF TO ADA OKAFOR", Amount: 25_000, Type: "debit"},
+			},
+			want:   model.CategoryReversal,
+			linked: 1,
+		},
+		{
+			name: "refund paired with a stored debit by reference",
+			transactions: []model.ParsedTransaction{
+				{Date: day(9), Description: "POS REFUND 40017733", Amount: 18_250, Type: "credit"},
+			},
+			stored:   []model.Transaction{storedDebit},
+			want:     model.CategoryRefund,
+			linked:   -1,
+			linkedID: storedDebit.ID,
+		},
+		{
+			name: "reference wins over the nearer date",
+			transactions: []model.ParsedTransaction{
+				{Date: day(10), Description: "CHARGEBACK", Amount: 5_000, Type: "credit", Reference: "FT2603AB"},
+				{Date: day(2), Description: "WEB PAY", Amount: 5_000, Type: "debit", Reference: "FT2603AB"},
+				{Date: day(9), Description: "AIRTIME", Amount: 5_000, Type: "debit"},
+			},
+			want:   model.CategoryRefund,
+			linked: 1,
+		},
+		{
+			name: "same session ref without a keyword",
+			transactions: []model.ParsedTransaction{
+				{Date: day(2), Description: "NIP CR", Amount: 7_500, Type: "credit", Narration: model.Narration{SessionRef: "000013260302101500"}},
+				{Date: day(2), Description: "NIP DR", Amount: 7_500, Type: "debit", Narration: model.Narration{SessionRef: "000013260302101500"}},
+			},
+			want:   model.CategoryReversal,
+			linked: 1,
+		},
+		{
+			name: "debit outside the window",
+			transactions: []model.ParsedTransaction{
+				{Date: day(1).AddDate(0, 2, 0), Description: "REVERSAL", Amount: 25_000, Type: "credit"},
+				{Date: day(1), Description: "NIP TRF TO ADA OKAFOR", Amount: 25_000, Type: "debit"},
+			},
+			want:   model.CategoryReversal,
+			linked: -1,
+		},
+		{
+			name: "debit already linked from a stored credit",
+			transactions: []model.ParsedTransaction{
+				{Date: day(9), Description: "POS REFUND 40017733", Amount: 18_250, Type: "credit"},
+			},
+			stored: []model.Transaction{
+				storedDebit,
+				{ID: uuid.New(), TransactionDate: day(4), Description: "REFUND", Amount: 18_250, TransactionType: "credit", LinkedTransactionID: &storedDebit.ID},
+			},
+			want:   model.CategoryRefund,
+			linked: -1,
+		},
+		{
+			name: "lender disbursement",
+			transactions: []model.ParsedTransaction{
+				{Date: day(4), Description: "NIP/FAIRMONEY MFB/LOAN", Amount: 50_000, Type: "credit"},
+			},
+			want:   model.CategoryLoan,
+			linked: -1,
+		},
+		{
+			name: "lender named in the counterparty",
+			transactions: []model.ParsedTransaction{
+				{Date: day(4), Description: "NIP CR 1029", Amount: 30_000, Type: "credit", Narration: model.Narration{Counterparty: "Renmoney MFB Loan"}},
+			},
+			want:   model.CategoryLoan,
+			linked: -1,
+		},
+		{
+			name: "loan disbursement without a known lender",
+			transactions: []model.ParsedTransaction{
+				{Date: day(4), Description: "LOAN DISB/ACCT 2210", Amount: 80_000, Type: "credit"},
+			},
+			want:   model.CategoryLoan,
+			linked: -1,
+		},
+		{
+			name: "channel reversal",
+			transactions: []model.ParsedTransaction{
+				{Date: day(3), Description: "POS REV PAYARENA 2034819", Amount: 5_000, Type: "credit"},
+			},
+			want:   model.CategoryReversal,
+			linked: -1,
+		},
+		{
+			name: "employer sharing a lender's name",
+			transactions: []model.ParsedTransaction{
+				{Date: day(28), Description: "NIP/CARBON ENERGY LTD/SALARY MAR", Amount: 400_000, Type: "credit"},
+			},
+		},
+		{
+			name: "employer sharing a lender's name in the counterparty",
+			transactions: []model.ParsedTransaction{
+				{Date: day(28), Description: "PAYROLL MAR 2026", Amount: 400_000, Type: "credit", Narration: model.Narration{Counterparty: "Migo Foods Ltd"}},
+			},
+		},
+		{
+			name: "loan marker far from the lender's name",
+			transactions: []model.ParsedTransaction{
+				{Date: day(28), Description: "CARBON ENERGY LTD SALARY MAR LESS STAFF LOAN", Amount: 380_000, Type: "credit"},
+			},
+		},
+		{
+			name: "REV as a title",
+			transactions: []model.ParsedTransaction{
+				{Date: day(5), Description: "TRF FROM REV FATHER OKEKE", Amount: 20_000, Type: "credit"},
+			},
+		},
+		{
+			name: "employer whose name starts with REV",
+			transactions: []model.ParsedTransaction{
+				{Date: day(28), Description: "NIP/REVENUE CYCLE LTD/SALARY", Amount: 350_000, Type: "credit"},
+			},
+		},
+		{
+			name: "same amount without keyword or reference",
+			transactions: []model.ParsedTransaction{
+				{Date: day(3), Description: "TRF FROM TUNDE", Amount: 25_000, Type: "credit"},
+				{Date: day(2), Description: "TRF TO ADA", Amount: 25_000, Type: "debit"},
+			},
+		},
+		{
+			name: "payment from the account paid earlier is not a reversal",
+			transactions: []model.ParsedTransaction{
+				{Date: day(31), Description: "NIP FRM ADA EZE 0123456789 INVOICE 14 DESIGN WORK", Amount: 250_000, Type: "credit",
+					Narration: model.Narration{Counterparty: "ADA EZE", AccountNumber: "0123456789"}},
+				{Date: day(1), Description: "NIP TRF TO ADA EZE 0123456789 HOUSE DEPOSIT", Amount: 250_000, Type: "debit",
+					Narration: model.Narration{Counterparty: "ADA EZE", AccountNumber: "0123456789"}},
+			},
+		},
+		{
+			name: "learned rule is left alone",
+			transactions: []model.ParsedTransaction{
+				{Date: day(4), Description: "CARBON LOAN PAYOUT", Amount: 50_000, Type: "credit"},
+			},
+			ruled: true,
+		},
+	}
+
+	detector := NewCreditDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make([]model.ClassificationResult, len(tt.transactions))
+			for i := range results {
+				results[i] = guess
+			}
+			if tt.ruled {
+				results[0].Method = SourceUserRule
+			}
+
+			detections := detector.Detect(tt.transactions, results, tt.stored)
+			if tt.want == "" {
+				if len(detections) != 0 {
+					t.Fatalf("Detect() = %+v, want nothing", detections)
+				}
+				return
+			}
+			if len(detections) != 1 || detections[0].Index != 0 {
+				t.Fatalf("Detect() = %+v, want the credit", detections)
+			}
+			d := detections[0]
+			if d.Result.Category != tt.want || d.Result.Method != MethodDetector || d.Result.Explanation == nil {
+				t.Errorf("Result = %+v, want %s from the detector with an explanation", d.Result, tt.want)
+			}
+			if d.Linked != tt.linked || d.LinkedID != tt.linkedID {
+				t.Errorf("linked to %d / %s, want %d / %s", d.Linked, d.LinkedID, tt.linked, tt.linkedID)
+			}
+			if d.Result.Category.IsIncome() {
+				t.Errorf("%s counts as income", d.Result.Category)
+			}
+			if len(d.Result.Alternatives) == 0 || d.Result.Alternatives[0].Category != model.CategoryOtherIncome {
+				t.Errorf("Alternatives = %+v, want the classifier's guess", d.Result.Alternatives)
+			}
+		})
+	}
+}
+
+func TestCreditDetector_PairsEachDebitOnce(t *testing.T) {
+	date := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	transactions := []model.ParsedTransaction{
+		{Date: date, Description: "NIP TRF TO ADA", Amount: 10_000, Type: "debit"},
+		{Date: date, Description: "REVERSAL", Amount: 10_000, Type: "credit"},
+		{Date: date, Description: "REVERSAL", Amount: 10_000, Type: "credit"},
+	}
+	results := make([]model.ClassificationResult, len(transactions))
+
+	detections := NewCreditDetector().Detect(transactions, results, nil)
+	if len(detections) != 2 {
+		t.Fatalf("Detect() = %+v, want both reversals", detections)
+	}
+	if detections[0].Linked != 0 || detections[1].Linked != -1 {
+		t.Errorf("linked to %d and %d, want only the first reversal paired", detections[0].Linked, detections[1].Linked)
+	}
+	if detections[1].Result.Confidence >= detections[0].Result.Confidence {
+		t.Errorf("unpaired confidence %.2f, want below paired %.2f", detections[1].Result.Confidence, detections[0].Result.Confidence)
+	}
+}
@@ -0,0 +1,158 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Kinds of AI failure. Test for them with errors.Is.
+var (
+	ErrAIAuth          = errors.New("AI provider rejected the credentials")
+	ErrAIQuota         = errors.New("AI provider quota or rate limit exceeded")
+	ErrAITimeout       = errors.New("AI provider timed out")
+	ErrAIInvalidOutput = errors.New("AI provider returned invalid output")
+	ErrAIUnavailable   = errors.New("AI provider unavailable")
+)
+
+// aiErrorKinds names the kinds of AI failure in AIErrorStats
+var aiErrorKinds = []struct {
+	name string
+	kind error
+}{
+	{"auth", ErrAIAuth},
+	{"quota", ErrAIQuota},
+	{"timeout", ErrAITimeout},
+	{"invalid_output", ErrAIInvalidOutput},
+	{"unavailable", ErrAIUnavailable},
+}
+
+// AIErrorStats counts the AI requests that failed since the classifier
+// started. Classification falls back to the local votes when the AI fails, so
+// these are how an expired key or an exhausted quota gets noticed.
+type AIErrorStats struct {
+	Total     int64            `json:"total"`
+	ByKind    map[string]int64 `json:"by_kind"` // "auth", "quota", "timeout", "invalid_output", "unavailable" or "other"
+	LastError string           `json:"last_error,omitempty"`
+	LastAt    *time.Time       `json:"last_at,omitempty"`
+}
+
+// aiErrorCounter tallies failed AI requests by kind
+type aiErrorCounter struct {
+	mu    sync.Mutex
+	stats AIErrorStats
+}
+
+// record counts a failed request
+func (c *aiErrorCounter) record(err error, now time.Time) {
+	name := "other"
+	for _, k := range aiErrorKinds {
+		if errors.Is(err, k.kind) {
+			name = k.name
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats.ByKind == nil {
+		c.stats.ByKind = make(map[string]int64)
+	}
+	c.stats.Total++
+	c.stats.ByKind[name]++
+	c.stats.LastError, c.stats.LastAt = err.Error(), &now
+}
+
+// snapshot returns a copy of the counts
+func (c *aiErrorCounter) snapshot() AIErrorStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.ByKind = make(map[string]int64, len(c.stats.ByKind))
+	for name, n := range c.stats.ByKind {
+		stats.ByKind[name] = n
+	}
+	return stats
+}
+
+// AIError describes a failed AI request
+type AIError struct {
+	Kind       error  // One of the ErrAI kinds
+	Provider   string // Provider and model, e.g. "openai/gpt-4o-mini"
+	StatusCode int    // 0 when no response was received
+	Message    string // The provider's own error message, when it sent one
+	Err        error  // Underlying error, if any
+}
+
+func (e *AIError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Provider, e.Kind)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap exposes both the kind and the underlying error to errors.Is and errors.As
+func (e *AIError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// statusError classifies an unsuccessful provider response
+func (c *AIClassifier) statusError(status int, body []byte) *AIError {
+	kind := ErrAIUnavailable
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		kind = ErrAIAuth
+	case status == http.StatusTooManyRequests || status == http.StatusPaymentRequired:
+		kind = ErrAIQuota
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		kind = ErrAITimeout
+	}
+	return &AIError{Kind: kind, Provider: c.provider.Name(), StatusCode: status, Message: providerErrorMessage(body)}
+}
+
+// transportError classifies a request that got no response. Cancellation by
+// the caller is returned as is.
+func (c *AIClassifier) transportError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &AIError{Kind: ErrAITimeout, Provider: c.provider.Name(), Err: err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return &AIError{Kind: ErrAIUnavailable, Provider: c.provider.Name(), Err: err}
+}
+
+// invalidOutput reports an answer that does not match the expected schema
+func (c *AIClassifier) invalidOutput(err error) *AIError {
+	return &AIError{Kind: ErrAIInvalidOutput, Provider: c.provider.Name(), Err: err}
+}
+
+// providerErrorMessage extracts the message from an error body. Gemini,
+// OpenAI and Anthropic all report errors as {"error": {"message": "..."}}.
+func providerErrorMessage(body []byte) string {
+	var envelope struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return ""
+	}
+	return envelope.Error.Message
+}
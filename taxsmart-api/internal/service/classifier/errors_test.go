@@ -0,0 +1,119 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestAIClassifier_FailureModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantKind    error
+		wantMessage string
+	}{
+		{"bad key", http.StatusUnauthorized, `{"error": {"message": "Incorrect API key provided"}}`, ErrAIAuth, "Incorrect API key provided"},
+		{"forbidden", http.StatusForbidden, `{"error": {"message": "permission denied"}}`, ErrAIAuth, "permission denied"},
+		{"quota", http.StatusTooManyRequests, `{"error": {"message": "You exceeded your current quota"}}`, ErrAIQuota, "exceeded your current quota"},
+		{"gateway timeout", http.StatusGatewayTimeout, ``, ErrAITimeout, ""},
+		{"overloaded", http.StatusServiceUnavailable, `{"error": {"message": "overloaded"}}`, ErrAIUnavailable, "overloaded"},
+		{"envelope not JSON", http.StatusOK, `<html>oops</html>`, ErrAIInvalidOutput, ""},
+		{"no choices", http.StatusOK, `{"choices": []}`, ErrAIInvalidOutput, ""},
+		{"answer not JSON", http.StatusOK, completionBody("Sure! The first one is employment income."), ErrAIInvalidOutput, ""},
+		{"answer without results", http.StatusOK, completionBody(`{"category": "expense"}`), ErrAIInvalidOutput, ""},
+		{"unknown category", http.StatusOK, completionBody(`{"results": [{"index": 1, "category": "salary", "confidence": 0.9}]}`), ErrAIInvalidOutput, ""},
+		{"confidence out of range", http.StatusOK, completionBody(`{"results": [{"index": 1, "category": "expense", "confidence": 90}]}`), ErrAIInvalidOutput, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			_, err := newTestAI(t, server.URL, 10).ClassifyBatch(context.Background(), uuid.Nil, testTransactions(1))
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("ClassifyBatch() error = %v, want %v", err, tt.wantKind)
+			}
+
+			var aiErr *AIError
+			if !errors.As(err, &aiErr) {
+				t.Fatalf("ClassifyBatch() error = %T, want *AIError", err)
+			}
+			if tt.status != http.StatusOK && aiErr.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", aiErr.StatusCode, tt.status)
+			}
+			if !strings.Contains(aiErr.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want %q", aiErr.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestAIClassifier_ClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ai := newTestAI(t, server.URL, 10)
+	ai.httpClient.Timeout = 20 * time.Millisecond
+
+	_, err := ai.ClassifyBatch(context.Background(), uuid.Nil, testTransactions(1))
+	if !errors.Is(err, ErrAITimeout) {
+		t.Errorf("ClassifyBatch() error = %v, want %v", err, ErrAITimeout)
+	}
+}
+
+func TestClassifier_FallsBackToRulesOnAIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := NewClassifier(newTestAI(t, server.URL, 10), nil, nil)
+	result := c.Classify(context.Background(), uuid.Nil, model.ParsedTransaction{Description: "POS PURCHASE SHOPRITE", Amount: 5000, Type: "debit"})
+	if result.Method != "rules" || result.Category != model.CategoryExpense {
+		t.Errorf("Classify() = %+v, want the rule result", result)
+	}
+	if stats := c.AIErrors(); stats.Total != 1 || stats.ByKind["auth"] != 1 || stats.LastAt == nil {
+		t.Errorf("AIErrors() = %+v, want the rejected credentials counted", stats)
+	}
+
+	// Requests the caller cancelled are not the provider's failures
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Classify(ctx, uuid.Nil, model.ParsedTransaction{Description: "UNKNOWN PARTY", Amount: 5000, Type: "credit"})
+	if stats := c.AIErrors(); stats.Total != 1 {
+		t.Errorf("AIErrors() = %+v after a cancelled request, want it not counted", stats)
+	}
+}
+
+// completionBody wraps a model answer in an OpenAI chat completion envelope
+func completionBody(answer string) string {
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": map[string]string{"content": answer}},
+		},
+	})
+	return string(body)
+}
@@ -0,0 +1,267 @@
+package classifier
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
+)
+
+// LabelledTransaction is a transaction with its known category
+type LabelledTransaction struct {
+	model.ParsedTransaction
+	Label model.Category `json:"label"`
+}
+
+// ReadLabelledCSV reads labelled transactions from a CSV file with a header
+// row naming the columns description, amount, type and category, and
+// optionally date (YYYY-MM-DD)
+func ReadLabelledCSV(r io.Reader) ([]LabelledTransaction, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"description", "amount", "type", "category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+
+	var samples []LabelledTransaction
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(record[columns["amount"]], ",", ""), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, record[columns["amount"]])
+		}
+		txType := strings.ToLower(strings.TrimSpace(record[columns["type"]]))
+		if txType != "credit" && txType != "debit" {
+			return nil, fmt.Errorf("line %d: type must be credit or debit", line)
+		}
+		label := model.Category(strings.TrimSpace(record[columns["category"]]))
+		if !label.IsValid() {
+			return nil, fmt.Errorf("line %d: unknown category %q", line, label)
+		}
+
+		sample := LabelledTransaction{
+			ParsedTransaction: model.ParsedTransaction{
+				Description: record[columns["description"]],
+				Amount:      amount,
+				Type:        txType,
+			},
+			Label: label,
+		}
+		if i, ok := columns["date"]; ok && record[i] != "" {
+			if sample.Date, err = time.Parse("2006-01-02", record[i]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid date %q", line, record[i])
+			}
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// WriteLabelledCSV writes labelled transactions in the format ReadLabelledCSV reads
+func WriteLabelledCSV(w io.Writer, samples []LabelledTransaction) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"description", "amount", "type", "category", "date"})
+	for _, sample := range samples {
+		var date string
+		if !sample.Date.IsZero() {
+			date = sample.Date.Format("2006-01-02")
+		}
+		writer.Write([]string{
+			sample.Description,
+			strconv.FormatFloat(sample.Amount, 'f', -1, 64),
+			sample.Type,
+			string(sample.Label),
+			date,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// CategoryMetrics measures predictions of one category
+type CategoryMetrics struct {
+	Category  model.Category `json:"category"`
+	Support   int            `json:"support"`   // Transactions labelled with the category
+	Predicted int            `json:"predicted"` // Transactions predicted as the category
+	Correct   int            `json:"correct"`
+	Precision float64        `json:"precision"`
+	Recall    float64        `json:"recall"`
+	F1        float64        `json:"f1"`
+}
+
+// EvaluationReport compares predictions with labels
+type EvaluationReport struct {
+	Total      int                                       `json:"total"`
+	Correct    int                                       `json:"correct"`
+	Accuracy   float64                                   `json:"accuracy"`
+	Coverage   float64                                   `json:"coverage"` // Share of transactions given a category
+	MacroF1    float64                                   `json:"macro_f1"` // Mean F1 over labelled categories
+	Categories []CategoryMetrics                         `json:"categories"`
+	Confusion  map[model.Category]map[model.Category]int `json:"confusion"` // Label to predicted category counts
+}
+
+// Evaluate scores predictions against the samples' labels
+func Evaluate(samples []LabelledTransaction, predictions []model.ClassificationResult) EvaluationReport {
+	report := EvaluationReport{
+		Total:     len(samples),
+		Confusion: make(map[model.Category]map[model.Category]int),
+	}
+	support := make(map[model.Category]int)
+	predicted := make(map[model.Category]int)
+	correct := make(map[model.Category]int)
+	covered := 0
+
+	for i, sample := range samples {
+		got := predictions[i].Category
+		if report.Confusion[sample.Label] == nil {
+			report.Confusion[sample.Label] = make(map[model.Category]int)
+		}
+		report.Confusion[sample.Label][got]++
+		support[sample.Label]++
+		predicted[got]++
+		if got == sample.Label {
+			correct[got]++
+			report.Correct++
+		}
+		if got != model.CategoryUncategorized {
+			covered++
+		}
+	}
+
+	labelled := 0
+	for _, category := range model.Categories {
+		if support[category] == 0 && predicted[category] == 0 {
+			continue
+		}
+		m := CategoryMetrics{
+			Category:  category,
+			Support:   support[category],
+			Predicted: predicted[category],
+			Correct:   correct[category],
+			Precision: ratio(correct[category], predicted[category]),
+			Recall:    ratio(correct[category], support[category]),
+		}
+		if m.Precision+m.Recall > 0 {
+			m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+		}
+		if m.Support > 0 {
+			report.MacroF1 += m.F1
+			labelled++
+		}
+		report.Categories = append(report.Categories, m)
+	}
+	if labelled > 0 {
+		report.MacroF1 /= float64(labelled)
+	}
+	report.Accuracy = ratio(report.Correct, report.Total)
+	report.Coverage = ratio(covered, report.Total)
+	return report
+}
+
+// WriteTable writes the report as an aligned text table
+func (r EvaluationReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "category\tsupport\tpredicted\tprecision\trecall\tf1\t")
+	for _, m := range r.Categories {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.3f\t%.3f\t\n", m.Category, m.Support, m.Predicted, m.Precision, m.Recall, m.F1)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\naccuracy %.3f (%d/%d), coverage %.3f, macro F1 %.3f\n",
+		r.Accuracy, r.Correct, r.Total, r.Coverage, r.MacroF1)
+	return err
+}
+
+// Evaluate classifies the samples as an anonymous user and scores the results
+func (c *Classifier) Evaluate(ctx context.Context, samples []LabelledTransaction) EvaluationReport {
+	return Evaluate(samples, c.ClassifyBatch(ctx, uuid.Nil, unlabelled(samples)))
+}
+
+// FitCalibration fits the calibration of the rule pack, local model and AI
+// votes to labelled transactions. Fit on transactions the local model was
+// not trained on, or its confidence will look better than it is. The AI is consulted, through the cache, only when
+// configured. Learned rules are per user, so they keep their default weight.
+func (c *Classifier) FitCalibration(ctx context.Context, samples []LabelledTransaction) (*Calibration, error) {
+	transactions := unlabelled(samples)
+	var rules, local, ai []calibrationObservation
+	for i, tx := range transactions {
+		if vote := c.rules.ClassifyTransaction(tx); castsVote(vote) {
+			rules = append(rules, calibrationObservation{confidence: vote.Confidence, correct: vote.Category == samples[i].Label})
+		}
+		if c.local == nil {
+			continue
+		}
+		if vote := c.local.Classify(tx); castsVote(vote) {
+			local = append(local, calibrationObservation{confidence: vote.Confidence, correct: vote.Category == samples[i].Label})
+		}
+	}
+
+	if c.ai != nil && c.ai.IsAvailable() {
+		votes, err := c.aiVotes(ctx, uuid.Nil, transactions, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect AI votes: %w", err)
+		}
+		for i, vote := range votes {
+			if castsVote(vote) {
+				ai = append(ai, calibrationObservation{confidence: vote.Confidence, correct: vote.Category == samples[i].Label})
+			}
+		}
+	}
+
+	calibration := &Calibration{
+		FittedAt: time.Now().UTC(),
+		Samples:  len(samples),
+		Sources:  map[string]SourceCalibration{SourceRules: fitSource(rules)},
+	}
+	if len(local) > 0 {
+		calibration.Sources[SourceLocal] = fitSource(local)
+	}
+	if len(ai) > 0 {
+		calibration.Sources[SourceAI] = fitSource(ai)
+	}
+	return calibration, nil
+}
+
+// unlabelled returns the samples' transactions with their narrations parsed
+func unlabelled(samples []LabelledTransaction) []model.ParsedTransaction {
+	transactions := make([]model.ParsedTransaction, len(samples))
+	for i, sample := range samples {
+		transactions[i] = parser.EnrichNarration(sample.ParsedTransaction)
+	}
+	return transactions
+}
+
+// ratio returns n/d, or 0 when d is 0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
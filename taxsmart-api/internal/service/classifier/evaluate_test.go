@@ -0,0 +1,114 @@
+package classifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+const labelledCSV = `Description,Amount,Type,Category,Date
+SALARY JAN ACME LTD,"450,000",credit,employment_income,2026-01-28
+UPWORK ESCROW PAYOUT,250000,credit,freelance_income,
+NETFLIX SUBSCRIPTION,4400,debit,expense,2026-01-05
+GIFT FROM MUM,50000,credit,other_income,
+`
+
+func TestReadLabelledCSV(t *testing.T) {
+	samples, err := ReadLabelledCSV(strings.NewReader(labelledCSV))
+	if err != nil {
+		t.Fatalf("ReadLabelledCSV() error = %v", err)
+	}
+	if len(samples) != 4 {
+		t.Fatalf("ReadLabelledCSV() = %d samples, want 4", len(samples))
+	}
+	if first := samples[0]; first.Amount != 450000 || first.Label != model.CategoryEmployment || first.Date.Day() != 28 {
+		t.Errorf("first sample = %+v", first)
+	}
+
+	invalid := []struct {
+		name string
+		csv  string
+	}{
+		{"missing column", "description,amount,type\nX,1,credit\n"},
+		{"bad amount", "description,amount,type,category\nX,abc,credit,expense\n"},
+		{"bad type", "description,amount,type,category\nX,1,sideways,expense\n"},
+		{"bad category", "description,amount,type,category\nX,1,credit,lottery\n"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadLabelledCSV(strings.NewReader(tt.csv)); err == nil {
+				t.Error("ReadLabelledCSV() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	samples := []LabelledTransaction{
+		{Label: model.CategoryEmployment},
+		{Label: model.CategoryEmployment},
+		{Label: model.CategoryFreelance},
+		{Label: model.CategoryExpense},
+	}
+	predictions := []model.ClassificationResult{
+		{Category: model.CategoryEmployment},
+		{Category: model.CategoryFreelance},
+		{Category: model.CategoryFreelance},
+		{Category: model.CategoryUncategorized},
+	}
+
+	report := Evaluate(samples, predictions)
+	if report.Correct != 2 || report.Accuracy != 0.5 || report.Coverage != 0.75 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Confusion[model.CategoryEmployment][model.CategoryFreelance] != 1 {
+		t.Errorf("Confusion = %v", report.Confusion)
+	}
+
+	metrics := make(map[model.Category]CategoryMetrics)
+	for _, m := range report.Categories {
+		metrics[m.Category] = m
+	}
+	if m := metrics[model.CategoryEmployment]; m.Precision != 1 || m.Recall != 0.5 || !approxEqual(m.F1, 2.0/3) {
+		t.Errorf("employment metrics = %+v", m)
+	}
+	if m := metrics[model.CategoryFreelance]; m.Precision != 0.5 || m.Recall != 1 {
+		t.Errorf("freelance metrics = %+v", m)
+	}
+	if m := metrics[model.CategoryExpense]; m.Recall != 0 || m.F1 != 0 {
+		t.Errorf("expense metrics = %+v", m)
+	}
+	// Mean of employment 2/3, freelance 2/3 and expense 0
+	if !approxEqual(report.MacroF1, 4.0/9) {
+		t.Errorf("MacroF1 = %.4f, want %.4f", report.MacroF1, 4.0/9)
+	}
+
+	var table strings.Builder
+	if err := report.WriteTable(&table); err != nil || !strings.Contains(table.String(), "employment_income") {
+		t.Errorf("WriteTable() = %q, %v", table.String(), err)
+	}
+}
+
+func TestClassifier_FitCalibration(t *testing.T) {
+	samples, err := ReadLabelledCSV(strings.NewReader(labelledCSV))
+	if err != nil {
+		t.Fatalf("ReadLabelledCSV() error = %v", err)
+	}
+
+	c := NewClassifier(nil, nil, nil)
+	calibration, err := c.FitCalibration(context.Background(), samples)
+	if err != nil {
+		t.Fatalf("FitCalibration() error = %v", err)
+	}
+	if calibration.Samples != len(samples) {
+		t.Errorf("Samples = %d, want %d", calibration.Samples, len(samples))
+	}
+	if _, ok := calibration.Sources[SourceRules]; !ok {
+		t.Error("expected a rules calibration")
+	}
+	if _, ok := calibration.Sources[SourceAI]; ok {
+		t.Error("expected no AI calibration without an AI provider")
+	}
+}
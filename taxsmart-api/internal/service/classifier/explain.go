@@ -0,0 +1,104 @@
+package classifier
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// MaxAlternatives is the number of alternative categories offered with a result
+const MaxAlternatives = 3
+
+// ruleExplanation describes where a rule matched a narration
+func ruleExplanation(rule *compiledRule, span matchSpan, n narration) *model.Explanation {
+	name := rule.pack + "/" + rule.ID
+	if span.field == "channel" {
+		return &model.Explanation{
+			Summary: fmt.Sprintf("Paid through %s, which rule %s classifies as %s", n.channel, name, rule.Category),
+			Field:   "channel",
+			Matched: n.channel,
+		}
+	}
+
+	text := n.description
+	if span.field == FieldCounterparty {
+		text = n.counterparty
+	}
+	// Token and substring matches are found in the upper-cased text, which
+	// only lines up with the original when upper-casing kept its length
+	source := text.raw
+	if rule.Match != MatchRegex && len(text.upper) != len(text.raw) {
+		source = text.upper
+	}
+	matched := source[span.start:span.end]
+
+	return &model.Explanation{
+		Summary: fmt.Sprintf("%q in the %s matched rule %s for %s", matched, span.field, name, rule.Category),
+		Pattern: rule.Pattern,
+		Field:   span.field,
+		Matched: matched,
+		Span:    &model.Span{Start: span.start, End: span.end},
+	}
+}
+
+// userRuleExplanation describes a learned rule match
+func userRuleExplanation(rule *model.UserRule) *model.Explanation {
+	return &model.Explanation{
+		Summary: fmt.Sprintf("You classified transactions like %q as %s before", rule.Key, rule.Category),
+		Pattern: rule.Key,
+		Field:   FieldDescription,
+	}
+}
+
+// aiExplanation wraps the AI's rationale
+func aiExplanation(provider, rationale string) *model.Explanation {
+	return &model.Explanation{
+		Summary:   "Classified by " + provider,
+		Rationale: rationale,
+	}
+}
+
+// rankAlternatives keeps the best score for each category other than the
+// chosen one and uncategorized, best first, up to MaxAlternatives
+func rankAlternatives(chosen model.Category, candidates []model.Alternative) []model.Alternative {
+	best := make(map[model.Category]float64)
+	for _, candidate := range candidates {
+		if candidate.Category == chosen || candidate.Category == model.CategoryUncategorized || !candidate.Category.IsValid() {
+			continue
+		}
+		if score, ok := best[candidate.Category]; !ok || candidate.Score > score {
+			best[candidate.Category] = candidate.Score
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+
+	ranked := make([]model.Alternative, 0, len(best))
+	for category, score := range best {
+		ranked = append(ranked, model.Alternative{Category: category, Score: score})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Category < ranked[j].Category
+	})
+	if len(ranked) > MaxAlternatives {
+		ranked = ranked[:MaxAlternatives]
+	}
+	return ranked
+}
+
+// withAlternatives returns the result with alternatives drawn from its own
+// and the other results' categories and alternatives
+func withAlternatives(result model.ClassificationResult, others ...model.ClassificationResult) model.ClassificationResult {
+	candidates := append([]model.Alternative(nil), result.Alternatives...)
+	for _, other := range others {
+		candidates = append(candidates, model.Alternative{Category: other.Category, Score: other.Confidence})
+		candidates = append(candidates, other.Alternatives...)
+	}
+	result.Alternatives = rankAlternatives(result.Category, candidates)
+	return result
+}
@@ -0,0 +1,159 @@
+package classifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestRuleEngine_Explains(t *testing.T) {
+	engine := &RuleEngine{packs: make(map[string]RulePack)}
+	err := engine.AddPack(RulePack{
+		Name:    "explain",
+		Version: "1",
+		Rules: []Rule{
+			{ID: "salary", Pattern: "SALARY", Category: model.CategoryEmployment, TxType: "credit", Priority: 100, Confidence: 0.9},
+			{ID: "upwork", Pattern: "UPWORK", Match: MatchContains, Category: model.CategoryFreelance, Priority: 90, Confidence: 0.85},
+			{ID: "ref", Pattern: `INV-\d+`, Match: MatchRegex, Category: model.CategoryFreelance, Priority: 80, Confidence: 0.7},
+			{ID: "landlord", Pattern: "ESTATES", Field: FieldCounterparty, Category: model.CategoryRentExpense, Priority: 70, Confidence: 0.75},
+			{ID: "pos", Channel: model.ChannelPOS, Category: model.CategoryExpense, Priority: 10, Confidence: 0.6},
+			{ID: "credit", Pattern: "TRF", Category: model.CategoryTransfer, Priority: 5, Confidence: 0.5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddPack() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		tx      model.ParsedTransaction
+		want    model.Explanation
+		wantAlt []model.Category
+	}{
+		{
+			name:    "token match with alternatives",
+			tx:      model.ParsedTransaction{Description: "TRF Salary Jan UPWORK", Type: "credit"},
+			want:    model.Explanation{Pattern: "SALARY", Field: FieldDescription, Matched: "Salary", Span: &model.Span{Start: 4, End: 10}},
+			wantAlt: []model.Category{model.CategoryFreelance, model.CategoryTransfer},
+		},
+		{
+			name: "substring match",
+			tx:   model.ParsedTransaction{Description: "payment upworkescrow", Type: "credit"},
+			want: model.Explanation{Pattern: "UPWORK", Field: FieldDescription, Matched: "upwork", Span: &model.Span{Start: 8, End: 14}},
+		},
+		{
+			name: "regex match",
+			tx:   model.ParsedTransaction{Description: "PAYMENT FOR INV-2041", Type: "credit"},
+			want: model.Explanation{Pattern: `INV-\d+`, Field: FieldDescription, Matched: "INV-2041", Span: &model.Span{Start: 12, End: 20}},
+		},
+		{
+			name: "counterparty match",
+			tx:   model.ParsedTransaction{Description: "RENT", Type: "debit", Narration: model.Narration{Counterparty: "Lekki Estates"}},
+			want: model.Explanation{Pattern: "ESTATES", Field: FieldCounterparty, Matched: "Estates", Span: &model.Span{Start: 6, End: 13}},
+		},
+		{
+			name: "channel match",
+			tx:   model.ParsedTransaction{Description: "SHOPRITE", Type: "debit", Narration: model.Narration{Channel: model.ChannelPOS}},
+			want: model.Explanation{Field: "channel", Matched: model.ChannelPOS},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := engine.ClassifyTransaction(tt.tx)
+			got := result.Explanation
+			if got == nil || got.Summary == "" {
+				t.Fatalf("Explanation = %+v, want a summary", got)
+			}
+			tt.want.Summary = got.Summary
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Explanation = %+v, want %+v", *got, tt.want)
+			}
+
+			var alternatives []model.Category
+			for _, alternative := range result.Alternatives {
+				alternatives = append(alternatives, alternative.Category)
+			}
+			if !reflect.DeepEqual(alternatives, tt.wantAlt) {
+				t.Errorf("Alternatives = %v, want %v", alternatives, tt.wantAlt)
+			}
+		})
+	}
+}
+
+func TestRankAlternatives(t *testing.T) {
+	got := rankAlternatives(model.CategoryEmployment, []model.Alternative{
+		{Category: model.CategoryFreelance, Score: 0.4},
+		{Category: model.CategoryEmployment, Score: 0.9}, // The chosen category
+		{Category: model.CategoryFreelance, Score: 0.6},  // Best score per category wins
+		{Category: model.CategoryUncategorized, Score: 0.8},
+		{Category: "bogus", Score: 0.8},
+		{Category: model.CategoryTransfer, Score: 0.3},
+		{Category: model.CategoryOtherIncome, Score: 0.2},
+		{Category: model.CategoryInterest, Score: 0.1},
+	})
+	want := []model.Alternative{
+		{Category: model.CategoryFreelance, Score: 0.6},
+		{Category: model.CategoryTransfer, Score: 0.3},
+		{Category: model.CategoryOtherIncome, Score: 0.2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rankAlternatives() = %v, want %v", got, want)
+	}
+}
+
+func TestAIClassifier_Rationale(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, map[string]interface{}{"results": []map[string]interface{}{{
+			"index":      1,
+			"category":   "rental_income",
+			"confidence": 0.9,
+			"rationale":  "[NAME_1] pays the same amount monthly from [ACCOUNT_1]",
+			"alternatives": []map[string]interface{}{
+				{"category": "freelance_income", "confidence": 0.2},
+				{"category": "rental_income", "confidence": 0.9},
+				{"category": "not_a_category", "confidence": 0.5},
+			},
+		}}})
+	}))
+	defer server.Close()
+
+	tx := model.ParsedTransaction{
+		Description: "TRF FROM KOLA ADEYEMI 0123456789",
+		Amount:      300000,
+		Type:        "credit",
+		Narration:   model.Narration{Counterparty: "KOLA ADEYEMI"},
+	}
+	results, err := newTestAI(t, server.URL, 10).ClassifyBatch(context.Background(), uuid.Nil, []model.ParsedTransaction{tx})
+	if err != nil {
+		t.Fatalf("ClassifyBatch() error = %v", err)
+	}
+
+	// Names come back, but account numbers stay masked as results are shared through the cache
+	if got, want := results[0].Explanation.Rationale, "KOLA ADEYEMI pays the same amount monthly from [ACCOUNT_1]"; got != want {
+		t.Errorf("Rationale = %q, want %q", got, want)
+	}
+	want := []model.Alternative{{Category: model.CategoryFreelance, Score: 0.2}}
+	if !reflect.DeepEqual(results[0].Alternatives, want) {
+		t.Errorf("Alternatives = %v, want %v", results[0].Alternatives, want)
+	}
+}
+
+func TestClassifier_UserRuleOffersRuleAlternative(t *testing.T) {
+	userID := uuid.New()
+	store := stubRuleStore{{UserID: userID, Key: "ACME LTD", Category: model.CategoryFreelance}}
+	c := NewClassifier(nil, store, nil)
+
+	result := c.Classify(context.Background(), userID, model.ParsedTransaction{Description: "SALARY ACME LTD", Type: "credit", Amount: 400000})
+	if result.Method != "user_rule" || result.Explanation == nil || result.Explanation.Pattern != "ACME LTD" {
+		t.Fatalf("result = %+v, want a learned rule match explained by its key", result)
+	}
+	if len(result.Alternatives) == 0 || result.Alternatives[0].Category != model.CategoryEmployment {
+		t.Errorf("Alternatives = %v, want the rule engine's %s first", result.Alternatives, model.CategoryEmployment)
+	}
+}
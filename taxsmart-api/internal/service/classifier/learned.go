@@ -0,0 +1,117 @@
+package classifier
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
+)
+
+// UserRuleConfidence is the confidence reported for a learned rule match
+const UserRuleConfidence = 0.95
+
+// UserRuleStore provides the rules learned from a user's corrections
+type UserRuleStore interface {
+	ListUserRules(ctx context.Context, userID uuid.UUID) ([]model.UserRule, error)
+}
+
+// NormaliseNarration reduces a description to the tokens that identify the
+// counterparty: upper-cased words without references, dates, amounts or
+// transfer boilerplate, in their original order and without repeats.
+func NormaliseNarration(description string) []string {
+	fields := tokenize(strings.ToUpper(description))
+
+	seen := make(map[string]bool, len(fields))
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len(field) < 2 || parser.IsTransferNoise(field) || seen[field] || strings.ContainsFunc(field, unicode.IsDigit) {
+			continue
+		}
+		seen[field] = true
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// LearnRule builds the rule taught by a manual category correction, keyed on
+// the counterparty when the narration names one. It returns false when the
+// description has nothing identifying to learn from.
+func LearnRule(tx model.Transaction, now time.Time) (model.UserRule, bool) {
+	tokens := NormaliseNarration(tx.Counterparty)
+	if len(tokens) == 0 {
+		tokens = NormaliseNarration(tx.Description)
+	}
+	if len(tokens) == 0 {
+		return model.UserRule{}, false
+	}
+	return model.UserRule{
+		ID:              uuid.New(),
+		UserID:          tx.UserID,
+		Key:             strings.Join(tokens, " "),
+		TransactionType: tx.TransactionType,
+		Category:        tx.Category,
+		Hits:            1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}, true
+}
+
+// MatchUserRule returns the most specific rule whose key tokens all appear in
+// the description. Ties go to the rule confirmed most often, then the newest.
+func MatchUserRule(rules []model.UserRule, description string, txType string) *model.UserRule {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	tokens := make(map[string]bool)
+	for _, token := range NormaliseNarration(description) {
+		tokens[token] = true
+	}
+
+	var best *model.UserRule
+	bestSize := 0
+	for i := range rules {
+		rule := &rules[i]
+		if rule.TransactionType != "" && rule.TransactionType != txType {
+			continue
+		}
+
+		keyTokens := strings.Fields(rule.Key)
+		if len(keyTokens) == 0 {
+			continue
+		}
+		matched := true
+		for _, token := range keyTokens {
+			if !tokens[token] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		if best == nil || len(keyTokens) > bestSize ||
+			(len(keyTokens) == bestSize && (rule.Hits > best.Hits ||
+				(rule.Hits == best.Hits && rule.UpdatedAt.After(best.UpdatedAt)))) {
+			best = rule
+			bestSize = len(keyTokens)
+		}
+	}
+	return best
+}
+
+// userRuleResult reports a learned rule match
+func userRuleResult(rule *model.UserRule) model.ClassificationResult {
+	return model.ClassificationResult{
+		Category:    rule.Category,
+		Confidence:  UserRuleConfidence,
+		Method:      "user_rule",
+		Rule:        rule.Key,
+		Explanation: userRuleExplanation(rule),
+	}
+}
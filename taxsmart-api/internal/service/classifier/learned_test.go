@@ -0,0 +1,137 @@
+package classifier
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+type stubRuleStore []model.UserRule
+
+func (s stubRuleStore) ListUserRules(ctx context.Context, userID uuid.UUID) ([]model.UserRule, error) {
+	var rules []model.UserRule
+	for _, rule := range s {
+		if rule.UserID == userID {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (s stubRuleStore) GetUser(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return &model.User{ID: id}, nil
+}
+
+func TestNormaliseNarration(t *testing.T) {
+	tests := []struct {
+		description string
+		expected    []string
+	}{
+		{"TRF FROM ACME LTD", []string{"ACME", "LTD"}},
+		{"NIP/ACME LTD/REF 0012345678/JUN 2026", []string{"ACME", "LTD"}},
+		{"Salary for January - Acme Ltd", []string{"SALARY", "ACME", "LTD"}},
+		{"POS/SHOPRITE LEKKI/SHOPRITE", []string{"SHOPRITE", "LEKKI"}},
+		{"TRF 000123 / 12-06-2026", []string{}},
+		{"MOBILE TRFR UTO JANE ROE BY ACME", []string{"JANE", "ROE", "ACME"}}, // Boilerplate shared with counterparty parsing
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := NormaliseNarration(tt.description)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("NormaliseNarration(%q) = %v, expected %v", tt.description, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLearnRule(t *testing.T) {
+	userID := uuid.New()
+	rule, ok := LearnRule(model.Transaction{
+		UserID:          userID,
+		Description:     "TRF FROM ACME LTD",
+		TransactionType: "credit",
+		Category:        model.CategoryEmployment,
+	}, time.Now())
+	if !ok {
+		t.Fatal("Expected a rule to be learned")
+	}
+	if rule.Key != "ACME LTD" || rule.UserID != userID || rule.TransactionType != "credit" || rule.Category != model.CategoryEmployment {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+
+	if _, ok := LearnRule(model.Transaction{Description: "NIP TRF 0012345678"}, time.Now()); ok {
+		t.Error("Expected no rule for a description without identifying tokens")
+	}
+}
+
+func TestMatchUserRule(t *testing.T) {
+	now := time.Now()
+	rules := []model.UserRule{
+		{Key: "ACME LTD", TransactionType: "credit", Category: model.CategoryEmployment, Hits: 1, UpdatedAt: now},
+		{Key: "ACME LTD DIVIDEND", TransactionType: "credit", Category: model.CategoryInvestment, Hits: 1, UpdatedAt: now},
+		{Key: "ACME", TransactionType: "credit", Category: model.CategoryFreelance, Hits: 5, UpdatedAt: now},
+		{Key: "LANDLORD OKAFOR", TransactionType: "debit", Category: model.CategoryRentExpense, Hits: 1, UpdatedAt: now},
+	}
+
+	tests := []struct {
+		name        string
+		description string
+		txType      string
+		expected    string
+	}{
+		{"Same counterparty next month", "NIP/ACME LTD/REF 99887766/JUL", "credit", "ACME LTD"},
+		{"Most specific rule wins", "ACME LTD DIVIDEND PAYMENT", "credit", "ACME LTD DIVIDEND"},
+		{"Partial key falls back to shorter rule", "ACME GLOBAL", "credit", "ACME"},
+		{"Transaction type must match", "TRF TO LANDLORD OKAFOR", "credit", ""},
+		{"Debit rule", "TRF TO LANDLORD OKAFOR", "debit", "LANDLORD OKAFOR"},
+		{"No match", "POS/SHOPRITE", "debit", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := MatchUserRule(rules, tt.description, tt.txType)
+			got := ""
+			if rule != nil {
+				got = rule.Key
+			}
+			if got != tt.expected {
+				t.Errorf("MatchUserRule(%q) = %q, expected %q", tt.description, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClassifier_UserRulesTakePrecedence(t *testing.T) {
+	userID := uuid.New()
+	store := stubRuleStore{
+		{UserID: userID, Key: "ACME LTD", TransactionType: "credit", Category: model.CategoryEmployment, Hits: 1},
+	}
+	c := NewClassifier(nil, store, nil)
+	ctx := context.Background()
+
+	tx := model.ParsedTransaction{Description: "TRF FROM ACME LTD", Type: "credit", Amount: 500_000}
+	result := c.Classify(ctx, userID, tx)
+	if result.Method != "user_rule" || result.Category != model.CategoryEmployment || result.Rule != "ACME LTD" {
+		t.Errorf("Expected the learned rule to match, got %+v", result)
+	}
+
+	// Other users and anonymous requests use the global rules
+	for _, id := range []uuid.UUID{uuid.New(), uuid.Nil} {
+		if result := c.Classify(ctx, id, tx); result.Method != "rules" {
+			t.Errorf("Expected global rules for user %s, got %+v", id, result)
+		}
+	}
+
+	results := c.ClassifyBatch(ctx, userID, []model.ParsedTransaction{
+		{Description: "NIP/ACME LTD/SALARY JUL", Type: "credit", Amount: 500_000},
+		{Description: "SALARY FOR JULY", Type: "credit", Amount: 300_000},
+	})
+	if results[0].Method != "user_rule" || results[1].Method != "rules" {
+		t.Errorf("Unexpected batch results %+v", results)
+	}
+}
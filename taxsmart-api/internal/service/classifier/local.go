@@ -0,0 +1,235 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
+)
+
+// SourceLocal names votes from the local statistical model
+const SourceLocal = "local"
+
+// localMinAlternative is the posterior below which a category is not offered as an alternative
+const localMinAlternative = 0.05
+
+// localEvidenceWords is the number of words named in a local model explanation
+const localEvidenceWords = 3
+
+// LocalModel is a multinomial naive Bayes classifier over narration words,
+// transaction type, amount size and payment channel. It runs in process, so
+// it can classify transactions that rules miss without an external AI.
+type LocalModel struct {
+	TrainedAt time.Time                      `json:"trained_at"`
+	Samples   int                            `json:"samples"`
+	Classes   map[model.Category]*LocalClass `json:"classes"`
+
+	vocabulary map[string]bool // Every feature seen in training
+}
+
+// LocalClass holds the training counts of one category
+type LocalClass struct {
+	Documents int            `json:"documents"` // Training transactions with the category
+	Features  int            `json:"features"`  // Feature occurrences across them
+	Counts    map[string]int `json:"counts"`    // Occurrences of each feature
+}
+
+// TrainLocalModel fits a local model to labelled transactions. At least
+// two categories are needed for the model to choose between.
+func TrainLocalModel(samples []LabelledTransaction) (*LocalModel, error) {
+	m := &LocalModel{
+		TrainedAt: time.Now().UTC(),
+		Samples:   len(samples),
+		Classes:   make(map[model.Category]*LocalClass),
+	}
+	for _, sample := range samples {
+		class, ok := m.Classes[sample.Label]
+		if !ok {
+			class = &LocalClass{Counts: make(map[string]int)}
+			m.Classes[sample.Label] = class
+		}
+		class.Documents++
+		for _, feature := range localFeatures(parser.EnrichNarration(sample.ParsedTransaction)) {
+			class.Counts[feature]++
+			class.Features++
+		}
+	}
+	if len(m.Classes) < 2 {
+		return nil, fmt.Errorf("training needs at least two categories, got %d", len(m.Classes))
+	}
+	m.index()
+	return m, nil
+}
+
+// LoadLocalModel reads a model written by SaveLocalModel
+func LoadLocalModel(path string) (*LocalModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m LocalModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid local model: %w", err)
+	}
+	if len(m.Classes) < 2 {
+		return nil, fmt.Errorf("invalid local model: needs at least two categories")
+	}
+	for category, class := range m.Classes {
+		if !category.IsValid() || class == nil || class.Documents <= 0 {
+			return nil, fmt.Errorf("invalid local model: bad class %q", category)
+		}
+	}
+	m.index()
+	return &m, nil
+}
+
+// SaveLocalModel writes a model as JSON
+func SaveLocalModel(path string, m *LocalModel) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// index builds the vocabulary from the class counts
+func (m *LocalModel) index() {
+	m.vocabulary = make(map[string]bool)
+	for _, class := range m.Classes {
+		for feature := range class.Counts {
+			m.vocabulary[feature] = true
+		}
+	}
+}
+
+// Classify returns the most probable category with its posterior probability
+// as confidence, explained by the words that favour it most. Transactions
+// with no word seen in training are left uncategorized, as type and amount
+// alone say little.
+func (m *LocalModel) Classify(tx model.ParsedTransaction) model.ClassificationResult {
+	tx = parser.EnrichNarration(tx)
+	features := localFeatures(tx)
+
+	var words []string
+	for _, feature := range features {
+		if m.vocabulary[feature] && !strings.Contains(feature, "=") {
+			words = append(words, feature)
+		}
+	}
+	if len(words) == 0 {
+		return model.ClassificationResult{
+			Category:    model.CategoryUncategorized,
+			Method:      SourceLocal,
+			Explanation: &model.Explanation{Summary: "No words seen in training"},
+		}
+	}
+
+	categories := make([]model.Category, 0, len(m.Classes))
+	for category := range m.Classes {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+
+	scores := make([]float64, len(categories))
+	best := 0
+	for i, category := range categories {
+		class := m.Classes[category]
+		scores[i] = math.Log(float64(class.Documents) / float64(m.Samples))
+		for _, feature := range features {
+			scores[i] += m.logLikelihood(class, feature)
+		}
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	// Normalise the log scores into posteriors
+	var total float64
+	posteriors := make([]float64, len(scores))
+	for i, score := range scores {
+		posteriors[i] = math.Exp(score - scores[best])
+		total += posteriors[i]
+	}
+	var alternatives []model.Alternative
+	for i := range posteriors {
+		posteriors[i] /= total
+		if i != best && posteriors[i] >= localMinAlternative {
+			alternatives = append(alternatives, model.Alternative{Category: categories[i], Score: posteriors[i]})
+		}
+	}
+
+	category := categories[best]
+	return model.ClassificationResult{
+		Category:     category,
+		Confidence:   posteriors[best],
+		Method:       SourceLocal,
+		Explanation:  m.explain(category, words),
+		Alternatives: rankAlternatives(category, alternatives),
+	}
+}
+
+// logLikelihood returns the Laplace-smoothed log probability of a feature in a class
+func (m *LocalModel) logLikelihood(class *LocalClass, feature string) float64 {
+	return math.Log(float64(class.Counts[feature]+1) / float64(class.Features+len(m.vocabulary)))
+}
+
+// explain names the words whose likelihood most favours the category over the others
+func (m *LocalModel) explain(category model.Category, words []string) *model.Explanation {
+	type evidence struct {
+		word   string
+		margin float64
+	}
+	winner := m.Classes[category]
+	var favouring []evidence
+	for _, word := range words {
+		margin := math.Inf(1)
+		for other, class := range m.Classes {
+			if other != category {
+				margin = math.Min(margin, m.logLikelihood(winner, word)-m.logLikelihood(class, word))
+			}
+		}
+		if margin > 0 {
+			favouring = append(favouring, evidence{word, margin})
+		}
+	}
+	sort.SliceStable(favouring, func(i, j int) bool { return favouring[i].margin > favouring[j].margin })
+	if len(favouring) > localEvidenceWords {
+		favouring = favouring[:localEvidenceWords]
+	}
+
+	explanation := &model.Explanation{
+		Summary: fmt.Sprintf("Most like the %d %s transactions the local model learned from", winner.Documents, category),
+		Field:   FieldDescription,
+	}
+	if len(favouring) > 0 {
+		quoted := make([]string, len(favouring))
+		for i, e := range favouring {
+			quoted[i] = strconv.Quote(e.word)
+		}
+		explanation.Matched = strings.Join(quoted, ", ")
+		explanation.Summary = fmt.Sprintf("Words %s are typical of the %d %s transactions the local model learned from",
+			explanation.Matched, winner.Documents, category)
+	}
+	return explanation
+}
+
+// localFeatures returns a transaction's features: its identifying narration
+// words, then its type, amount size and channel as name=value features
+func localFeatures(tx model.ParsedTransaction) []string {
+	features := NormaliseNarration(tx.Description)
+	features = append(features,
+		"type="+strings.ToLower(tx.Type),
+		"amount="+strconv.Itoa(amountBucket(tx.Amount)),
+	)
+	if tx.Channel != "" {
+		features = append(features, "channel="+tx.Channel)
+	}
+	return features
+}
@@ -0,0 +1,111 @@
+package classifier
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// localSamples labels narrations the built-in rules do not recognise
+func localSamples() []LabelledTransaction {
+	labelled := func(description string, amount float64, txType string, label model.Category) LabelledTransaction {
+		return LabelledTransaction{
+			ParsedTransaction: model.ParsedTransaction{Description: description, Amount: amount, Type: txType},
+			Label:             label,
+		}
+	}
+	return []LabelledTransaction{
+		labelled("KLOVAPAY CREATOR PAYOUT", 180000, "credit", model.CategoryFreelance),
+		labelled("KLOVAPAY PAYOUT WEEK 12", 95000, "credit", model.CategoryFreelance),
+		labelled("KLOVAPAY CREATOR PAYOUT MAR", 210000, "credit", model.CategoryFreelance),
+		labelled("DRIMZ KITCHEN LEKKI", 12500, "debit", model.CategoryExpense),
+		labelled("DRIMZ KITCHEN IKEJA", 8000, "debit", model.CategoryExpense),
+		labelled("DRIMZ KITCHEN ORDER", 15000, "debit", model.CategoryExpense),
+	}
+}
+
+func TestLocalModelClassify(t *testing.T) {
+	m, err := TrainLocalModel(localSamples())
+	if err != nil {
+		t.Fatalf("TrainLocalModel() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tx       model.ParsedTransaction
+		expected model.Category
+	}{
+		{"payout", model.ParsedTransaction{Description: "KLOVAPAY PAYOUT APR", Amount: 120000, Type: "credit"}, model.CategoryFreelance},
+		{"kitchen", model.ParsedTransaction{Description: "DRIMZ KITCHEN VI", Amount: 9000, Type: "debit"}, model.CategoryExpense},
+		{"unseen words", model.ParsedTransaction{Description: "ZQXV", Amount: 9000, Type: "debit"}, model.CategoryUncategorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := m.Classify(tt.tx)
+			if result.Category != tt.expected || result.Method != SourceLocal {
+				t.Fatalf("Classify() = %s by %s, want %s by local", result.Category, result.Method, tt.expected)
+			}
+			if result.Explanation == nil {
+				t.Fatal("Classify() gave no explanation")
+			}
+			if tt.expected != model.CategoryUncategorized && result.Confidence < 0.5 {
+				t.Errorf("Classify() confidence = %.2f, want at least 0.5", result.Confidence)
+			}
+		})
+	}
+
+	explanation := m.Classify(model.ParsedTransaction{Description: "KLOVAPAY PAYOUT APR", Amount: 120000, Type: "credit"}).Explanation
+	if !strings.Contains(explanation.Matched, `"KLOVAPAY"`) || strings.Contains(explanation.Matched, "APR") {
+		t.Errorf("Explanation matched %s, want KLOVAPAY and no unseen words", explanation.Matched)
+	}
+}
+
+func TestTrainLocalModelNeedsTwoCategories(t *testing.T) {
+	samples := localSamples()[:3]
+	if _, err := TrainLocalModel(samples); err == nil {
+		t.Error("TrainLocalModel() error = nil, want an error for a single category")
+	}
+}
+
+func TestLocalModelSaveLoad(t *testing.T) {
+	m, err := TrainLocalModel(localSamples())
+	if err != nil {
+		t.Fatalf("TrainLocalModel() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "local-model.json")
+	if err := SaveLocalModel(path, m); err != nil {
+		t.Fatalf("SaveLocalModel() error = %v", err)
+	}
+	loaded, err := LoadLocalModel(path)
+	if err != nil {
+		t.Fatalf("LoadLocalModel() error = %v", err)
+	}
+
+	tx := model.ParsedTransaction{Description: "DRIMZ KITCHEN VI", Amount: 9000, Type: "debit"}
+	if got, want := loaded.Classify(tx), m.Classify(tx); got.Category != want.Category || !approxEqual(got.Confidence, want.Confidence) {
+		t.Errorf("loaded model gave %s %.3f, want %s %.3f", got.Category, got.Confidence, want.Category, want.Confidence)
+	}
+}
+
+func TestClassifierUsesLocalModel(t *testing.T) {
+	m, err := TrainLocalModel(localSamples())
+	if err != nil {
+		t.Fatalf("TrainLocalModel() error = %v", err)
+	}
+	tx := model.ParsedTransaction{Description: "KLOVAPAY CREATOR PAYOUT", Amount: 150000, Type: "credit"}
+
+	c := NewClassifier(nil, nil, nil)
+	if result := c.ClassifyBatch(context.Background(), uuid.New(), []model.ParsedTransaction{tx})[0]; result.Category == model.CategoryFreelance {
+		t.Fatalf("rules alone classified %s as %s, want them to miss", tx.Description, result.Category)
+	}
+
+	c.SetLocalModel(m)
+	result := c.ClassifyBatch(context.Background(), uuid.New(), []model.ParsedTransaction{tx})[0]
+	if result.Category != model.CategoryFreelance || result.Method != SourceLocal {
+		t.Errorf("ClassifyBatch() = %s by %s, want freelance_income by local", result.Category, result.Method)
+	}
+}
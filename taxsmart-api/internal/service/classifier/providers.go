@@ -0,0 +1,342 @@
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Supported AI providers
+const (
+	ProviderGemini           = "gemini"
+	ProviderOpenAI           = "openai"
+	ProviderAnthropic        = "anthropic"
+	ProviderOpenAICompatible = "openai-compatible" // Ollama, llama.cpp, vLLM and other OpenAI-style servers
+)
+
+// Default models and endpoints per provider
+const (
+	defaultGeminiModel      = "gemini-1.5-flash"
+	defaultOpenAIModel      = "gpt-4o-mini"
+	defaultAnthropicModel   = "claude-3-5-haiku-latest"
+	defaultGeminiBaseURL    = "https://generativelanguage.googleapis.com"
+	defaultOpenAIBaseURL    = "https://api.openai.com/v1"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+	anthropicAnswerTool     = "record_answer"
+)
+
+// completionTemperature keeps answers close to deterministic
+const completionTemperature = 0.1
+
+// CompletionRequest asks a model for a JSON answer
+type CompletionRequest struct {
+	Prompt    string
+	MaxTokens int
+	Schema    map[string]interface{} // JSON schema the answer must follow
+}
+
+// LLMProvider adapts a language model API for the AI classifier. Providers
+// only build requests and decode responses; rate limiting and retries are
+// shared by the classifier.
+type LLMProvider interface {
+	// Name identifies the provider and model, e.g. "openai/gpt-4o-mini"
+	Name() string
+	// NewRequest builds the HTTP request, constraining the answer to the
+	// schema as far as the API allows
+	NewRequest(ctx context.Context, req CompletionRequest) (*http.Request, error)
+	// DecodeResponse extracts the model's JSON answer from a successful response body
+	DecodeResponse(body []byte) (string, error)
+}
+
+// NewProvider creates the provider named in the config. "claude" is accepted
+// as another name for Anthropic.
+func NewProvider(cfg AIConfig) (LLMProvider, error) {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderGemini:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the %s provider requires an API key", ProviderGemini)
+		}
+		return &geminiProvider{
+			apiKey:  cfg.APIKey,
+			model:   valueOr(cfg.Model, defaultGeminiModel),
+			baseURL: valueOr(baseURL, defaultGeminiBaseURL),
+		}, nil
+	case ProviderOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the %s provider requires an API key", ProviderOpenAI)
+		}
+		return &openAIProvider{
+			name:         ProviderOpenAI,
+			apiKey:       cfg.APIKey,
+			model:        valueOr(cfg.Model, defaultOpenAIModel),
+			baseURL:      valueOr(baseURL, defaultOpenAIBaseURL),
+			strictSchema: true,
+		}, nil
+	case ProviderAnthropic, "claude":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the %s provider requires an API key", ProviderAnthropic)
+		}
+		return &anthropicProvider{
+			apiKey:  cfg.APIKey,
+			model:   valueOr(cfg.Model, defaultAnthropicModel),
+			baseURL: valueOr(baseURL, defaultAnthropicBaseURL),
+		}, nil
+	case ProviderOpenAICompatible:
+		if baseURL == "" || cfg.Model == "" {
+			return nil, fmt.Errorf("the %s provider requires a base URL and a model", ProviderOpenAICompatible)
+		}
+		return &openAIProvider{
+			name:    ProviderOpenAICompatible,
+			apiKey:  cfg.APIKey,
+			model:   cfg.Model,
+			baseURL: baseURL,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported AI provider: %s", cfg.Provider)
+	}
+}
+
+// geminiProvider calls the Google Gemini generateContent API
+type geminiProvider struct {
+	apiKey  string
+	model   string
+	baseURL string
+}
+
+func (p *geminiProvider) Name() string {
+	return ProviderGemini + "/" + p.model
+}
+
+func (p *geminiProvider) NewRequest(ctx context.Context, completion CompletionRequest) (*http.Request, error) {
+	generationConfig := map[string]interface{}{
+		"temperature":      completionTemperature,
+		"maxOutputTokens":  completion.MaxTokens,
+		"responseMimeType": "application/json",
+	}
+	if completion.Schema != nil {
+		generationConfig["responseSchema"] = geminiSchema(completion.Schema)
+	}
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent", p.baseURL, p.model)
+	req, err := newJSONRequest(ctx, url, map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"parts": []map[string]string{
+					{"text": completion.Prompt},
+				},
+			},
+		},
+		"generationConfig": generationConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", p.apiKey)
+	return req, nil
+}
+
+func (p *geminiProvider) DecodeResponse(body []byte) (string, error) {
+	var envelope struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", fmt.Errorf("failed to decode Gemini response: %w", err)
+	}
+	if len(envelope.Candidates) == 0 || len(envelope.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("Gemini response has no content")
+	}
+	return envelope.Candidates[0].Content.Parts[0].Text, nil
+}
+
+// openAIProvider calls the OpenAI chat completions API, or any server that implements it
+type openAIProvider struct {
+	name         string
+	apiKey       string // Optional for self-hosted servers
+	model        string
+	baseURL      string
+	strictSchema bool // Use strict JSON schema output rather than plain JSON mode
+}
+
+func (p *openAIProvider) Name() string {
+	return p.name + "/" + p.model
+}
+
+func (p *openAIProvider) NewRequest(ctx context.Context, completion CompletionRequest) (*http.Request, error) {
+	responseFormat := map[string]interface{}{"type": "json_object"}
+	if p.strictSchema && completion.Schema != nil {
+		responseFormat = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "answer",
+				"strict": true,
+				"schema": completion.Schema,
+			},
+		}
+	}
+
+	req, err := newJSONRequest(ctx, p.baseURL+"/chat/completions", map[string]interface{}{
+		"model": p.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": completion.Prompt},
+		},
+		"temperature":     completionTemperature,
+		"max_tokens":      completion.MaxTokens,
+		"response_format": responseFormat,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return req, nil
+}
+
+func (p *openAIProvider) DecodeResponse(body []byte) (string, error) {
+	var envelope struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", fmt.Errorf("failed to decode %s response: %w", p.name, err)
+	}
+	if len(envelope.Choices) == 0 {
+		return "", fmt.Errorf("%s response has no choices", p.name)
+	}
+	return envelope.Choices[0].Message.Content, nil
+}
+
+// anthropicProvider calls the Anthropic Messages API
+type anthropicProvider struct {
+	apiKey  string
+	model   string
+	baseURL string
+}
+
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic + "/" + p.model
+}
+
+// NewRequest asks for the answer as the input of a forced tool call, which
+// Anthropic validates against the tool's schema
+func (p *anthropicProvider) NewRequest(ctx context.Context, completion CompletionRequest) (*http.Request, error) {
+	payload := map[string]interface{}{
+		"model":       p.model,
+		"max_tokens":  completion.MaxTokens,
+		"temperature": completionTemperature,
+		"messages": []map[string]string{
+			{"role": "user", "content": completion.Prompt},
+		},
+	}
+	if completion.Schema != nil {
+		payload["tools"] = []map[string]interface{}{
+			{
+				"name":         anthropicAnswerTool,
+				"description":  "Record the answer in the requested format",
+				"input_schema": completion.Schema,
+			},
+		}
+		payload["tool_choice"] = map[string]string{"type": "tool", "name": anthropicAnswerTool}
+	}
+
+	req, err := newJSONRequest(ctx, p.baseURL+"/v1/messages", payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+	return req, nil
+}
+
+func (p *anthropicProvider) DecodeResponse(body []byte) (string, error) {
+	var envelope struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+	for _, block := range envelope.Content {
+		if block.Type == "tool_use" && block.Name == anthropicAnswerTool {
+			return string(block.Input), nil
+		}
+	}
+	for _, block := range envelope.Content {
+		if block.Type == "text" {
+			return block.Text, nil
+		}
+	}
+	return "", fmt.Errorf("Anthropic response has no answer")
+}
+
+// geminiSchema converts a JSON schema to Gemini's OpenAPI subset, which
+// spells types in upper case and has no additionalProperties
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "properties" {
+				properties := make(map[string]interface{}, len(v))
+				for name, property := range v {
+					properties[name] = geminiSchema(property.(map[string]interface{}))
+				}
+				converted[key] = properties
+			} else {
+				converted[key] = geminiSchema(v)
+			}
+		case string:
+			if key == "type" {
+				converted[key] = strings.ToUpper(v)
+			} else {
+				converted[key] = v
+			}
+		default:
+			if key != "additionalProperties" {
+				converted[key] = v
+			}
+		}
+	}
+	return converted
+}
+
+// newJSONRequest builds a POST request with a JSON body
+func newJSONRequest(ctx context.Context, url string, payload interface{}) (*http.Request, error) {
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// valueOr returns value, or fallback when value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
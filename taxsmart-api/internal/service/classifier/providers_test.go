@@ -0,0 +1,184 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProviders_RoundTrip(t *testing.T) {
+	const answer = `{"results":[]}`
+
+	tests := []struct {
+		name       string
+		cfg        AIConfig
+		wantPath   string
+		wantHeader map[string]string
+		wantModel  string // Model sent in the request body; Gemini puts it in the path
+		wantName   string
+		envelope   interface{}
+	}{
+		{
+			name:       "gemini",
+			cfg:        AIConfig{Provider: "gemini", APIKey: "g-key", Model: "gemini-2.0-flash"},
+			wantPath:   "/v1beta/models/gemini-2.0-flash:generateContent",
+			wantHeader: map[string]string{"x-goog-api-key": "g-key"},
+			wantName:   "gemini/gemini-2.0-flash",
+			envelope: map[string]interface{}{"candidates": []interface{}{
+				map[string]interface{}{"content": map[string]interface{}{"parts": []interface{}{map[string]string{"text": answer}}}},
+			}},
+		},
+		{
+			name:       "openai",
+			cfg:        AIConfig{Provider: "openai", APIKey: "o-key"},
+			wantPath:   "/chat/completions",
+			wantHeader: map[string]string{"Authorization": "Bearer o-key"},
+			wantModel:  defaultOpenAIModel,
+			wantName:   "openai/" + defaultOpenAIModel,
+			envelope: map[string]interface{}{"choices": []interface{}{
+				map[string]interface{}{"message": map[string]string{"content": answer}},
+			}},
+		},
+		{
+			name:       "anthropic",
+			cfg:        AIConfig{Provider: "claude", APIKey: "a-key"},
+			wantPath:   "/v1/messages",
+			wantHeader: map[string]string{"x-api-key": "a-key", "anthropic-version": anthropicAPIVersion},
+			wantModel:  defaultAnthropicModel,
+			wantName:   "anthropic/" + defaultAnthropicModel,
+			envelope: map[string]interface{}{"content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "name": anthropicAnswerTool, "input": json.RawMessage(answer)},
+			}},
+		},
+		{
+			name:       "openai-compatible without key",
+			cfg:        AIConfig{Provider: "openai-compatible", Model: "llama3.1:8b"},
+			wantPath:   "/chat/completions",
+			wantHeader: map[string]string{"Authorization": ""},
+			wantModel:  "llama3.1:8b",
+			wantName:   "openai-compatible/llama3.1:8b",
+			envelope: map[string]interface{}{"choices": []interface{}{
+				map[string]interface{}{"message": map[string]string{"content": answer}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("path = %s, want %s", r.URL.Path, tt.wantPath)
+				}
+				for header, want := range tt.wantHeader {
+					if got := r.Header.Get(header); got != want {
+						t.Errorf("%s header = %q, want %q", header, got, want)
+					}
+				}
+				var body struct {
+					Model string `json:"model"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				if body.Model != tt.wantModel {
+					t.Errorf("model = %q, want %q", body.Model, tt.wantModel)
+				}
+				json.NewEncoder(w).Encode(tt.envelope)
+			}))
+			defer server.Close()
+
+			cfg := tt.cfg
+			cfg.BaseURL = server.URL
+			ai, err := NewAIClassifier(cfg, nil)
+			if err != nil {
+				t.Fatalf("NewAIClassifier() error = %v", err)
+			}
+			if got := ai.provider.Name(); got != tt.wantName {
+				t.Errorf("Name() = %q, want %q", got, tt.wantName)
+			}
+
+			got, err := ai.complete(context.Background(), CompletionRequest{Prompt: "prompt", MaxTokens: 100})
+			if err != nil {
+				t.Fatalf("complete() error = %v", err)
+			}
+			if got != answer {
+				t.Errorf("complete() = %q, want %q", got, answer)
+			}
+		})
+	}
+}
+
+func TestProviders_RequestSchema(t *testing.T) {
+	schema := batchSchema()
+	completion := CompletionRequest{Prompt: "prompt", MaxTokens: 100, Schema: schema}
+
+	tests := []struct {
+		name  string
+		cfg   AIConfig
+		check func(t *testing.T, body map[string]interface{})
+	}{
+		{"openai uses strict json_schema", AIConfig{Provider: "openai", APIKey: "key"}, func(t *testing.T, body map[string]interface{}) {
+			format := body["response_format"].(map[string]interface{})
+			if format["type"] != "json_schema" || format["json_schema"].(map[string]interface{})["strict"] != true {
+				t.Errorf("response_format = %v", format)
+			}
+		}},
+		{"compatible uses json mode", AIConfig{Provider: "openai-compatible", BaseURL: "http://localhost", Model: "llama3"}, func(t *testing.T, body map[string]interface{}) {
+			if format := body["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+				t.Errorf("response_format = %v", format)
+			}
+		}},
+		{"anthropic forces the answer tool", AIConfig{Provider: "anthropic", APIKey: "key"}, func(t *testing.T, body map[string]interface{}) {
+			if choice := body["tool_choice"].(map[string]interface{}); choice["name"] != anthropicAnswerTool {
+				t.Errorf("tool_choice = %v", choice)
+			}
+		}},
+		{"gemini uses an upper-case response schema", AIConfig{Provider: "gemini", APIKey: "key"}, func(t *testing.T, body map[string]interface{}) {
+			responseSchema := body["generationConfig"].(map[string]interface{})["responseSchema"].(map[string]interface{})
+			if responseSchema["type"] != "OBJECT" {
+				t.Errorf("responseSchema.type = %v, want OBJECT", responseSchema["type"])
+			}
+			if _, ok := responseSchema["additionalProperties"]; ok {
+				t.Error("responseSchema has additionalProperties, which Gemini rejects")
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewProvider(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			req, err := provider.NewRequest(context.Background(), completion)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			var body map[string]interface{}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Fatalf("request body is not JSON: %v", err)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestNewProvider_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AIConfig
+	}{
+		{"unknown provider", AIConfig{Provider: "palm", APIKey: "key"}},
+		{"hosted provider without key", AIConfig{Provider: "anthropic"}},
+		{"compatible without base URL", AIConfig{Provider: "openai-compatible", Model: "llama3"}},
+		{"compatible without model", AIConfig{Provider: "openai-compatible", BaseURL: "http://localhost:11434/v1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProvider(tt.cfg); err == nil {
+				t.Error("NewProvider() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// AIRecording holds AI answers per transaction so evaluations can replay a
+// provider offline and repeatably. Answers are keyed by transaction type and
+// description, e.g. "credit|UPWORK ESCROW INC", so a recording can also be
+// written by hand as a stub provider.
+type AIRecording struct {
+	Provider string                    `json:"provider"` // Provider and model the answers came from
+	Answers  map[string]RecordedAnswer `json:"answers"`
+
+	mu sync.Mutex
+}
+
+// RecordedAnswer is the AI's answer for one transaction
+type RecordedAnswer struct {
+	Category     model.Category      `json:"category"`
+	Confidence   float64             `json:"confidence"`
+	Rationale    string              `json:"rationale,omitempty"`
+	Alternatives []model.Alternative `json:"alternatives,omitempty"`
+}
+
+// NewAIRecording creates an empty recording of a provider's answers
+func NewAIRecording(provider string) *AIRecording {
+	return &AIRecording{Provider: provider, Answers: make(map[string]RecordedAnswer)}
+}
+
+// LoadAIRecording reads a recording written by SaveAIRecording or by hand
+func LoadAIRecording(path string) (*AIRecording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var recording AIRecording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("invalid AI recording: %w", err)
+	}
+	if recording.Provider == "" {
+		return nil, fmt.Errorf("invalid AI recording: missing provider")
+	}
+	for key, answer := range recording.Answers {
+		if !answer.Category.IsValid() || answer.Confidence < 0 || answer.Confidence > 1 {
+			return nil, fmt.Errorf("invalid AI recording: bad answer for %q", key)
+		}
+	}
+	if recording.Answers == nil {
+		recording.Answers = make(map[string]RecordedAnswer)
+	}
+	return &recording, nil
+}
+
+// SaveAIRecording writes a recording as indented JSON
+func SaveAIRecording(path string, recording *AIRecording) error {
+	recording.mu.Lock()
+	data, err := json.MarshalIndent(recording, "", "  ")
+	recording.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// NewReplayAIClassifier creates an AI classifier that answers from a
+// recording instead of calling a provider. Transactions missing from the
+// recording come back uncategorized, as if the model had skipped them.
+func NewReplayAIClassifier(recording *AIRecording) *AIClassifier {
+	return &AIClassifier{
+		provider:  recordedProvider{name: recording.Provider},
+		recording: recording,
+		replay:    true,
+	}
+}
+
+// RecordTo keeps every answer the classifier gets from its provider in recording
+func (c *AIClassifier) RecordTo(recording *AIRecording) {
+	c.recording = recording
+}
+
+// record stores the answers for a classified batch
+func (r *AIRecording) record(transactions []model.ParsedTransaction, results []model.ClassificationResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, tx := range transactions {
+		answer := RecordedAnswer{
+			Category:     results[i].Category,
+			Confidence:   results[i].Confidence,
+			Alternatives: results[i].Alternatives,
+		}
+		if results[i].Explanation != nil {
+			answer.Rationale = results[i].Explanation.Rationale
+		}
+		r.Answers[recordingKey(tx)] = answer
+	}
+}
+
+// replay answers a batch from the recording
+func (r *AIRecording) replay(transactions []model.ParsedTransaction) []model.ClassificationResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]model.ClassificationResult, len(transactions))
+	for i, tx := range transactions {
+		answer, ok := r.Answers[recordingKey(tx)]
+		if !ok {
+			results[i] = model.ClassificationResult{Category: model.CategoryUncategorized, Method: "ai"}
+			continue
+		}
+		results[i] = model.ClassificationResult{
+			Category:     answer.Category,
+			Confidence:   answer.Confidence,
+			Method:       "ai",
+			Explanation:  aiExplanation(r.Provider, answer.Rationale),
+			Alternatives: rankAlternatives(answer.Category, answer.Alternatives),
+		}
+	}
+	return results
+}
+
+// recordingKey identifies a transaction in a recording by its type and
+// description with runs of whitespace collapsed
+func recordingKey(tx model.ParsedTransaction) string {
+	return strings.ToLower(tx.Type) + "|" + strings.Join(strings.Fields(tx.Description), " ")
+}
+
+// recordedProvider names the provider of a replayed recording. It never sends requests.
+type recordedProvider struct {
+	name string
+}
+
+func (p recordedProvider) Name() string {
+	return p.name
+}
+
+func (p recordedProvider) NewRequest(ctx context.Context, completion CompletionRequest) (*http.Request, error) {
+	return nil, fmt.Errorf("recorded provider %s cannot send requests", p.name)
+}
+
+func (p recordedProvider) DecodeResponse(body []byte) (string, error) {
+	return string(body), nil
+}
@@ -0,0 +1,213 @@
+package classifier
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Kinds of personal data masked before text leaves for an AI provider
+const (
+	RedactAccount = "ACCOUNT" // 10-digit NUBAN account numbers
+	RedactBVN     = "BVN"     // 11-digit runs such as BVNs and NINs
+	RedactNumber  = "NUMBER"  // Longer digit runs such as card numbers and references
+	RedactPhone   = "PHONE"
+	RedactEmail   = "EMAIL"
+	RedactName    = "NAME"
+)
+
+var (
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	digitRunPattern    = regexp.MustCompile(`\+?\d+`)
+	localPhonePattern  = regexp.MustCompile(`^0[789][01]\d{8}$`)
+	intlPhonePattern   = regexp.MustCompile(`^\+?234[789][01]\d{8}$`)
+	placeholderPattern = regexp.MustCompile(`\[(?:ACCOUNT|BVN|NUMBER|PHONE|EMAIL|NAME)_\d+\]`)
+	nameWordPattern    = regexp.MustCompile(`\pL[\pL'&.-]*`)
+	markerGapPattern   = regexp.MustCompile(`^[\s/]+$`) // Between a party marker and the name, as in "TRF/TO/JOHN DOE"
+	nameGapPattern     = regexp.MustCompile(`^\s+$`)
+)
+
+// partyMarkers introduce the other party in a narration, e.g. "TRF FRM JOHN DOE"
+var partyMarkers = map[string]bool{
+	"FRM": true, "FROM": true, "TO": true, "IFO": true, "BO": true, "BY": true,
+}
+
+// partyStops end the name following a party marker, e.g. "JOHN DOE FOR RENT"
+var partyStops = map[string]bool{
+	"REF": true, "FOR": true, "SESSION": true, "NARRATION": true,
+}
+
+// businessMarkers are words that mark a counterparty as an organisation
+// rather than a person, so its name is kept for classification
+var businessMarkers = map[string]bool{
+	"LTD": true, "LIMITED": true, "PLC": true, "NIG": true, "NIGERIA": true, "CO": true, "COMPANY": true,
+	"INC": true, "LLC": true, "ENTERPRISE": true, "ENTERPRISES": true, "VENTURES": true, "SERVICES": true,
+	"GLOBAL": true, "INTERNATIONAL": true, "HOLDINGS": true, "GROUP": true, "BANK": true, "MFB": true,
+	"INVESTMENT": true, "INVESTMENTS": true, "TECHNOLOGIES": true, "TECH": true, "SOLUTIONS": true,
+	"CONSULT": true, "CONSULTING": true, "STORES": true, "STORE": true, "SUPERMARKET": true, "MART": true,
+	"AGENCY": true, "SCHOOL": true, "ACADEMY": true, "UNIVERSITY": true, "CHURCH": true, "MINISTRIES": true,
+	"MOSQUE": true, "HOSPITAL": true, "PHARMACY": true, "FOUNDATION": true, "ASSOCIATION": true,
+	"ESCROW": true, "PAYOUT": true, "SETTLEMENT": true,
+}
+
+// Redactor masks personal data with numbered placeholders such as
+// "[PHONE_1]" and remembers the originals so answers can be restored
+// locally. The same value always gets the same placeholder, so one
+// Redactor should be used per request.
+type Redactor struct {
+	placeholders map[string]string // Kind and normalised value to placeholder
+	originals    map[string]string // Placeholder to original value
+	counts       map[string]int    // Values masked per kind
+}
+
+// NewRedactor creates an empty redactor
+func NewRedactor() *Redactor {
+	return &Redactor{
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// Redact masks emails, phone numbers, account numbers, BVN-like digit runs,
+// the given personal names, and any other name following a party marker such
+// as "FROM" or "BO" unless it is an organisation's
+func (r *Redactor) Redact(text string, names ...string) string {
+	text = emailPattern.ReplaceAllStringFunc(text, func(email string) string {
+		return r.placeholder(RedactEmail, strings.ToLower(email), email)
+	})
+
+	text = digitRunPattern.ReplaceAllStringFunc(text, func(run string) string {
+		digits := strings.TrimPrefix(run, "+")
+		switch {
+		case localPhonePattern.MatchString(digits):
+			return r.placeholder(RedactPhone, "234"+digits[1:], run)
+		case intlPhonePattern.MatchString(run):
+			return r.placeholder(RedactPhone, digits, run)
+		case len(digits) == 10:
+			return r.placeholder(RedactAccount, digits, run)
+		case len(digits) == 11:
+			return r.placeholder(RedactBVN, digits, run)
+		case len(digits) > 11:
+			return r.placeholder(RedactNumber, digits, run)
+		}
+		return run
+	})
+
+	for _, name := range names {
+		words := strings.Fields(strings.ToUpper(name))
+		if len(words) == 0 {
+			continue
+		}
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = regexp.QuoteMeta(word)
+		}
+		pattern := regexp.MustCompile(`(?i)\b` + strings.Join(quoted, `\W+`) + `\b`)
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			return r.placeholder(RedactName, strings.Join(words, " "), match)
+		})
+	}
+	return r.redactParties(text)
+}
+
+// redactParties masks the run of words after each party marker, up to a
+// stop word, another marker or anything other than a word. Runs naming an
+// organisation are kept; anything else is taken to be a person.
+func (r *Redactor) redactParties(text string) string {
+	words := nameWordPattern.FindAllStringIndex(text, -1)
+	var masked strings.Builder
+	last := 0
+	for i := 0; i < len(words); i++ {
+		if !partyMarkers[strings.ToUpper(text[words[i][0]:words[i][1]])] {
+			continue
+		}
+		end := i
+		for end+1 < len(words) {
+			gap := text[words[end][1]:words[end+1][0]]
+			if (end == i && !markerGapPattern.MatchString(gap)) || (end > i && !nameGapPattern.MatchString(gap)) {
+				break
+			}
+			word := strings.ToUpper(text[words[end+1][0]:words[end+1][1]])
+			if partyStops[word] || partyMarkers[word] {
+				break
+			}
+			end++
+		}
+		if end == i {
+			continue
+		}
+
+		run := make([]string, 0, end-i)
+		business := false
+		for _, word := range words[i+1 : end+1] {
+			upper := strings.ToUpper(strings.Trim(text[word[0]:word[1]], "'&.-"))
+			business = business || businessMarkers[upper]
+			run = append(run, upper)
+		}
+		if !business {
+			start, stop := words[i+1][0], words[end][1]
+			masked.WriteString(text[last:start])
+			masked.WriteString(r.placeholder(RedactName, strings.Join(run, " "), text[start:stop]))
+			last = stop
+		}
+		i = end
+	}
+	masked.WriteString(text[last:])
+	return masked.String()
+}
+
+// Restore replaces placeholders in text with the values they masked. When
+// kinds are given, only placeholders of those kinds are restored.
+func (r *Redactor) Restore(text string, kinds ...string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if len(kinds) > 0 && !slices.Contains(kinds, placeholder[1:strings.LastIndex(placeholder, "_")]) {
+			return placeholder
+		}
+		if original, ok := r.originals[placeholder]; ok {
+			return original
+		}
+		return placeholder
+	})
+}
+
+// Counts returns how many distinct values of each kind were masked
+func (r *Redactor) Counts() map[string]int {
+	counts := make(map[string]int, len(r.counts))
+	for kind, n := range r.counts {
+		counts[kind] = n
+	}
+	return counts
+}
+
+// placeholder returns the placeholder for a value, numbering new values per
+// kind. Values are matched in their normalised form and restored as first seen.
+func (r *Redactor) placeholder(kind, normalised, original string) string {
+	key := kind + ":" + normalised
+	if placeholder, ok := r.placeholders[key]; ok {
+		return placeholder
+	}
+	r.counts[kind]++
+	placeholder := fmt.Sprintf("[%s_%d]", kind, r.counts[kind])
+	r.placeholders[key] = placeholder
+	r.originals[placeholder] = original
+	return placeholder
+}
+
+// IsPersonalName reports whether a parsed counterparty may be a person:
+// alphabetic words, none of which mark a business. Single words and long
+// names count, so a name is masked whenever it might be someone's.
+func IsPersonalName(name string) bool {
+	words := strings.Fields(strings.ToUpper(name))
+	if len(words) == 0 {
+		return false
+	}
+	for _, word := range words {
+		if businessMarkers[word] || strings.ContainsFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }) {
+			return false
+		}
+	}
+	return true
+}
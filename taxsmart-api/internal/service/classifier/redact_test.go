@@ -0,0 +1,153 @@
+package classifier
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		names    []string
+		expected string
+	}{
+		{"account number", "TRF TO 0123456789 RENT", nil, "TRF TO [ACCOUNT_1] RENT"},
+		{"bvn", "BVN 22123456789 VERIFICATION", nil, "BVN [BVN_1] VERIFICATION"},
+		{"local phone", "AIRTIME 08031234567", nil, "AIRTIME [PHONE_1]"},
+		{"international phone", "AIRTIME +2348031234567", nil, "AIRTIME [PHONE_1]"},
+		{"long reference", "REF 000013240101120000123456", nil, "REF [NUMBER_1]"},
+		{"email", "PAYPAL FROM Ada.Obi@example.com", nil, "PAYPAL FROM [EMAIL_1]"},
+		{"name", "TRF FROM JOHN  DOE FOR RENT", []string{"John Doe"}, "TRF FROM [NAME_1] FOR RENT"},
+		{"short numbers kept", "POS 2024 TERMINAL 1234", nil, "POS 2024 TERMINAL 1234"},
+		{"same value same placeholder", "08031234567 / +2348031234567", nil, "[PHONE_1] / [PHONE_1]"},
+		{"distinct values numbered", "0123456789 TO 9876543210", nil, "[ACCOUNT_1] TO [ACCOUNT_2]"},
+		{"both parties", "TRANSFER FROM CHUKWUEMEKA OKAFOR TO MARY JANE", []string{"CHUKWUEMEKA OKAFOR"}, "TRANSFER FROM [NAME_1] TO [NAME_2]"},
+		{"single surname", "TRF FRM OKAFOR FOR SCHOOL FEES", nil, "TRF FRM [NAME_1] FOR SCHOOL FEES"},
+		{"five-word name", "NIP FRM ADAEZE CHIOMA NGOZI AMAKA OKAFOR REF 1234", nil, "NIP FRM [NAME_1] REF 1234"},
+		{"second party", "TRF FROM JOHN DOE BO JANE ROE", []string{"JOHN DOE"}, "TRF FROM [NAME_1] BO [NAME_2]"},
+		{"after a segment break", "MOB/TO/Jane Roe/LUNCH", nil, "MOB/TO/[NAME_1]/LUNCH"},
+		{"organisation kept", "TRF FRM ACME NIGERIA LTD", nil, "TRF FRM ACME NIGERIA LTD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRedactor().Redact(tt.text, tt.names...); got != tt.expected {
+				t.Errorf("Redact() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRedactor_Restore(t *testing.T) {
+	r := NewRedactor()
+	r.Redact("TRF FROM JOHN DOE 0123456789 08031234567", "JOHN DOE")
+
+	got := r.Restore("Rent from [NAME_1] via [ACCOUNT_1], phone [PHONE_1], unknown [EMAIL_9]")
+	want := "Rent from JOHN DOE via 0123456789, phone 08031234567, unknown [EMAIL_9]"
+	if got != want {
+		t.Errorf("Restore() = %q, want %q", got, want)
+	}
+
+	wantCounts := map[string]int{RedactName: 1, RedactAccount: 1, RedactPhone: 1}
+	if counts := r.Counts(); !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("Counts() = %v, want %v", counts, wantCounts)
+	}
+}
+
+func TestIsPersonalName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"JOHN DOE", true},
+		{"Adaeze Chioma Okafor", true},
+		{"JOHN", true},
+		{"ADAEZE CHIOMA NGOZI AMAKA OKAFOR", true},
+		{"ACME NIGERIA LTD", false},
+		{"PAYSTACK PAYOUT", false},
+		{"JOHN DOE 2", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPersonalName(tt.name); got != tt.expected {
+				t.Errorf("IsPersonalName(%q) = %v, want %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAIClassifier_AuditsRedactedPrompt(t *testing.T) {
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryRental), &calls)
+	store := memory.New()
+
+	ai := newTestAI(t, server.URL, 10)
+	ai.audit = store
+
+	userID := uuid.New()
+	tx := model.ParsedTransaction{
+		Description: "TRF FROM JOHN DOE 0123456789 RENT",
+		Amount:      500000,
+		Type:        "credit",
+		Narration:   model.Narration{Counterparty: "JOHN DOE"},
+	}
+	if _, err := ai.ClassifyBatch(context.Background(), userID, []model.ParsedTransaction{tx}); err != nil {
+		t.Fatalf("ClassifyBatch() error = %v", err)
+	}
+
+	entries, err := store.ListAIAudit(context.Background(), repository.AIAuditFilter{UserID: userID})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListAIAudit() = %d entries, %v, want 1", len(entries), err)
+	}
+	entry := entries[0]
+	for _, personal := range []string{"JOHN DOE", "0123456789"} {
+		if strings.Contains(entry.Prompt, personal) {
+			t.Errorf("audited prompt contains %q: %s", personal, entry.Prompt)
+		}
+	}
+	if entry.Status != "ok" || entry.TransactionCount != 1 || entry.Redactions[RedactName] != 1 || entry.Redactions[RedactAccount] != 1 {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
+
+func TestClassifier_RespectsExternalAIOptOut(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int32
+	server := stubOpenAI(t, string(model.CategoryFreelance), &calls)
+	store := memory.New()
+
+	user := &model.User{ID: uuid.New(), AuthUID: "uid"}
+	if err := store.EnsureUser(ctx, user); err != nil {
+		t.Fatalf("EnsureUser() error = %v", err)
+	}
+	user.ExternalAIDisabled = true
+	if err := store.UpdateUserSettings(ctx, user); err != nil {
+		t.Fatalf("UpdateUserSettings() error = %v", err)
+	}
+
+	c := NewClassifier(newTestAI(t, server.URL, 10), store, nil)
+	tx := model.ParsedTransaction{Description: "KOLA ADEYEMI PROJECT", Amount: 250000, Type: "credit"}
+
+	result := c.Classify(ctx, user.ID, tx)
+	if calls.Load() != 0 {
+		t.Errorf("AI requests = %d for a user who opted out, want 0", calls.Load())
+	}
+	if result.Method == "ai" {
+		t.Errorf("Method = %q, want a rules result", result.Method)
+	}
+
+	if c.Classify(ctx, uuid.New(), tx); calls.Load() != 1 {
+		t.Errorf("AI requests = %d for another user, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,365 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// Rule match types
+const (
+	MatchToken    = "token"    // Pattern words appear as whole, consecutive words
+	MatchContains = "contains" // Pattern appears anywhere in the description, even inside words
+	MatchRegex    = "regex"    // Pattern is a regular expression over the raw text
+)
+
+// Rule fields
+const (
+	FieldDescription  = "description"
+	FieldCounterparty = "counterparty" // Counterparty parsed from the narration
+)
+
+// Rule classifies transactions whose description or counterparty matches its
+// pattern. A rule may instead, or as well, require a payment channel.
+type Rule struct {
+	ID         string         `json:"id"`
+	Pattern    string         `json:"pattern,omitempty"`
+	Match      string         `json:"match,omitempty"`   // Defaults to MatchToken
+	Field      string         `json:"field,omitempty"`   // Defaults to FieldDescription
+	Channel    string         `json:"channel,omitempty"` // e.g. "POS"; empty for any channel
+	Category   model.Category `json:"category"`
+	TxType     string         `json:"tx_type,omitempty"` // "credit", "debit" or empty for both
+	Priority   int            `json:"priority"`          // Higher priorities are tried first
+	Confidence float64        `json:"confidence"`
+	MinAmount  float64        `json:"min_amount,omitempty"`
+	MaxAmount  float64        `json:"max_amount,omitempty"` // 0 means no upper bound
+}
+
+// RulePack is a named, versioned set of rules
+type RulePack struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	Rules       []Rule `json:"rules"`
+}
+
+// packNamePattern restricts pack names to safe file names
+var packNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// compiledRule is a rule with its matcher built once
+type compiledRule struct {
+	Rule
+	pack  string
+	order int
+	match func(n narration) (matchSpan, bool)
+}
+
+// matchSpan locates a rule match: the field searched and the byte range
+// of the matched text, which is empty for channel-only rules
+type matchSpan struct {
+	field      string
+	start, end int
+}
+
+// Validate checks that a pack can be compiled
+func (p RulePack) Validate() error {
+	_, err := compilePack(p)
+	return err
+}
+
+// compilePack validates a pack and builds matchers for its rules
+func compilePack(pack RulePack) ([]compiledRule, error) {
+	if !packNamePattern.MatchString(pack.Name) {
+		return nil, fmt.Errorf("invalid pack name %q: use lower-case letters, digits, '-' and '_'", pack.Name)
+	}
+	if pack.Version == "" {
+		return nil, fmt.Errorf("pack %s: version is required", pack.Name)
+	}
+
+	seen := make(map[string]bool, len(pack.Rules))
+	compiled := make([]compiledRule, 0, len(pack.Rules))
+	for i, rule := range pack.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("pack %s: rule %d has no id", pack.Name, i)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("pack %s: duplicate rule id %q", pack.Name, rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if strings.TrimSpace(rule.Pattern) == "" && rule.Channel == "" {
+			return nil, fmt.Errorf("pack %s: rule %s needs a pattern or a channel", pack.Name, rule.ID)
+		}
+		if rule.Field != "" && rule.Field != FieldDescription && rule.Field != FieldCounterparty {
+			return nil, fmt.Errorf("pack %s: rule %s has unknown field %q", pack.Name, rule.ID, rule.Field)
+		}
+		if !rule.Category.IsValid() {
+			return nil, fmt.Errorf("pack %s: rule %s has unknown category %q", pack.Name, rule.ID, rule.Category)
+		}
+		if rule.TxType != "" && rule.TxType != "credit" && rule.TxType != "debit" {
+			return nil, fmt.Errorf("pack %s: rule %s has invalid tx_type %q", pack.Name, rule.ID, rule.TxType)
+		}
+		if rule.Confidence < 0 || rule.Confidence > 1 {
+			return nil, fmt.Errorf("pack %s: rule %s confidence must be between 0 and 1", pack.Name, rule.ID)
+		}
+		if rule.MinAmount < 0 || rule.MaxAmount < 0 || (rule.MaxAmount > 0 && rule.MaxAmount < rule.MinAmount) {
+			return nil, fmt.Errorf("pack %s: rule %s has an invalid amount range", pack.Name, rule.ID)
+		}
+
+		match, err := buildMatcher(rule)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s: rule %s: %w", pack.Name, rule.ID, err)
+		}
+		compiled = append(compiled, compiledRule{Rule: rule, pack: pack.Name, order: i, match: match})
+	}
+	return compiled, nil
+}
+
+// matchText is one field of a transaction prepared once for matching against every rule
+type matchText struct {
+	raw    string
+	upper  string
+	tokens []string
+	bounds [][2]int // Byte range of each token in upper
+}
+
+// newMatchText upper-cases and tokenises text
+func newMatchText(text string) matchText {
+	upper := strings.ToUpper(text)
+	tokens, bounds := tokenizeBounds(upper)
+	return matchText{raw: text, upper: upper, tokens: tokens, bounds: bounds}
+}
+
+// narration is a transaction's text fields prepared for matching
+type narration struct {
+	description  matchText
+	counterparty matchText
+	channel      string
+}
+
+// newNarration prepares a transaction whose narration fields are filled in
+func newNarration(tx model.ParsedTransaction) narration {
+	return narration{
+		description:  newMatchText(tx.Description),
+		counterparty: newMatchText(tx.Counterparty),
+		channel:      tx.Channel,
+	}
+}
+
+// tokenize splits text into words at every non-alphanumeric character and at
+// letter-digit boundaries, so "NIP/ACME" and "FEB2026" both yield two tokens
+func tokenize(text string) []string {
+	tokens, _ := tokenizeBounds(text)
+	return tokens
+}
+
+// tokenizeBounds tokenises text like tokenize, also returning the byte range of each token
+func tokenizeBounds(text string) ([]string, [][2]int) {
+	var tokens []string
+	var bounds [][2]int
+	start := -1
+	var prevDigit bool
+	for i, r := range text {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		digit := unicode.IsDigit(r)
+		if start >= 0 && (!alnum || digit != prevDigit) {
+			tokens = append(tokens, text[start:i])
+			bounds = append(bounds, [2]int{start, i})
+			start = -1
+		}
+		if alnum && start < 0 {
+			start = i
+		}
+		prevDigit = digit
+	}
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+		bounds = append(bounds, [2]int{start, len(text)})
+	}
+	return tokens, bounds
+}
+
+// buildMatcher returns a function reporting whether and where a narration matches the rule
+func buildMatcher(rule Rule) (func(n narration) (matchSpan, bool), error) {
+	channel := strings.ToUpper(rule.Channel)
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return func(n narration) (matchSpan, bool) {
+			return matchSpan{field: "channel"}, n.channel == channel
+		}, nil
+	}
+
+	match, err := buildTextMatcher(rule)
+	if err != nil {
+		return nil, err
+	}
+	fieldName := FieldDescription
+	field := func(n narration) matchText { return n.description }
+	if rule.Field == FieldCounterparty {
+		fieldName = FieldCounterparty
+		field = func(n narration) matchText { return n.counterparty }
+	}
+	return func(n narration) (matchSpan, bool) {
+		if channel != "" && n.channel != channel {
+			return matchSpan{}, false
+		}
+		start, end, ok := match(field(n))
+		return matchSpan{field: fieldName, start: start, end: end}, ok
+	}, nil
+}
+
+// buildTextMatcher returns a function locating the rule's pattern in text
+func buildTextMatcher(rule Rule) (func(t matchText) (start, end int, ok bool), error) {
+	switch rule.Match {
+	case "", MatchToken:
+		words := tokenize(strings.ToUpper(rule.Pattern))
+		if len(words) == 0 {
+			return nil, fmt.Errorf("pattern has no words to match")
+		}
+		return func(t matchText) (int, int, bool) {
+			i := indexSequence(t.tokens, words)
+			if i < 0 {
+				return 0, 0, false
+			}
+			return t.bounds[i][0], t.bounds[i+len(words)-1][1], true
+		}, nil
+	case MatchContains:
+		pattern := strings.ToUpper(rule.Pattern)
+		return func(t matchText) (int, int, bool) {
+			i := strings.Index(t.upper, pattern)
+			if i < 0 {
+				return 0, 0, false
+			}
+			return i, i + len(pattern), true
+		}, nil
+	case MatchRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return func(t matchText) (int, int, bool) {
+			loc := re.FindStringIndex(t.raw)
+			if loc == nil {
+				return 0, 0, false
+			}
+			return loc[0], loc[1], true
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown match type %q", rule.Match)
+	}
+}
+
+// indexSequence returns the index in tokens where words first appear
+// consecutively, or -1
+func indexSequence(tokens, words []string) int {
+	for i := 0; i+len(words) <= len(tokens); i++ {
+		matched := true
+		for j, word := range words {
+			if tokens[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
+
+// applies reports whether the rule applies to the transaction and where its pattern matched
+func (r *compiledRule) applies(tx model.ParsedTransaction, n narration) (matchSpan, bool) {
+	if r.TxType != "" && r.TxType != tx.Type {
+		return matchSpan{}, false
+	}
+	if r.MinAmount > 0 && tx.Amount < r.MinAmount {
+		return matchSpan{}, false
+	}
+	if r.MaxAmount > 0 && tx.Amount > r.MaxAmount {
+		return matchSpan{}, false
+	}
+	return r.match(n)
+}
+
+// sortRules orders rules by priority, then longer patterns first so specific
+// rules beat general ones, then by pack name and position in the pack
+func sortRules(rules []compiledRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if len(a.Pattern) != len(b.Pattern) {
+			return len(a.Pattern) > len(b.Pattern)
+		}
+		if a.pack != b.pack {
+			return a.pack < b.pack
+		}
+		return a.order < b.order
+	})
+}
+
+// ParseRulePack decodes and validates a rule pack
+func ParseRulePack(data []byte) (RulePack, error) {
+	var pack RulePack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return RulePack{}, fmt.Errorf("invalid rule pack: %w", err)
+	}
+	if err := pack.Validate(); err != nil {
+		return RulePack{}, err
+	}
+	return pack, nil
+}
+
+// LoadRulePacks reads every *.json rule pack in a directory
+func LoadRulePacks(dir string) ([]RulePack, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	packs := make([]RulePack, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		pack, err := ParseRulePack(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		packs = append(packs, pack)
+	}
+	return packs, nil
+}
+
+// SaveRulePack writes a rule pack to <dir>/<name>.json
+func SaveRulePack(dir string, pack RulePack) error {
+	data, err := json.MarshalIndent(pack, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, pack.Name+".json"), append(data, '\n'), 0o644)
+}
+
+// DeleteRulePack removes <dir>/<name>.json if it exists
+func DeleteRulePack(dir, name string) error {
+	if !packNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid pack name %q", name)
+	}
+	err := os.Remove(filepath.Join(dir, name+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
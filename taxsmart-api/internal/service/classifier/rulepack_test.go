@@ -0,0 +1,176 @@
+package classifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func fintechPack() RulePack {
+	return RulePack{
+		Name:    "ng-fintech-payouts",
+		Version: "1",
+		Rules: []Rule{
+			{ID: "paystack-payout", Pattern: "PAYSTACK PAYOUT", Match: MatchToken, Category: model.CategoryFreelance, TxType: "credit", Priority: 200, Confidence: 0.9},
+			{ID: "large-chipper", Pattern: `(?i)chipper\s*cash`, Match: MatchRegex, Category: model.CategoryFreelance, TxType: "credit", Priority: 200, Confidence: 0.8, MinAmount: 100_000},
+			{ID: "small-chipper", Pattern: "CHIPPER", Match: MatchContains, Category: model.CategoryTransfer, TxType: "credit", Priority: 150, Confidence: 0.6, MaxAmount: 99_999.99},
+		},
+	}
+}
+
+func TestDefaultRulePack(t *testing.T) {
+	pack := DefaultRulePack()
+	if pack.Name != DefaultPackName || pack.Version == "" || len(pack.Rules) == 0 {
+		t.Fatalf("Unexpected default pack %s %s with %d rules", pack.Name, pack.Version, len(pack.Rules))
+	}
+	if err := pack.Validate(); err != nil {
+		t.Errorf("Default pack is invalid: %v", err)
+	}
+}
+
+func TestRuleEngine_RulePacks(t *testing.T) {
+	engine := NewRuleEngine()
+	if err := engine.AddPack(fintechPack()); err != nil {
+		t.Fatalf("AddPack failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tx       model.ParsedTransaction
+		expected model.Category
+		rule     string
+	}{
+		{"Higher priority pack rule wins", model.ParsedTransaction{Description: "NIP/PAYSTACK PAYOUT/ORD 552", Type: "credit", Amount: 50_000}, model.CategoryFreelance, "ng-fintech-payouts/paystack-payout"},
+		{"Regex rule above minimum amount", model.ParsedTransaction{Description: "Chipper Cash transfer", Type: "credit", Amount: 250_000}, model.CategoryFreelance, "ng-fintech-payouts/large-chipper"},
+		{"Amount range selects the lower rule", model.ParsedTransaction{Description: "CHIPPERCASH", Type: "credit", Amount: 20_000}, model.CategoryTransfer, "ng-fintech-payouts/small-chipper"},
+		{"Transaction type is respected", model.ParsedTransaction{Description: "PAYSTACK PAYOUT PAYMENT", Type: "debit", Amount: 5_000}, model.CategoryExpense, "default/expense-payment"},
+		{"Default pack still applies", model.ParsedTransaction{Description: "SALARY FOR JUNE", Type: "credit", Amount: 400_000}, model.CategoryEmployment, "default/employment-salary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := engine.ClassifyTransaction(tt.tx)
+			if result.Category != tt.expected || result.Rule != tt.rule {
+				t.Errorf("ClassifyTransaction(%q) = %s via %s, expected %s via %s",
+					tt.tx.Description, result.Category, result.Rule, tt.expected, tt.rule)
+			}
+		})
+	}
+
+	if err := engine.RemovePack("ng-fintech-payouts"); err != nil {
+		t.Fatalf("RemovePack failed: %v", err)
+	}
+	if result := engine.ClassifyTransaction(model.ParsedTransaction{Description: "CHIPPERCASH", Type: "credit"}); result.Rule != "" {
+		t.Errorf("Expected no match after removing the pack, got %+v", result)
+	}
+	if err := engine.RemovePack(DefaultPackName); err == nil {
+		t.Error("Expected removing the default pack to fail")
+	}
+}
+
+func TestRuleEngine_WithPackLeavesEngineUnchanged(t *testing.T) {
+	engine := NewRuleEngine()
+	proposed, err := engine.WithPack(fintechPack())
+	if err != nil {
+		t.Fatalf("WithPack failed: %v", err)
+	}
+
+	tx := model.ParsedTransaction{Description: "PAYSTACK PAYOUT", Type: "credit"}
+	if got := proposed.ClassifyTransaction(tx).Category; got != model.CategoryFreelance {
+		t.Errorf("Expected the proposed engine to use the new pack, got %s", got)
+	}
+	if _, ok := engine.Pack("ng-fintech-payouts"); ok {
+		t.Error("Expected the original engine to be unchanged")
+	}
+}
+
+func TestRulePack_Validate(t *testing.T) {
+	valid := Rule{ID: "r1", Pattern: "X", Category: model.CategoryExpense, Confidence: 0.5}
+
+	tests := []struct {
+		name    string
+		pack    RulePack
+		wantErr string
+	}{
+		{"Invalid name", RulePack{Name: "../etc", Version: "1"}, "invalid pack name"},
+		{"Missing version", RulePack{Name: "p"}, "version is required"},
+		{"Duplicate id", RulePack{Name: "p", Version: "1", Rules: []Rule{valid, valid}}, "duplicate rule id"},
+		{"Unknown category", RulePack{Name: "p", Version: "1", Rules: []Rule{{ID: "r", Pattern: "X", Category: "salary"}}}, "unknown category"},
+		{"Bad regex", RulePack{Name: "p", Version: "1", Rules: []Rule{{ID: "r", Pattern: "(", Match: MatchRegex, Category: model.CategoryExpense}}}, "invalid regex"},
+		{"Unknown match", RulePack{Name: "p", Version: "1", Rules: []Rule{{ID: "r", Pattern: "X", Match: "fuzzy", Category: model.CategoryExpense}}}, "unknown match type"},
+		{"Inverted amount range", RulePack{Name: "p", Version: "1", Rules: []Rule{{ID: "r", Pattern: "X", Category: model.CategoryExpense, MinAmount: 10, MaxAmount: 5}}}, "invalid amount range"},
+		{"Valid", RulePack{Name: "p", Version: "1", Rules: []Rule{valid}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pack.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRulePackFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := SaveRulePack(dir, fintechPack()); err != nil {
+		t.Fatalf("SaveRulePack failed: %v", err)
+	}
+
+	packs, err := LoadRulePacks(dir)
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("LoadRulePacks returned %d packs, %v", len(packs), err)
+	}
+	if packs[0].Name != "ng-fintech-payouts" || len(packs[0].Rules) != 3 || packs[0].Rules[1].MinAmount != 100_000 {
+		t.Errorf("Rule pack did not round-trip: %+v", packs[0])
+	}
+
+	if err := DeleteRulePack(dir, "ng-fintech-payouts"); err != nil {
+		t.Fatalf("DeleteRulePack failed: %v", err)
+	}
+	if packs, _ := LoadRulePacks(dir); len(packs) != 0 {
+		t.Errorf("Expected no packs after delete, got %d", len(packs))
+	}
+}
+
+func TestRuleEngine_NarrationFields(t *testing.T) {
+	engine := NewRuleEngine()
+	err := engine.AddPack(RulePack{
+		Name:    "employers",
+		Version: "1",
+		Rules: []Rule{
+			{ID: "acme", Pattern: "ACME LTD", Field: FieldCounterparty, Category: model.CategoryEmployment, TxType: "credit", Priority: 200, Confidence: 0.9},
+			{ID: "ussd-gifts", Channel: "USSD", Category: model.CategoryOtherIncome, TxType: "credit", Priority: 200, Confidence: 0.6},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddPack failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		description string
+		expected    string
+	}{
+		{"Counterparty field", "NIP/GTB/ACME LTD/JUNE/0123456789", "employers/acme"},
+		{"Pattern outside the counterparty does not match", "NIP/GTB/JOHN DOE/ACME LTD SHARES", "default/transfer-in-nip"},
+		{"Channel-only rule", "USSD TRF FROM AUNTY BISI", "employers/ussd-gifts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := engine.ClassifyTransaction(model.ParsedTransaction{Description: tt.description, Type: "credit"})
+			if result.Rule != tt.expected {
+				t.Errorf("ClassifyTransaction(%q) matched %q, expected %q", tt.description, result.Rule, tt.expected)
+			}
+		})
+	}
+}
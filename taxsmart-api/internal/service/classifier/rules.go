@@ -1,155 +1,187 @@
 package classifier
 
 import (
-	"regexp"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"sort"
-	"strings"
+	"sync"
 
 	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/parser"
 )
 
-// RuleEngine classifies transactions based on pattern matching
+// DefaultPackName names the built-in rule pack
+const DefaultPackName = "default"
+
+//go:embed rules/default.json
+var defaultPackJSON []byte
+
+// RuleEngine classifies transactions using rule packs. Packs can be added and
+// replaced at runtime; rules from every pack are matched in priority order.
 type RuleEngine struct {
-	incomePatterns  map[string]model.Category
-	expensePatterns map[string]model.Category
+	mu          sync.RWMutex
+	packs       map[string]RulePack
+	rules       []compiledRule
+	fingerprint string
+}
+
+// DefaultRulePack returns the built-in rule pack
+func DefaultRulePack() RulePack {
+	pack, err := ParseRulePack(defaultPackJSON)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in rule pack: %v", err))
+	}
+	return pack
 }
 
-// NewRuleEngine creates a new rule-based classifier
+// NewRuleEngine creates a new rule-based classifier with the built-in rule pack
 func NewRuleEngine() *RuleEngine {
-	return &RuleEngine{
-		incomePatterns: map[string]model.Category{
-			// Employment
-			"SALARY":       model.CategoryEmployment,
-			"PAY":          model.CategoryEmployment,
-			"WAGES":        model.CategoryEmployment,
-			"PAYROLL":      model.CategoryEmployment,
-			"REMUNERATION": model.CategoryEmployment,
-
-			// Freelance
-			"UPWORK":     model.CategoryFreelance,
-			"FIVERR":     model.CategoryFreelance,
-			"PAYONEER":   model.CategoryFreelance,
-			"WISE":       model.CategoryFreelance,
-			"TOPTAL":     model.CategoryFreelance,
-			"FREELANCER": model.CategoryFreelance,
-			"CONTRA":     model.CategoryFreelance,
-
-			// Crypto
-			"BINANCE":     model.CategoryCrypto,
-			"LUNO":        model.CategoryCrypto,
-			"QUIDAX":      model.CategoryCrypto,
-			"PAXFUL":      model.CategoryCrypto,
-			"COINBASE":    model.CategoryCrypto,
-			"KRAKEN":      model.CategoryCrypto,
-			"BYBIT":       model.CategoryCrypto,
-			"KUCOIN":      model.CategoryCrypto,
-			"ROQQU":       model.CategoryCrypto,
-			"PATRICIA":    model.CategoryCrypto,
-			"BUSHA":       model.CategoryCrypto,
-			"YELLOW CARD": model.CategoryCrypto,
-			"NOONES":      model.CategoryCrypto,
-
-			// Investment
-			"DIVIDEND":          model.CategoryInvestment,
-			"INVESTMENT RETURN": model.CategoryInvestment,
-			"BAMBOO":            model.CategoryInvestment,
-			"RISEVEST":          model.CategoryInvestment,
-			"TROVE":             model.CategoryInvestment,
-			"CHAKA":             model.CategoryInvestment,
-
-			// Interest
-			"INTEREST":   model.CategoryInterest,
-			"INT CREDIT": model.CategoryInterest,
-
-			// Rental
-			"RENT RECEIVED": model.CategoryRental,
-			"TENANT":        model.CategoryRental,
-			"RENTAL INCOME": model.CategoryRental,
-		},
-		expensePatterns: map[string]model.Category{
-			// Rent expense
-			"RENT PAYMENT":  model.CategoryRentExpense,
-			"LANDLORD":      model.CategoryRentExpense,
-			"HOUSE RENT":    model.CategoryRentExpense,
-			"ACCOMMODATION": model.CategoryRentExpense,
-		},
+	r := &RuleEngine{packs: make(map[string]RulePack)}
+	if err := r.AddPack(DefaultRulePack()); err != nil {
+		panic(fmt.Sprintf("invalid built-in rule pack: %v", err))
 	}
+	return r
 }
 
-// sortedPatterns returns patterns sorted by length (longest first) for deterministic matching
-func sortedPatterns(patterns map[string]model.Category) []string {
-	keys := make([]string, 0, len(patterns))
-	for k := range patterns {
-		keys = append(keys, k)
+// AddPack validates a pack and installs it, replacing any pack with the same name
+func (r *RuleEngine) AddPack(pack RulePack) error {
+	if _, err := compilePack(pack); err != nil {
+		return err
 	}
-	sort.Slice(keys, func(i, j int) bool {
-		return len(keys[i]) > len(keys[j])
-	})
-	return keys
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packs[pack.Name] = pack
+	r.rebuild()
+	return nil
 }
 
-// Classify attempts to classify a transaction based on its description
-func (r *RuleEngine) Classify(description string, txType string) model.ClassificationResult {
-	upperDesc := strings.ToUpper(description)
-
-	// Check income patterns for credits (longest patterns first to avoid partial matches)
-	if txType == "credit" {
-		for _, pattern := range sortedPatterns(r.incomePatterns) {
-			if strings.Contains(upperDesc, pattern) {
-				return model.ClassificationResult{
-					Category:   r.incomePatterns[pattern],
-					Confidence: 0.85,
-					Method:     "rules",
-				}
-			}
-		}
+// RemovePack uninstalls a pack. The built-in pack cannot be removed.
+func (r *RuleEngine) RemovePack(name string) error {
+	if name == DefaultPackName {
+		return fmt.Errorf("the %s rule pack cannot be removed", DefaultPackName)
 	}
 
-	// Check expense patterns for debits (longest patterns first)
-	if txType == "debit" {
-		for _, pattern := range sortedPatterns(r.expensePatterns) {
-			if strings.Contains(upperDesc, pattern) {
-				return model.ClassificationResult{
-					Category:   r.expensePatterns[pattern],
-					Confidence: 0.85,
-					Method:     "rules",
-				}
-			}
-		}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.packs[name]; !ok {
+		return fmt.Errorf("rule pack %q not found", name)
+	}
+	delete(r.packs, name)
+	r.rebuild()
+	return nil
+}
 
-		// General expenses
-		expenseKeywords := []string{"POS", "ATM", "WITHDRAWAL", "TRANSFER", "PAYMENT", "PURCHASE"}
-		for _, keyword := range expenseKeywords {
-			if strings.Contains(upperDesc, keyword) {
-				return model.ClassificationResult{
-					Category:   model.CategoryExpense,
-					Confidence: 0.70,
-					Method:     "rules",
-				}
-			}
-		}
+// Pack returns an installed pack by name
+func (r *RuleEngine) Pack(name string) (RulePack, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pack, ok := r.packs[name]
+	return pack, ok
+}
+
+// Packs returns the installed packs ordered by name
+func (r *RuleEngine) Packs() []RulePack {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	packs := make([]RulePack, 0, len(r.packs))
+	for _, pack := range r.packs {
+		packs = append(packs, pack)
 	}
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Name < packs[j].Name
+	})
+	return packs
+}
 
-	// Check for transfers (could be income or expense)
-	transferPatterns := regexp.MustCompile(`(?i)(NIP|TRANSFER|TRF)`)
-	if transferPatterns.MatchString(description) {
-		if txType == "credit" {
-			return model.ClassificationResult{
-				Category:   model.CategoryUncategorized,
-				Confidence: 0.50,
-				Method:     "rules",
-			}
-		}
-		return model.ClassificationResult{
-			Category:   model.CategoryTransfer,
-			Confidence: 0.60,
-			Method:     "rules",
-		}
+// WithPack returns a copy of the engine with the pack added or replaced,
+// leaving this engine untouched. It is used to preview rule changes.
+func (r *RuleEngine) WithPack(pack RulePack) (*RuleEngine, error) {
+	clone := &RuleEngine{packs: make(map[string]RulePack)}
+	for _, existing := range r.Packs() {
+		clone.packs[existing.Name] = existing
+	}
+	if err := clone.AddPack(pack); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// rebuild recompiles the merged rule list. Callers must hold the write lock.
+func (r *RuleEngine) rebuild() {
+	var rules []compiledRule
+	for _, pack := range r.packs {
+		compiled, _ := compilePack(pack) // Validated when the pack was added
+		rules = append(rules, compiled...)
 	}
+	sortRules(rules)
+	r.rules = rules
+
+	names := make([]string, 0, len(r.packs))
+	for name := range r.packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		packJSON, _ := json.Marshal(r.packs[name])
+		hash.Write(packJSON)
+	}
+	r.fingerprint = hex.EncodeToString(hash.Sum(nil))[:16]
+}
 
-	return model.ClassificationResult{
+// Fingerprint identifies the installed packs and their rules. It changes
+// whenever a pack is added, replaced or removed.
+func (r *RuleEngine) Fingerprint() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fingerprint
+}
+
+// Classify attempts to classify a transaction based on its description.
+// Rules with a minimum amount never match, as no amount is known.
+func (r *RuleEngine) Classify(description string, txType string) model.ClassificationResult {
+	return r.ClassifyTransaction(model.ParsedTransaction{Description: description, Type: txType})
+}
+
+// ClassifyTransaction classifies a transaction using the first matching rule,
+// explaining the match. Categories of later matching rules are offered as
+// alternatives. Narration fields are parsed from the description when not
+// already set.
+func (r *RuleEngine) ClassifyTransaction(tx model.ParsedTransaction) model.ClassificationResult {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tx = parser.EnrichNarration(tx)
+	n := newNarration(tx)
+	result := model.ClassificationResult{
 		Category:   model.CategoryUncategorized,
 		Confidence: 0.0,
 		Method:     "rules",
 	}
+	var others []model.Alternative
+	for i := range r.rules {
+		rule := &r.rules[i]
+		span, ok := rule.applies(tx, n)
+		if !ok {
+			continue
+		}
+		if result.Rule == "" {
+			result.Category = rule.Category
+			result.Confidence = rule.Confidence
+			result.Rule = rule.pack + "/" + rule.ID
+			result.Explanation = ruleExplanation(rule, span, n)
+			continue
+		}
+		others = append(others, model.Alternative{Category: rule.Category, Score: rule.Confidence})
+	}
+	if result.Explanation == nil {
+		result.Explanation = &model.Explanation{Summary: "No rule matched"}
+	}
+	result.Alternatives = rankAlternatives(result.Category, others)
+	return result
 }
@@ -0,0 +1,66 @@
+package income
+
+import (
+	"math"
+	"sort"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// Sources summarises who a user's income came from: each payer's total
+// income, its share of all income and, when the payer pays regularly, the
+// kind and cadence of its largest stream. Credits whose payer cannot be
+// identified are grouped under "Other". Largest total first.
+func Sources(transactions []model.Transaction, streams []model.IncomeStream) []model.IncomeSource {
+	type payer struct {
+		source     model.IncomeSource
+		categories map[model.Category]float64
+	}
+	byPayer := make(map[string]*payer)
+	var order []string
+	var total float64
+	for _, tx := range transactions {
+		if tx.TransactionType != "credit" || !tx.Category.IsIncome() {
+			continue
+		}
+		key := PayerKey(tx)
+		if key == "" {
+			key = "Other"
+		}
+		p, ok := byPayer[key]
+		if !ok {
+			p = &payer{source: model.IncomeSource{Payer: key}, categories: make(map[model.Category]float64)}
+			byPayer[key] = p
+			order = append(order, key)
+		}
+		p.source.Count++
+		p.source.Total += tx.Amount
+		p.categories[tx.Category] += tx.Amount
+		total += tx.Amount
+	}
+
+	// Streams are largest first, so the first seen for a payer is its largest
+	for _, stream := range streams {
+		if p, ok := byPayer[stream.Payer]; ok && p.source.Kind == "" {
+			p.source.Kind = stream.Kind
+			p.source.Cadence = stream.Cadence
+		}
+	}
+
+	sources := make([]model.IncomeSource, 0, len(order))
+	for _, key := range order {
+		p := byPayer[key]
+		for _, category := range model.Categories {
+			if p.categories[category] > p.categories[p.source.Category] {
+				p.source.Category = category
+			}
+		}
+		p.source.Treatment = p.source.Category.Treatment()
+		if total > 0 {
+			p.source.Share = math.Round(p.source.Total/total*10000) / 10000
+		}
+		sources = append(sources, p.source)
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Total > sources[j].Total })
+	return sources
+}
@@ -0,0 +1,37 @@
+package income
+
+import (
+	"testing"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestSources(t *testing.T) {
+	start := time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC)
+	txs := payments("ACME LTD", model.CategoryEmployment, start, 30, 3, 300_000)
+	txs = append(txs,
+		model.Transaction{TransactionDate: start, Description: "UPWORK ESCROW", Amount: 200_000, TransactionType: "credit", Category: model.CategoryFreelanceForeign},
+		model.Transaction{TransactionDate: start, Description: "BIRTHDAY GIFT FROM MUM", Amount: 100_000, TransactionType: "credit", Category: model.CategoryGift, Narration: model.Narration{Counterparty: "ADA OKAFOR"}},
+		model.Transaction{TransactionDate: start, Description: "CARBON LOAN", Amount: 500_000, TransactionType: "credit", Category: model.CategoryLoan},
+		model.Transaction{TransactionDate: start, Description: "SHOPRITE", Amount: 20_000, TransactionType: "debit", Category: model.CategoryExpense},
+	)
+
+	sources := Sources(txs, FindStreams(txs))
+	if len(sources) != 3 {
+		t.Fatalf("Sources() = %+v, want the employer, the client and the gift", sources)
+	}
+	acme := sources[0]
+	if acme.Payer != "ACME LTD" || acme.Total != 900_000 || acme.Count != 3 || acme.Kind != model.StreamSalary || acme.Cadence != model.CadenceMonthly {
+		t.Errorf("first source = %+v, want ACME's monthly salary", acme)
+	}
+	if acme.Share != 0.75 || acme.Treatment != model.TreatmentTaxable {
+		t.Errorf("ACME share %.4f, treatment %s, want 0.75 taxable", acme.Share, acme.Treatment)
+	}
+	if sources[1].Payer != "UPWORK ESCROW" || sources[1].Kind != "" {
+		t.Errorf("second source = %+v, want the one-off Upwork payment", sources[1])
+	}
+	if sources[2].Treatment != model.TreatmentExempt {
+		t.Errorf("gift treatment = %s, want exempt", sources[2].Treatment)
+	}
+}
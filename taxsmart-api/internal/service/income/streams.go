@@ -0,0 +1,237 @@
+// Package income profiles where a user's money comes from: the payers who pay
+// them, and the regular streams, such as a salary or a retainer, that their
+// payments form.
+package income
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/service/classifier"
+)
+
+// MinStreamSize is the fewest payments that make a stream
+const MinStreamSize = 3
+
+// AmountTolerance is how far, as a fraction of the smallest, payments in a stream may differ
+const AmountTolerance = 0.25
+
+// StreamBoost is the share of the remaining doubt a fully regular stream
+// removes from the confidence of its members' classifications
+const StreamBoost = 0.5
+
+// minRegularity is the fraction of a stream's gaps that must match its cadence
+const minRegularity = 0.6
+
+// cadences are the intervals streams are recognised at, with the slack allowed on each gap
+var cadences = []struct {
+	name  string
+	days  float64
+	slack float64
+}{
+	{model.CadenceWeekly, 7, 2},
+	{model.CadenceBiweekly, 14, 3},
+	{model.CadenceMonthly, 30.4, 7},
+	{model.CadenceQuarterly, 91, 15},
+}
+
+// salaryWords mark a payment as pay from an employer
+var salaryWords = map[string]bool{"SALARY": true, "SAL": true, "PAYROLL": true, "WAGES": true, "WAGE": true}
+
+// PayerKey identifies who paid a transaction: its counterparty when the
+// narration names one, otherwise the identifying words of its description.
+// It is empty when nothing identifies the payer.
+func PayerKey(tx model.Transaction) string {
+	if tx.Counterparty != "" {
+		return tx.Counterparty
+	}
+	return strings.Join(classifier.NormaliseNarration(tx.Description), " ")
+}
+
+// isPayment reports whether a credit can belong to an income stream.
+// Reversals, refunds, loans and the owner's own transfers never do.
+func isPayment(tx model.Transaction) bool {
+	if tx.TransactionType != "credit" || tx.Amount <= 0 || tx.TransactionDate.IsZero() {
+		return false
+	}
+	return !tx.Category.IsA(model.CategoryTransfer) || tx.Category == model.CategoryTransfer
+}
+
+// FindStreams clusters a user's credits by payer, amount and cadence into
+// income streams, largest total first. Each payer's credits are split into
+// runs of similar amounts, and a run becomes a stream when it has at least
+// MinStreamSize payments whose gaps mostly match one cadence.
+func FindStreams(transactions []model.Transaction) []model.IncomeStream {
+	byPayer := make(map[string][]model.Transaction)
+	for _, tx := range transactions {
+		if !isPayment(tx) {
+			continue
+		}
+		if key := PayerKey(tx); key != "" {
+			byPayer[key] = append(byPayer[key], tx)
+		}
+	}
+
+	var streams []model.IncomeStream
+	for payer, payments := range byPayer {
+		for _, run := range amountRuns(payments) {
+			if stream, ok := newStream(payer, run); ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		if streams[i].Total != streams[j].Total {
+			return streams[i].Total > streams[j].Total
+		}
+		return streams[i].Payer < streams[j].Payer
+	})
+	return streams
+}
+
+// amountRuns splits payments into runs whose amounts are within
+// AmountTolerance of the run's smallest
+func amountRuns(payments []model.Transaction) [][]model.Transaction {
+	if len(payments) < MinStreamSize {
+		return nil
+	}
+	sorted := append([]model.Transaction(nil), payments...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Amount < sorted[j].Amount })
+
+	var runs [][]model.Transaction
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i == len(sorted) || sorted[i].Amount > sorted[start].Amount*(1+AmountTolerance) {
+			if i-start >= MinStreamSize {
+				runs = append(runs, sorted[start:i])
+			}
+			start = i
+		}
+	}
+	return runs
+}
+
+// newStream builds a stream from a run of payments if their dates keep to a cadence
+func newStream(payer string, run []model.Transaction) (model.IncomeStream, bool) {
+	sort.Slice(run, func(i, j int) bool { return run[i].TransactionDate.Before(run[j].TransactionDate) })
+
+	gaps := make([]float64, len(run)-1)
+	for i := 1; i < len(run); i++ {
+		gaps[i-1] = run[i].TransactionDate.Sub(run[i-1].TransactionDate).Hours() / 24
+	}
+	cadence, regularity := fitCadence(gaps)
+	if cadence == "" || regularity < minRegularity {
+		return model.IncomeStream{}, false
+	}
+
+	stream := model.IncomeStream{
+		Payer:          payer,
+		Cadence:        cadence,
+		Count:          len(run),
+		FirstDate:      run[0].TransactionDate,
+		LastDate:       run[len(run)-1].TransactionDate,
+		Confidence:     math.Round(regularity*(1-1/float64(len(run)))*100) / 100,
+		TransactionIDs: make([]uuid.UUID, len(run)),
+	}
+	counts := make(map[model.Category]int)
+	salary := false
+	for i, tx := range run {
+		stream.Total += tx.Amount
+		stream.TransactionIDs[i] = tx.ID
+		if tx.Category != model.CategoryUncategorized {
+			counts[tx.Category]++
+		}
+		for _, word := range classifier.NormaliseNarration(tx.Description) {
+			salary = salary || salaryWords[word]
+		}
+	}
+	stream.AverageAmount = math.Round(stream.Total/float64(len(run))*100) / 100
+	stream.Category = mostCommon(counts)
+
+	switch {
+	case stream.Category.IsA(model.CategoryEmployment) || salary:
+		stream.Kind = model.StreamSalary
+	case stream.Category.IsA(model.CategoryFreelance) && cadence == model.CadenceMonthly:
+		stream.Kind = model.StreamRetainer
+	default:
+		stream.Kind = model.StreamRecurring
+	}
+	return stream, true
+}
+
+// fitCadence picks the cadence nearest the median gap and returns the
+// fraction of gaps within its slack, or "" when no cadence is near
+func fitCadence(gaps []float64) (string, float64) {
+	sorted := append([]float64(nil), gaps...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	for _, c := range cadences {
+		if math.Abs(median-c.days) > c.slack {
+			continue
+		}
+		regular := 0
+		for _, gap := range gaps {
+			if math.Abs(gap-c.days) <= c.slack {
+				regular++
+			}
+		}
+		return c.name, float64(regular) / float64(len(gaps))
+	}
+	return "", 0
+}
+
+// mostCommon returns the category counted most often, earliest in the
+// taxonomy on a tie, or uncategorized when none were counted
+func mostCommon(counts map[model.Category]int) model.Category {
+	best := model.CategoryUncategorized
+	for _, category := range model.Categories {
+		if counts[category] > counts[best] {
+			best = category
+		}
+	}
+	return best
+}
+
+// Boost raises the confidence of classifications whose transactions belong
+// to a stream and agree with its category, removing up to StreamBoost of
+// the remaining doubt in proportion to the stream's confidence, and notes
+// the stream in their explanations. results are aligned with transactions,
+// and both are updated. It returns the number boosted.
+func Boost(transactions []model.Transaction, results []model.ClassificationResult, streams []model.IncomeStream) int {
+	members := make(map[uuid.UUID]*model.IncomeStream)
+	for i := range streams {
+		for _, id := range streams[i].TransactionIDs {
+			members[id] = &streams[i]
+		}
+	}
+
+	boosted := 0
+	for i := range transactions {
+		stream, ok := members[transactions[i].ID]
+		if !ok || results[i].Category != stream.Category || !stream.Category.IsIncome() {
+			continue
+		}
+		confidence := results[i].Confidence + (1-results[i].Confidence)*StreamBoost*stream.Confidence
+		results[i].Confidence = math.Round(confidence*1000) / 1000
+		transactions[i].Confidence = results[i].Confidence
+
+		note := fmt.Sprintf("one of %d %s %s payments from %s", stream.Count, stream.Cadence, stream.Kind, stream.Payer)
+		if results[i].Explanation == nil {
+			results[i].Explanation = &model.Explanation{Summary: "Classified as " + string(stream.Category) + ", " + note}
+		} else {
+			explanation := *results[i].Explanation
+			explanation.Summary += "; " + note
+			results[i].Explanation = &explanation
+		}
+		boosted++
+	}
+	return boosted
+}
@@ -0,0 +1,119 @@
+package income
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// payments returns n credits from a payer, days apart, with amounts cycling through amounts
+func payments(payer string, category model.Category, start time.Time, days, n int, amounts ...float64) []model.Transaction {
+	txs := make([]model.Transaction, n)
+	for i := range txs {
+		txs[i] = model.Transaction{
+			ID:              uuid.New(),
+			TransactionDate: start.AddDate(0, 0, i*days),
+			Description:     "NIP/" + payer,
+			Amount:          amounts[i%len(amounts)],
+			TransactionType: "credit",
+			Category:        category,
+			Confidence:      0.6,
+			Narration:       model.Narration{Counterparty: payer},
+		}
+	}
+	return txs
+}
+
+func TestFindStreams(t *testing.T) {
+	jan := time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		transactions []model.Transaction
+		kind         string
+		cadence      string
+		count        int
+	}{
+		{"monthly salary", payments("ACME LTD", model.CategoryEmployment, jan, 30, 6, 450_000, 452_500), model.StreamSalary, model.CadenceMonthly, 6},
+		{"monthly retainer", payments("BRIGHT STUDIOS", model.CategoryFreelanceForeign, jan, 31, 4, 300_000), model.StreamRetainer, model.CadenceMonthly, 4},
+		{"salary named in an uncategorized narration", func() []model.Transaction {
+			txs := payments("GLOBEX", model.CategoryUncategorized, jan, 30, 3, 200_000)
+			for i := range txs {
+				txs[i].Description = "GLOBEX SALARY"
+			}
+			return txs
+		}(), model.StreamSalary, model.CadenceMonthly, 3},
+		{"weekly payouts", payments("BOLT", model.CategoryFreelance, jan, 7, 5, 40_000, 46_000), model.StreamRecurring, model.CadenceWeekly, 5},
+		{"too few payments", payments("ACME LTD", model.CategoryEmployment, jan, 30, 2, 450_000), "", "", 0},
+		{"irregular dates", func() []model.Transaction {
+			txs := payments("TUNDE", model.CategoryOtherIncome, jan, 30, 4, 50_000)
+			txs[1].TransactionDate = jan.AddDate(0, 0, 3)
+			txs[2].TransactionDate = jan.AddDate(0, 0, 100)
+			return txs
+		}(), "", "", 0},
+		{"amounts too far apart", payments("TUNDE", model.CategoryOtherIncome, jan, 30, 4, 10_000, 90_000), "", "", 0},
+		{"reversals are not payments", payments("PAYSTACK", model.CategoryReversal, jan, 30, 4, 5_000), "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := FindStreams(tt.transactions)
+			if tt.count == 0 {
+				if len(streams) != 0 {
+					t.Fatalf("FindStreams() = %+v, want none", streams)
+				}
+				return
+			}
+			if len(streams) != 1 {
+				t.Fatalf("FindStreams() = %+v, want one stream", streams)
+			}
+			s := streams[0]
+			if s.Kind != tt.kind || s.Cadence != tt.cadence || s.Count != tt.count || len(s.TransactionIDs) != tt.count {
+				t.Errorf("stream = %s %s of %d, want %s %s of %d", s.Cadence, s.Kind, s.Count, tt.cadence, tt.kind, tt.count)
+			}
+			if s.Confidence <= 0 || s.Confidence > 1 {
+				t.Errorf("Confidence = %.2f, want within (0, 1]", s.Confidence)
+			}
+		})
+	}
+}
+
+func TestFindStreams_SplitsPayerByAmount(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	txs := append(
+		payments("ACME LTD", model.CategoryEmployment, start, 30, 4, 400_000),
+		payments("ACME LTD", model.CategoryEmployment, start.AddDate(0, 0, 10), 30, 4, 60_000)...,
+	)
+
+	streams := FindStreams(txs)
+	if len(streams) != 2 || streams[0].AverageAmount != 400_000 || streams[1].AverageAmount != 60_000 {
+		t.Fatalf("FindStreams() = %+v, want the salary and the allowance, largest first", streams)
+	}
+}
+
+func TestBoost(t *testing.T) {
+	txs := payments("ACME LTD", model.CategoryEmployment, time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC), 30, 6, 450_000)
+	txs = append(txs, model.Transaction{ID: uuid.New(), Description: "POS REFUND", Amount: 450_000, TransactionType: "credit"})
+	results := make([]model.ClassificationResult, len(txs))
+	for i, tx := range txs {
+		results[i] = model.ClassificationResult{Category: tx.Category, Confidence: 0.6}
+	}
+	// A member the classifier put elsewhere is left alone
+	results[1].Category = model.CategoryFreelance
+
+	boosted := Boost(txs, results, FindStreams(txs))
+	if boosted != 5 {
+		t.Fatalf("Boost() = %d, want the 5 members that agree with the stream", boosted)
+	}
+	if results[0].Confidence <= 0.6 || results[0].Confidence > 1 || txs[0].Confidence != results[0].Confidence {
+		t.Errorf("member confidence = %.3f (transaction %.3f), want raised above 0.6 on both", results[0].Confidence, txs[0].Confidence)
+	}
+	if results[0].Explanation == nil || results[0].Explanation.Summary == "" {
+		t.Errorf("member explanation = %+v, want the stream noted", results[0].Explanation)
+	}
+	if results[1].Confidence != 0.6 || results[6].Confidence != 0.6 {
+		t.Errorf("non-members boosted: %.3f, %.3f", results[1].Confidence, results[6].Confidence)
+	}
+}
@@ -0,0 +1,313 @@
+// Package jobs runs statement uploads in the background. Each job is parsed,
+// classified and used to calculate a tax report by a pool of workers, with
+// its progress stored after every step so it can be polled, watched live,
+// canceled, and resumed after a restart.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+)
+
+// ErrQueueFull is returned when a job is submitted while every queue slot is taken
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrJobFinished is returned when canceling a job that has already finished
+var ErrJobFinished = errors.New("job has already finished")
+
+// errCanceled is the cause of a running job's context when it is canceled
+var errCanceled = errors.New("job canceled")
+
+// Each stage's share of a job's progress, in percent
+const (
+	parseShare    = 10
+	classifyShare = 80
+)
+
+// Pipeline does the work of each stage. Stages are only run again on resume
+// until they have recorded their result on the job: Classify sets UploadID,
+// and Calculate sets ReportID when the job has a tax year.
+type Pipeline interface {
+	// Parse reads the job's input into transactions, setting its bank format.
+	// Queued jobs do not carry their input, so it is loaded from the repository.
+	Parse(ctx context.Context, job *model.Job) ([]model.ParsedTransaction, error)
+	// Classify classifies and stores the transactions as an upload, calling
+	// progress with the number classified so far
+	Classify(ctx context.Context, job *model.Job, transactions []model.ParsedTransaction, progress func(done int)) error
+	// Calculate calculates and stores the report for the job's tax year
+	Calculate(ctx context.Context, job *model.Job) error
+	// Finished is called once the job has succeeded, failed or been canceled
+	Finished(ctx context.Context, job model.Job)
+}
+
+// Runner runs jobs on a pool of workers
+type Runner struct {
+	repo     repository.JobRepository
+	pipeline Pipeline
+	workers  int
+	queue    chan model.Job
+
+	mu       sync.Mutex
+	cancels  map[uuid.UUID]context.CancelCauseFunc // Running jobs
+	canceled map[uuid.UUID]bool                    // Queued jobs canceled before a worker took them
+	watchers map[uuid.UUID][]chan model.Job
+}
+
+// NewRunner creates a runner with the given number of workers and queue slots
+func NewRunner(repo repository.JobRepository, pipeline Pipeline, workers, queueSize int) *Runner {
+	return &Runner{
+		repo:     repo,
+		pipeline: pipeline,
+		workers:  max(workers, 1),
+		queue:    make(chan model.Job, max(queueSize, 1)),
+		cancels:  make(map[uuid.UUID]context.CancelCauseFunc),
+		canceled: make(map[uuid.UUID]bool),
+		watchers: make(map[uuid.UUID][]chan model.Job),
+	}
+}
+
+// Start starts the workers and queues the jobs left unfinished by the last
+// run. Workers stop when ctx is done, leaving their jobs to be resumed.
+func (r *Runner) Start(ctx context.Context) error {
+	unfinished, err := r.repo.ListUnfinishedJobs(ctx)
+	if err != nil {
+		return err
+	}
+	for range r.workers {
+		go r.work(ctx)
+	}
+	go func() {
+		for _, job := range unfinished {
+			select {
+			case r.queue <- *job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Submit stores a new job and queues it without its input, which Parse
+// loads when a worker takes the job
+func (r *Runner) Submit(ctx context.Context, job *model.Job) error {
+	now := time.Now()
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	job.Status, job.Stage, job.CreatedAt, job.UpdatedAt = model.JobQueued, model.StageParse, now, now
+	if err := r.repo.CreateJob(ctx, job); err != nil {
+		return err
+	}
+
+	queued := *job
+	queued.Input = nil
+	select {
+	case r.queue <- queued:
+		return nil
+	default:
+		job.Status, job.Error, job.FinishedAt = model.JobFailed, ErrQueueFull.Error(), &now
+		if err := r.repo.UpdateJob(ctx, job); err != nil {
+			return err
+		}
+		return ErrQueueFull
+	}
+}
+
+// Cancel stops one of the user's jobs. A running job stops at its next step.
+func (r *Runner) Cancel(ctx context.Context, userID, id uuid.UUID) (*model.Job, error) {
+	job, queued, err := r.cancel(ctx, userID, id)
+	if err != nil {
+		return job, err
+	}
+	// Called after unlocking, as Finished may be slow or use the runner
+	if queued {
+		r.pipeline.Finished(ctx, *job)
+	}
+	return job, nil
+}
+
+// cancel cancels the job under the lock, reporting whether it was still
+// queued and so is finished by the cancellation itself
+func (r *Runner) cancel(ctx context.Context, userID, id uuid.UUID) (*model.Job, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Loaded under the lock so a worker cannot take or finish the job meanwhile
+	job, err := r.repo.GetJob(ctx, userID, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if job.Finished() {
+		return job, false, ErrJobFinished
+	}
+	if cancel, ok := r.cancels[id]; ok {
+		cancel(errCanceled)
+		return job, false, nil
+	}
+	now := time.Now()
+	job.Status, job.UpdatedAt, job.FinishedAt = model.JobCanceled, now, &now
+	if err := r.repo.UpdateJob(ctx, job); err != nil {
+		return nil, false, err
+	}
+	r.canceled[id] = true
+	r.notify(*job)
+	r.closeWatchers(id)
+	return job, true, nil
+}
+
+// Watch returns a channel receiving the job's state after each step, closed
+// when the job finishes, and a function to stop watching. Updates are dropped
+// while the channel is full, so read the final state from the repository.
+func (r *Runner) Watch(id uuid.UUID) (<-chan model.Job, func()) {
+	ch := make(chan model.Job, 16)
+	r.mu.Lock()
+	r.watchers[id] = append(r.watchers[id], ch)
+	r.mu.Unlock()
+
+	return ch, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		watchers := r.watchers[id]
+		for i, watcher := range watchers {
+			if watcher == ch {
+				r.watchers[id] = append(watchers[:i], watchers[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(r.watchers[id]) == 0 {
+			delete(r.watchers, id)
+		}
+	}
+}
+
+// notify sends the job's state to its watchers. r.mu must be held.
+func (r *Runner) notify(job model.Job) {
+	for _, ch := range r.watchers[job.ID] {
+		select {
+		case ch <- job:
+		default:
+		}
+	}
+}
+
+// closeWatchers closes and forgets the job's watchers. r.mu must be held.
+func (r *Runner) closeWatchers(id uuid.UUID) {
+	for _, ch := range r.watchers[id] {
+		close(ch)
+	}
+	delete(r.watchers, id)
+}
+
+// work runs queued jobs until ctx is done
+func (r *Runner) work(ctx context.Context) {
+	for {
+		select {
+		case job := <-r.queue:
+			if ctx.Err() != nil {
+				return // Left queued for the next start
+			}
+			r.run(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// run runs a job to the end, or until it is canceled or the runner stops
+func (r *Runner) run(ctx context.Context, job model.Job) {
+	r.mu.Lock()
+	if r.canceled[job.ID] {
+		delete(r.canceled, job.ID)
+		r.mu.Unlock()
+		return
+	}
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	r.cancels[job.ID] = cancel
+	r.mu.Unlock()
+
+	err := r.execute(jobCtx, &job)
+	defer func() {
+		r.mu.Lock()
+		delete(r.cancels, job.ID)
+		if job.Finished() {
+			r.closeWatchers(job.ID)
+		}
+		r.mu.Unlock()
+		cancel(nil)
+	}()
+
+	switch {
+	case err == nil:
+		job.Status, job.Progress = model.JobSucceeded, 100
+	case errors.Is(context.Cause(jobCtx), errCanceled):
+		job.Status = model.JobCanceled
+	case ctx.Err() != nil:
+		// The runner is stopping; the job stays unfinished and is resumed on the next start
+		return
+	default:
+		job.Status, job.Error = model.JobFailed, err.Error()
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	r.save(context.WithoutCancel(ctx), &job)
+	r.pipeline.Finished(context.WithoutCancel(ctx), job)
+}
+
+// execute runs the stages the job has not completed
+func (r *Runner) execute(ctx context.Context, job *model.Job) error {
+	job.Status = model.JobRunning
+	if job.UploadID == nil {
+		job.Stage, job.Progress, job.Processed = model.StageParse, 0, 0
+		if err := r.save(ctx, job); err != nil {
+			return err
+		}
+		transactions, err := r.pipeline.Parse(ctx, job)
+		if err != nil {
+			return err
+		}
+
+		job.Stage, job.Progress, job.Total = model.StageClassify, parseShare, len(transactions)
+		if err := r.save(ctx, job); err != nil {
+			return err
+		}
+		progress := func(done int) {
+			job.Processed = done
+			if job.Total > 0 {
+				job.Progress = parseShare + classifyShare*done/job.Total
+			}
+			r.save(ctx, job)
+		}
+		if err := r.pipeline.Classify(ctx, job, transactions, progress); err != nil {
+			return err
+		}
+	}
+
+	job.Stage, job.Progress = model.StageCalculate, parseShare+classifyShare
+	if err := r.save(ctx, job); err != nil {
+		return err
+	}
+	if job.ReportID == nil {
+		return r.pipeline.Calculate(ctx, job)
+	}
+	return nil
+}
+
+// save stores the job's state and sends it to its watchers
+func (r *Runner) save(ctx context.Context, job *model.Job) error {
+	job.UpdatedAt = time.Now()
+	if err := r.repo.UpdateJob(ctx, job); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.notify(*job)
+	r.mu.Unlock()
+	return nil
+}
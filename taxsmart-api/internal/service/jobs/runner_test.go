@@ -0,0 +1,324 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+)
+
+// fakePipeline records the stages it runs and the jobs it sees finish.
+// Classify waits for started to be received from, then for release or the
+// job's cancellation, when they are set. Finished calls onFinish when set.
+type fakePipeline struct {
+	mu       sync.Mutex
+	stages   []string
+	finished map[uuid.UUID]string
+	parseErr error
+	started  chan struct{}
+	release  chan struct{}
+	onFinish func(job model.Job)
+}
+
+func (p *fakePipeline) record(stage string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stages = append(p.stages, stage)
+}
+
+func (p *fakePipeline) ran() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.stages)
+}
+
+func (p *fakePipeline) Parse(ctx context.Context, job *model.Job) ([]model.ParsedTransaction, error) {
+	p.record(model.StageParse)
+	if p.parseErr != nil {
+		return nil, p.parseErr
+	}
+	job.BankFormat = "gtbank"
+	return make([]model.ParsedTransaction, 4), nil
+}
+
+func (p *fakePipeline) Classify(ctx context.Context, job *model.Job, transactions []model.ParsedTransaction, progress func(done int)) error {
+	p.record(model.StageClassify)
+	if p.started != nil {
+		select {
+		case p.started <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for done := 2; done <= len(transactions); done += 2 {
+		progress(done)
+	}
+	job.UploadID = &job.ID
+	return nil
+}
+
+func (p *fakePipeline) Calculate(ctx context.Context, job *model.Job) error {
+	p.record(model.StageCalculate)
+	reportID := uuid.New()
+	job.ReportID = &reportID
+	return nil
+}
+
+func (p *fakePipeline) Finished(ctx context.Context, job model.Job) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished == nil {
+		p.finished = make(map[uuid.UUID]string)
+	}
+	p.finished[job.ID] = job.Status
+	if p.onFinish != nil {
+		p.onFinish(job)
+	}
+}
+
+// finishedAs returns the status the job was reported finished with
+func (p *fakePipeline) finishedAs(id uuid.UUID) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.finished[id]
+}
+
+// waitFinished polls until the job has finished
+func waitFinished(t *testing.T, repo *memory.Store, job *model.Job) *model.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		got, err := repo.GetJob(context.Background(), job.UserID, job.ID)
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		if got.Finished() {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", job.ID)
+	return nil
+}
+
+func TestRunner_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		parseErr error
+		status   string
+		stages   []string
+	}{
+		{"all stages", nil, model.JobSucceeded, []string{model.StageParse, model.StageClassify, model.StageCalculate}},
+		{"parse fails", errors.New("failed to parse CSV: no header"), model.JobFailed, []string{model.StageParse}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			repo := memory.New()
+			pipeline := &fakePipeline{parseErr: tt.parseErr, started: make(chan struct{}), release: make(chan struct{})}
+			runner := NewRunner(repo, pipeline, 2, 10)
+			if err := runner.Start(ctx); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			job := &model.Job{ID: uuid.New(), UserID: uuid.New(), Filename: "jan.csv", Input: []byte("csv")}
+			updates, _ := runner.Watch(job.ID)
+			if err := runner.Submit(ctx, job); err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+			if tt.parseErr == nil {
+				<-pipeline.started
+				close(pipeline.release)
+			}
+
+			var progress []int
+			for update := range updates {
+				progress = append(progress, update.Progress)
+			}
+			got := waitFinished(t, repo, job)
+			if got.Status != tt.status || !slices.Equal(pipeline.ran(), tt.stages) {
+				t.Fatalf("job %s after %v, want %s after %v", got.Status, pipeline.ran(), tt.status, tt.stages)
+			}
+			if len(got.Input) != 0 {
+				t.Errorf("finished job kept its input")
+			}
+			if status := pipeline.finishedAs(job.ID); status != tt.status {
+				t.Errorf("reported finished as %q, want %q", status, tt.status)
+			}
+			if tt.parseErr != nil {
+				if got.Error != tt.parseErr.Error() {
+					t.Errorf("Error = %q, want %q", got.Error, tt.parseErr)
+				}
+				return
+			}
+			if got.Progress != 100 || got.Processed != 4 || got.Total != 4 || got.UploadID == nil || got.ReportID == nil || got.BankFormat != "gtbank" {
+				t.Errorf("finished job = %+v", got)
+			}
+			if !slices.IsSorted(progress) || !slices.Contains(progress, 50) || progress[len(progress)-1] != 100 {
+				t.Errorf("watched progress = %v, want rising through classification to 100", progress)
+			}
+		})
+	}
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := memory.New()
+	pipeline := &fakePipeline{started: make(chan struct{}), release: make(chan struct{})}
+	runner := NewRunner(repo, pipeline, 1, 10)
+	// Finished is called without the runner's lock held, so it may use the runner
+	pipeline.onFinish = func(job model.Job) {
+		_, stop := runner.Watch(job.ID)
+		stop()
+	}
+	if err := runner.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	userID := uuid.New()
+	running := &model.Job{UserID: userID, Filename: "jan.csv"}
+	queued := &model.Job{UserID: userID, Filename: "feb.csv"}
+	for _, job := range []*model.Job{running, queued} {
+		if err := runner.Submit(ctx, job); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+	<-pipeline.started
+
+	// The only worker is busy, so the second job is still queued
+	if got, err := runner.Cancel(ctx, userID, queued.ID); err != nil || got.Status != model.JobCanceled {
+		t.Fatalf("Cancel(queued) = %+v, %v", got, err)
+	}
+	if _, err := runner.Cancel(ctx, uuid.New(), running.ID); err == nil {
+		t.Errorf("Cancel succeeded for another user's job")
+	}
+	if _, err := runner.Cancel(ctx, userID, running.ID); err != nil {
+		t.Fatalf("Cancel(running) failed: %v", err)
+	}
+
+	if got := waitFinished(t, repo, running); got.Status != model.JobCanceled || got.UploadID != nil {
+		t.Errorf("running job = %s with upload %v, want canceled before storing", got.Status, got.UploadID)
+	}
+	if _, err := runner.Cancel(ctx, userID, running.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel(finished) = %v, want ErrJobFinished", err)
+	}
+
+	// The canceled job is skipped when the worker reaches it
+	close(pipeline.release)
+	follow := &model.Job{UserID: userID, Filename: "mar.csv"}
+	if err := runner.Submit(ctx, follow); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-pipeline.started
+	waitFinished(t, repo, follow)
+	// The only worker has moved on, so it has reported the canceled job
+	for _, job := range []*model.Job{running, queued} {
+		if status := pipeline.finishedAs(job.ID); status != model.JobCanceled {
+			t.Errorf("job %s reported finished as %q, want canceled", job.Filename, status)
+		}
+	}
+	if got := pipeline.ran(); len(got) != 2+3 {
+		t.Errorf("stages run = %v, want the canceled jobs' then one full job", got)
+	}
+}
+
+func TestRunner_Resume(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	userID := uuid.New()
+	uploadID := uuid.New()
+	created := time.Now().Add(-time.Hour)
+	jobs := []*model.Job{
+		// Interrupted after its upload was stored
+		{ID: uuid.New(), UserID: userID, Status: model.JobRunning, Stage: model.StageCalculate, UploadID: &uploadID, TaxYear: 2026, CreatedAt: created},
+		// Interrupted while classifying
+		{ID: uuid.New(), UserID: userID, Status: model.JobRunning, Stage: model.StageClassify, Input: []byte("csv"), CreatedAt: created.Add(time.Minute)},
+	}
+	for _, job := range jobs {
+		if err := repo.CreateJob(ctx, job); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pipeline := &fakePipeline{}
+	if err := NewRunner(repo, pipeline, 1, 10).Start(runCtx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	for _, job := range jobs {
+		if got := waitFinished(t, repo, job); got.Status != model.JobSucceeded {
+			t.Errorf("resumed job = %+v, want succeeded", got)
+		}
+	}
+	want := []string{model.StageCalculate, model.StageParse, model.StageClassify, model.StageCalculate}
+	if got := pipeline.ran(); !slices.Equal(got, want) {
+		t.Errorf("stages run = %v, want %v", got, want)
+	}
+}
+
+func TestRunner_StopLeavesJobsToResume(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	repo := memory.New()
+	pipeline := &fakePipeline{started: make(chan struct{}), release: make(chan struct{})}
+	runner := NewRunner(repo, pipeline, 1, 1)
+	if err := runner.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	userID := uuid.New()
+	job := &model.Job{UserID: userID, Filename: "jan.csv", Input: []byte("csv")}
+	if err := runner.Submit(ctx, job); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	<-pipeline.started
+	if err := runner.Submit(ctx, &model.Job{UserID: userID, Filename: "feb.csv"}); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if err := runner.Submit(ctx, &model.Job{UserID: userID, Filename: "mar.csv"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit to a full queue = %v, want ErrQueueFull", err)
+	}
+
+	stop()
+	unfinished := func() []*model.Job {
+		jobs, err := repo.ListUnfinishedJobs(context.Background())
+		if err != nil {
+			t.Fatalf("ListUnfinishedJobs failed: %v", err)
+		}
+		return jobs
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		runner.mu.Lock()
+		running := len(runner.cancels)
+		runner.mu.Unlock()
+		if running == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	jobs := unfinished()
+	if len(jobs) != 2 || jobs[0].ID != job.ID || jobs[0].Status != model.JobRunning {
+		t.Fatalf("unfinished jobs = %+v, want the running and queued jobs", jobs)
+	}
+	if stored, err := repo.GetJob(context.Background(), userID, job.ID); err != nil || len(stored.Input) == 0 {
+		t.Errorf("running job = %+v, %v, want its input kept to resume from", stored, err)
+	}
+}
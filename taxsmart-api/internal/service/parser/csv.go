@@ -61,6 +61,7 @@ func (p *CSVParser) Parse(reader io.Reader) ([]model.ParsedTransaction, BankForm
 			continue
 		}
 		if tx.Amount != 0 {
+			tx.Narration = ParseNarration(tx.Description)
 			transactions = append(transactions, tx)
 		}
 		_ = i // Avoid unused variable warning
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// channelTokens maps narration tokens to payment channels
+var channelTokens = map[string]string{
+	"NIP":    model.ChannelNIP,
+	"NIBSS":  model.ChannelNIP,
+	"POS":    model.ChannelPOS,
+	"USSD":   model.ChannelUSSD,
+	"ATM":    model.ChannelATM,
+	"WEB":    model.ChannelWeb,
+	"MOB":    model.ChannelMobile,
+	"MOBILE": model.ChannelMobile,
+}
+
+// bankAliases maps the ways banks appear in narrations to a short code
+var bankAliases = map[string]string{
+	"FBN": "FBN", "FIRSTBANK": "FBN", "FIRST BANK": "FBN",
+	"GTB": "GTB", "GTBANK": "GTB", "GTCO": "GTB", "GUARANTY TRUST BANK": "GTB",
+	"UBA": "UBA",
+	"ZIB": "ZENITH", "ZENITH": "ZENITH", "ZENITH BANK": "ZENITH",
+	"ACCESS": "ACCESS", "ACCESS BANK": "ACCESS",
+	"FCMB":     "FCMB",
+	"FIDELITY": "FIDELITY", "FIDELITY BANK": "FIDELITY",
+	"STANBIC": "STANBIC", "STANBIC IBTC": "STANBIC",
+	"STERLING": "STERLING", "STERLING BANK": "STERLING",
+	"WEMA": "WEMA", "ALAT": "WEMA",
+	"UNION": "UNION", "UNION BANK": "UNION",
+	"ECOBANK":    "ECOBANK",
+	"KEYSTONE":   "KEYSTONE",
+	"POLARIS":    "POLARIS",
+	"OPAY":       "OPAY",
+	"PALMPAY":    "PALMPAY",
+	"KUDA":       "KUDA",
+	"MONIEPOINT": "MONIEPOINT",
+}
+
+// counterpartyMarkers introduce the other party, e.g. "TRF FRM ACME"
+var counterpartyMarkers = map[string]bool{
+	"FRM": true, "FROM": true, "TO": true, "IFO": true, "BO": true, "BY": true,
+}
+
+// counterpartyStops end the other party's name, e.g. "ACME REF 1234" or "ACME FOR RENT"
+var counterpartyStops = map[string]bool{
+	"REF": true, "FOR": true, "SESSION": true, "NARRATION": true,
+}
+
+// transferNoise holds words that say how money moved rather than who sent it
+var transferNoise = map[string]bool{
+	"TRF": true, "TRFR": true, "TRSF": true, "TRANSFER": true, "CR": true, "DR": true,
+	"INWARD": true, "OUTWARD": true, "VIA": true, "UTO": true,
+	"JAN": true, "FEB": true, "MAR": true, "APR": true, "MAY": true, "JUN": true,
+	"JUL": true, "AUG": true, "SEP": true, "SEPT": true, "OCT": true, "NOV": true, "DEC": true,
+	"JANUARY": true, "FEBRUARY": true, "MARCH": true, "APRIL": true, "JUNE": true, "JULY": true,
+	"AUGUST": true, "SEPTEMBER": true, "OCTOBER": true, "NOVEMBER": true, "DECEMBER": true,
+}
+
+// paymentWords describe what a payment was for. They tell kinds of payment
+// apart, so narrations keep them, but are never part of a counterparty's name.
+var paymentWords = map[string]bool{
+	"PURCHASE": true, "WDL": true, "WITHDRAWAL": true, "PAYMENT": true, "PMT": true, "ID": true,
+	"NG": true, "NGN": true, "SALARY": true, "PAYROLL": true, "ALLOWANCE": true, "BONUS": true,
+	"REFUND": true, "REVERSAL": true, "RVSL": true, "REV": true, "CHARGES": true, "CHARGE": true,
+	"FEE": true, "VAT": true, "STAMP": true, "DUTY": true, "AIRTIME": true, "DATA": true, "BILL": true,
+}
+
+var (
+	narrationSegments = regexp.MustCompile(`\s*[/|]\s*|\s+-\s+`)
+	nipSessionPattern = regexp.MustCompile(`(?:^|\D)(\d{30})(?:\D|$)`)
+	refPattern        = regexp.MustCompile(`\b(?:REF|SESSION(?:\s*ID)?)\s*[:#.]?\s*([A-Z0-9]*\d[A-Z0-9]*)`)
+	nubanPattern      = regexp.MustCompile(`(?:^|\D)(\d{10})(?:\D|$)`)
+	cbnBankCode       = regexp.MustCompile(`^\d{3}$`)
+)
+
+// ParseNarration extracts the channel, counterparty, bank, account number and
+// session reference from a bank narration such as
+// "NIP/FBN/JOHN DOE/TRF FRM ACME/0987654321". Parts that cannot be found are
+// left empty.
+func ParseNarration(description string) model.Narration {
+	upper := strings.ToUpper(strings.TrimSpace(description))
+	var n model.Narration
+
+	for _, token := range strings.FieldsFunc(upper, isSeparator) {
+		if channel, ok := channelTokens[token]; ok {
+			n.Channel = channel
+			break
+		}
+	}
+
+	if m := nipSessionPattern.FindStringSubmatch(upper); m != nil {
+		n.SessionRef = m[1]
+	} else if m := refPattern.FindStringSubmatch(upper); m != nil {
+		n.SessionRef = m[1]
+	}
+	if m := nubanPattern.FindStringSubmatch(upper); m != nil {
+		n.AccountNumber = m[1]
+	}
+
+	type candidate struct {
+		index  int
+		name   string
+		marked bool
+	}
+	var candidates []candidate
+	bankIndex := -1
+	for i, segment := range narrationSegments.Split(upper, -1) {
+		segment = strings.Join(strings.Fields(segment), " ")
+		if code, ok := bankAliases[segment]; ok && n.BankCode == "" {
+			n.BankCode, bankIndex = code, i
+			continue
+		}
+		if cbnBankCode.MatchString(segment) && n.BankCode == "" {
+			n.BankCode, bankIndex = segment, i
+			continue
+		}
+		if name, marked := counterpartyIn(segment); name != "" {
+			candidates = append(candidates, candidate{index: i, name: name, marked: marked})
+		}
+	}
+
+	// The name after the bank is the other party in NIP-style narrations;
+	// otherwise prefer an explicit "FROM x" or "TO x"
+	switch {
+	case bankIndex >= 0:
+		for _, c := range candidates {
+			if c.index > bankIndex {
+				n.Counterparty = c.name
+				break
+			}
+		}
+	default:
+		for _, c := range candidates {
+			if c.marked {
+				n.Counterparty = c.name
+				break
+			}
+		}
+	}
+	if n.Counterparty == "" && len(candidates) > 0 {
+		n.Counterparty = candidates[0].name
+	}
+
+	return n
+}
+
+// counterpartyIn returns the name-like words of a narration segment and
+// whether they followed a marker such as "FROM"
+func counterpartyIn(segment string) (string, bool) {
+	words := strings.FieldsFunc(segment, isSeparator)
+
+	marked := false
+	for i, word := range words {
+		if counterpartyMarkers[word] {
+			words, marked = words[i+1:], true
+			break
+		}
+	}
+
+	// A further marker introduces a second party, as in "FROM A TO B"
+	var name []string
+	for _, word := range words {
+		if counterpartyStops[word] || (counterpartyMarkers[word] && len(name) > 0) {
+			break
+		}
+		if IsTransferNoise(word) || paymentWords[word] || strings.ContainsFunc(word, unicode.IsDigit) {
+			continue
+		}
+		name = append(name, word)
+	}
+
+	normalised := NormaliseCounterparty(strings.Join(name, " "))
+	if len(normalised) < 2 {
+		return "", false
+	}
+	return normalised, marked
+}
+
+// IsTransferNoise reports whether an upper-case word is transfer boilerplate,
+// such as a channel, a party marker, "TRF" or a month, which neither names
+// the counterparty nor says what the payment was for
+func IsTransferNoise(word string) bool {
+	_, channel := channelTokens[word]
+	return channel || transferNoise[word] || counterpartyMarkers[word] || counterpartyStops[word]
+}
+
+// NormaliseCounterparty puts a counterparty name in a canonical form so the
+// same party groups together: upper case, single spaces, common suffixes shortened
+func NormaliseCounterparty(name string) string {
+	words := strings.FieldsFunc(strings.ToUpper(name), isSeparator)
+	for i, word := range words {
+		switch word {
+		case "LIMITED":
+			words[i] = "LTD"
+		case "NIGERIA":
+			words[i] = "NIG"
+		case "COMPANY":
+			words[i] = "CO"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// isSeparator reports whether r separates words in a narration
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '&'
+}
+
+// EnrichNarration fills in the narration fields of a parsed transaction
+// that has none yet
+func EnrichNarration(tx model.ParsedTransaction) model.ParsedTransaction {
+	if tx.Narration == (model.Narration{}) {
+		tx.Narration = ParseNarration(tx.Description)
+	}
+	return tx
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestParseNarration(t *testing.T) {
+	tests := []struct {
+		description string
+		expected    model.Narration
+	}{
+		{
+			description: "NIP/FBN/JOHN DOE/TRF FRM ACME/0987654321",
+			expected:    model.Narration{Channel: model.ChannelNIP, Counterparty: "JOHN DOE", BankCode: "FBN", AccountNumber: "0987654321"},
+		},
+		{
+			description: "NIP FRM JOHN DOE-ACME LIMITED REF 000013260612101512345678901234",
+			expected:    model.Narration{Channel: model.ChannelNIP, Counterparty: "JOHN DOE ACME LTD", SessionRef: "000013260612101512345678901234"},
+		},
+		{
+			description: "POS/WEB PURCHASE/SHOPRITE LEKKI/LAGOS NG",
+			expected:    model.Narration{Channel: model.ChannelPOS, Counterparty: "SHOPRITE LEKKI"},
+		},
+		{
+			description: "USSD TRF TO JANE DOE 0123456789",
+			expected:    model.Narration{Channel: model.ChannelUSSD, Counterparty: "JANE DOE", AccountNumber: "0123456789"},
+		},
+		{
+			description: "TRF/058/0123456789/Okafor & Sons",
+			expected:    model.Narration{Counterparty: "OKAFOR & SONS", BankCode: "058", AccountNumber: "0123456789"},
+		},
+		{
+			description: "MOB/UTO/JOHN DOE/Lunch money",
+			expected:    model.Narration{Channel: model.ChannelMobile, Counterparty: "JOHN DOE"},
+		},
+		{
+			description: "SALARY FOR JAN 2026/ACME LTD",
+			expected:    model.Narration{Counterparty: "ACME LTD"},
+		},
+		{
+			description: "ATM WDL/ACCESS ATM IKEJA",
+			expected:    model.Narration{Channel: model.ChannelATM, Counterparty: "ACCESS IKEJA"},
+		},
+		{
+			description: "PAYMENT TO LANDLORD FOR RENT REF:INV20261",
+			expected:    model.Narration{Counterparty: "LANDLORD", SessionRef: "INV20261"},
+		},
+		{
+			description: "TRANSFER FROM CHUKWUEMEKA OKAFOR TO MARY JANE",
+			expected:    model.Narration{Counterparty: "CHUKWUEMEKA OKAFOR"},
+		},
+		{
+			description: "SMS ALERT CHARGES",
+			expected:    model.Narration{Counterparty: "SMS ALERT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			got := ParseNarration(tt.description)
+			if got != tt.expected {
+				t.Errorf("ParseNarration(%q) = %+v, expected %+v", tt.description, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormaliseCounterparty(t *testing.T) {
+	tests := map[string]string{
+		"Acme Limited":            "ACME LTD",
+		"  ACME   LTD ":           "ACME LTD",
+		"acme-nigeria limited":    "ACME NIG LTD",
+		"Dangote Cement Company.": "DANGOTE CEMENT CO",
+	}
+	for name, expected := range tests {
+		if got := NormaliseCounterparty(name); got != expected {
+			t.Errorf("NormaliseCounterparty(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
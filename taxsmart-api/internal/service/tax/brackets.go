@@ -20,6 +20,11 @@ const (
 	RentReliefPercentage = 0.20
 	RentReliefCap        = 500_000.0
 
+	// Statutory contributions are deductible up to a share of gross emoluments
+	PensionStatutoryRate = 0.08  // employee pension contribution under the PRA
+	NHISStatutoryRate    = 0.05  // employee health insurance contribution
+	NHFStatutoryRate     = 0.025 // National Housing Fund contribution
+
 	// Tax-free threshold
 	TaxFreeThreshold = 800_000.0
 
@@ -31,32 +31,43 @@ func (e *Engine) CalculateTax(req model.TaxCalculationRequest) (*model.TaxReport
 		UpdatedAt: time.Now(),
 	}
 
-	// Aggregate income by category
+	// Aggregate income by category and treatment. Only taxable income counts
+	// towards the totals; exempt and finally withheld income is reported alone.
 	incomeByCategory := make(map[string]float64)
+	incomeByTreatment := make(map[string]float64)
+	incomeByRoot := make(map[model.Category]float64)
 	for _, tx := range req.Transactions {
-		if tx.Category.IsIncome() && tx.TransactionType == "credit" {
+		if !InTaxYear(tx, req.TaxYear) || tx.TransactionType != "credit" || !tx.Category.IsIncome() {
+			continue
+		}
+		incomeByTreatment[string(tx.Category.Treatment())] += tx.Amount
+		if tx.Category.IsTaxable() {
 			incomeByCategory[string(tx.Category)] += tx.Amount
+			incomeByRoot[tx.Category.Root()] += tx.Amount
 		}
 	}
 
-	// Calculate totals by category
-	report.EmploymentIncome = incomeByCategory[string(model.CategoryEmployment)]
-	report.FreelanceIncome = incomeByCategory[string(model.CategoryFreelance)]
-	report.RentalIncome = incomeByCategory[string(model.CategoryRental)]
-	report.InvestmentIncome = incomeByCategory[string(model.CategoryInvestment)]
-	report.CryptoIncome = incomeByCategory[string(model.CategoryCrypto)]
-	report.OtherIncome = incomeByCategory[string(model.CategoryOtherIncome)] + incomeByCategory[string(model.CategoryInterest)]
+	// Calculate totals by top-level category
+	report.EmploymentIncome = incomeByRoot[model.CategoryEmployment]
+	report.FreelanceIncome = incomeByRoot[model.CategoryFreelance]
+	report.RentalIncome = incomeByRoot[model.CategoryRental]
+	report.InvestmentIncome = incomeByRoot[model.CategoryInvestment]
+	report.CryptoIncome = incomeByRoot[model.CategoryCrypto]
+	report.OtherIncome = incomeByRoot[model.CategoryOtherIncome]
 
 	report.TotalIncome = report.EmploymentIncome + report.FreelanceIncome +
 		report.RentalIncome + report.InvestmentIncome +
 		report.CryptoIncome + report.OtherIncome
 
-	// Calculate reliefs
-	totalReliefs, reliefsApplied := e.reliefCalculator.CalculateReliefs(req.Reliefs)
-	report.RentRelief = reliefsApplied["rent_relief"]
-	report.PensionDeduction = reliefsApplied["pension"]
-	report.NHISDeduction = reliefsApplied["nhis"]
-	report.NHFDeduction = reliefsApplied["nhf"]
+	// Calculate reliefs, bounded by income
+	totalReliefs, reliefsApplied := e.reliefCalculator.ApplyReliefs(req.Reliefs, ReliefIncome{
+		Gross:      report.TotalIncome,
+		Emoluments: report.EmploymentIncome,
+	})
+	report.RentRelief = reliefsApplied["rent_relief"].Allowed
+	report.PensionDeduction = reliefsApplied["pension"].Allowed
+	report.NHISDeduction = reliefsApplied["nhis"].Allowed
+	report.NHFDeduction = reliefsApplied["nhf"].Allowed
 	report.TotalReliefs = totalReliefs
 
 	// Calculate taxable income (income - reliefs, but not below 0)
@@ -78,14 +89,24 @@ func (e *Engine) CalculateTax(req model.TaxCalculationRequest) (*model.TaxReport
 
 	// Build breakdown
 	report.Breakdown = &model.TaxBreakdown{
-		PITBreakdown:     pitBreakdown,
-		IncomeByCategory: incomeByCategory,
-		ReliefsApplied:   reliefsApplied,
+		PITBreakdown:      pitBreakdown,
+		IncomeByCategory:  incomeByCategory,
+		IncomeByTreatment: incomeByTreatment,
+		ReliefsApplied:    reliefsApplied,
 	}
 
 	return report, nil
 }
 
+// InTaxYear reports whether a transaction belongs to the tax year. Undated
+// transactions, and any transaction when no year is given, are included.
+func InTaxYear(tx model.Transaction, taxYear int) bool {
+	if taxYear == 0 || tx.TransactionDate.IsZero() {
+		return true
+	}
+	return tx.TransactionDate.Year() == taxYear
+}
+
 // QuickCalculatePIT is a convenience method for quick PIT calculation
 func (e *Engine) QuickCalculatePIT(annualIncome float64) float64 {
 	return e.pitCalculator.CalculateSimple(annualIncome)
@@ -0,0 +1,76 @@
+package tax
+
+import (
+	"sort"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// SplitByYear groups transactions by the year of their TransactionDate.
+// Undated transactions cannot be assigned to a year and are returned separately.
+func SplitByYear(transactions []model.Transaction) (map[int][]model.Transaction, []model.Transaction) {
+	byYear := make(map[int][]model.Transaction)
+	var undated []model.Transaction
+	for _, tx := range transactions {
+		if tx.TransactionDate.IsZero() {
+			undated = append(undated, tx)
+			continue
+		}
+		year := tx.TransactionDate.Year()
+		byYear[year] = append(byYear[year], tx)
+	}
+	return byYear, undated
+}
+
+// CalculateYears computes one report per tax year spanned by the transactions, oldest first
+func (e *Engine) CalculateYears(req model.MultiYearRequest) ([]*model.TaxReport, error) {
+	byYear, _ := SplitByYear(req.Transactions)
+
+	years := make([]int, 0, len(byYear))
+	for year := range byYear {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+
+	reports := make([]*model.TaxReport, 0, len(years))
+	for _, year := range years {
+		report, err := e.CalculateTax(model.TaxCalculationRequest{
+			TaxYear:      year,
+			Transactions: byYear[year],
+			Reliefs:      req.ReliefsByYear[year],
+		})
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// Summarise returns the history listing view of a report
+func Summarise(report *model.TaxReport) model.TaxReportSummary {
+	return model.TaxReportSummary{
+		ID:            report.ID,
+		TaxYear:       report.TaxYear,
+		TotalIncome:   report.TotalIncome,
+		TotalReliefs:  report.TotalReliefs,
+		TaxableIncome: report.TaxableIncome,
+		TotalTax:      report.TotalTax,
+		EffectiveRate: EffectiveRate(report),
+		UpdatedAt:     report.UpdatedAt,
+	}
+}
+
+// CompareYears compares two reports, reporting the change from the first to the second
+func CompareYears(from, to *model.TaxReport) model.YearComparison {
+	return model.YearComparison{
+		FromYear:          from.TaxYear,
+		ToYear:            to.TaxYear,
+		From:              from,
+		To:                to,
+		FromEffectiveRate: EffectiveRate(from),
+		ToEffectiveRate:   EffectiveRate(to),
+		Change:            DiffReports(from, to),
+	}
+}
@@ -0,0 +1,116 @@
+package tax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestEngine_CalculateTaxFiltersByYear(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.TaxCalculationRequest{
+		TaxYear: 2026,
+		Transactions: []model.Transaction{
+			{TransactionDate: time.Date(2025, time.December, 28, 0, 0, 0, 0, time.UTC), Amount: 1_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+			{TransactionDate: time.Date(2026, time.January, 28, 0, 0, 0, 0, time.UTC), Amount: 2_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+			{Amount: 500_000, TransactionType: "credit", Category: model.CategoryFreelance},
+		},
+	}
+
+	report, err := engine.CalculateTax(req)
+	if err != nil {
+		t.Fatalf("CalculateTax failed: %v", err)
+	}
+
+	// The December 2025 salary belongs to the previous year; the undated row is kept
+	if report.TotalIncome != 2_500_000 {
+		t.Errorf("Expected total income 2,500,000, got %.2f", report.TotalIncome)
+	}
+}
+
+func TestEngine_CalculateTaxByTreatment(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.TaxCalculationRequest{
+		Transactions: []model.Transaction{
+			{Amount: 1_000_000, TransactionType: "credit", Category: model.CategoryFreelanceForeign},
+			{Amount: 400_000, TransactionType: "credit", Category: model.CategoryFreelance},
+			{Amount: 200_000, TransactionType: "credit", Category: model.CategoryGift},
+			{Amount: 90_000, TransactionType: "credit", Category: model.CategoryDividend},
+			{Amount: 60_000, TransactionType: "credit", Category: model.CategoryInterest},
+			{Amount: 500_000, TransactionType: "credit", Category: model.CategoryLoan},
+			{Amount: 30_000, TransactionType: "credit", Category: model.CategoryRefund},
+		},
+	}
+
+	report, err := engine.CalculateTax(req)
+	if err != nil {
+		t.Fatalf("CalculateTax failed: %v", err)
+	}
+
+	// Subcategories roll up into their parent; only taxable income is totalled
+	if report.FreelanceIncome != 1_400_000 || report.TotalIncome != 1_400_000 {
+		t.Errorf("Expected freelance and total income of 1,400,000, got %.2f and %.2f", report.FreelanceIncome, report.TotalIncome)
+	}
+	// Bank interest bears final withholding tax, so it is no longer part of other income
+	if report.OtherIncome != 0 || report.InvestmentIncome != 0 {
+		t.Errorf("Expected gifts, interest and dividends left out of the totals, got %.2f and %.2f", report.OtherIncome, report.InvestmentIncome)
+	}
+
+	byTreatment := report.Breakdown.IncomeByTreatment
+	expected := map[string]float64{"taxable": 1_400_000, "exempt": 200_000, "final_wht": 150_000}
+	if len(byTreatment) != len(expected) {
+		t.Errorf("Expected income under %d treatments, got %v", len(expected), byTreatment)
+	}
+	for treatment, amount := range expected {
+		if byTreatment[treatment] != amount {
+			t.Errorf("Expected %s income of %.2f, got %.2f", treatment, amount, byTreatment[treatment])
+		}
+	}
+}
+
+func TestEngine_CalculateYearsAndCompare(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.MultiYearRequest{
+		Transactions: []model.Transaction{
+			{TransactionDate: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: 6_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+			{TransactionDate: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), Amount: 4_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+			{TransactionDate: time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC), Amount: 1_000_000, TransactionType: "credit", Category: model.CategoryFreelance},
+			{Amount: 50_000, TransactionType: "credit", Category: model.CategoryOtherIncome},
+		},
+		ReliefsByYear: map[int]model.ReliefInput{
+			2026: {PensionContribution: 300_000},
+		},
+	}
+
+	reports, err := engine.CalculateYears(req)
+	if err != nil {
+		t.Fatalf("CalculateYears failed: %v", err)
+	}
+	if len(reports) != 2 || reports[0].TaxYear != 2025 || reports[1].TaxYear != 2026 {
+		t.Fatalf("Expected reports for 2025 and 2026 in order, got %d", len(reports))
+	}
+	if reports[0].TotalIncome != 4_000_000 || reports[1].TotalIncome != 7_000_000 {
+		t.Errorf("Expected income 4,000,000 and 7,000,000, got %.2f and %.2f", reports[0].TotalIncome, reports[1].TotalIncome)
+	}
+
+	comparison := CompareYears(reports[0], reports[1])
+	if comparison.Change.IncomeByCategory[string(model.CategoryFreelance)] != 1_000_000 {
+		t.Errorf("Expected freelance income to rise by 1,000,000, got %.2f",
+			comparison.Change.IncomeByCategory[string(model.CategoryFreelance)])
+	}
+	if comparison.Change.Reliefs["pension"] != 300_000 {
+		t.Errorf("Expected pension relief to rise by 300,000, got %.2f", comparison.Change.Reliefs["pension"])
+	}
+	if comparison.ToEffectiveRate <= comparison.FromEffectiveRate {
+		t.Errorf("Expected the effective rate to rise, got %.2f%% to %.2f%%",
+			comparison.FromEffectiveRate, comparison.ToEffectiveRate)
+	}
+	if comparison.Change.TotalTax != reports[1].TotalTax-reports[0].TotalTax {
+		t.Errorf("Expected total tax change %.2f, got %.2f",
+			reports[1].TotalTax-reports[0].TotalTax, comparison.Change.TotalTax)
+	}
+}
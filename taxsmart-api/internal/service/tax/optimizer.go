@@ -0,0 +1,131 @@
+package tax
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// Recommendation actions
+const (
+	ActionPensionTopUp    = "pension_top_up"
+	ActionNHISTopUp       = "nhis_top_up"
+	ActionNHFRegistration = "nhf_registration"
+	ActionClaimRentRelief = "claim_rent_relief"
+)
+
+// Recommend suggests actions that reduce the tax in the given request, ranked
+// by expected saving. Savings are computed by re-running the PIT brackets on
+// the reduced taxable income, so suggestions that cross a bracket are exact.
+func (e *Engine) Recommend(req model.TaxCalculationRequest) ([]model.Recommendation, error) {
+	report, err := e.CalculateTax(req)
+	if err != nil {
+		return nil, err
+	}
+	if report.TaxableIncome <= TaxFreeThreshold {
+		return []model.Recommendation{}, nil
+	}
+
+	emoluments := report.EmploymentIncome
+	if emoluments <= 0 {
+		emoluments = report.TotalIncome
+	}
+
+	var candidates []model.Recommendation
+
+	// Statutory contributions below their limit can be topped up
+	if headroom := emoluments*PensionStatutoryRate - req.Reliefs.PensionContribution; headroom > 0 {
+		adjusted := req.Reliefs
+		adjusted.PensionContribution += headroom
+		candidates = append(candidates, model.Recommendation{
+			Action:          ActionPensionTopUp,
+			Title:           "Top up your pension contributions",
+			Description:     fmt.Sprintf("Make voluntary contributions of ₦%.2f to your Retirement Savings Account.", headroom),
+			SuggestedAmount: headroom,
+			Rule:            fmt.Sprintf("Pension contributions are deductible up to %.0f%% of gross emoluments", PensionStatutoryRate*100),
+		})
+		e.priceRecommendation(&candidates[len(candidates)-1], report, adjusted)
+	}
+
+	if report.EmploymentIncome > 0 {
+		if headroom := emoluments*NHFStatutoryRate - req.Reliefs.NHFContribution; headroom > 0 {
+			adjusted := req.Reliefs
+			adjusted.NHFContribution += headroom
+			title := "Increase your NHF contributions"
+			if req.Reliefs.NHFContribution <= 0 {
+				title = "Register for the National Housing Fund"
+			}
+			candidates = append(candidates, model.Recommendation{
+				Action:          ActionNHFRegistration,
+				Title:           title,
+				Description:     fmt.Sprintf("Contribute ₦%.2f to the National Housing Fund through your employer.", headroom),
+				SuggestedAmount: headroom,
+				Rule:            fmt.Sprintf("NHF contributions are deductible up to %.1f%% of gross emoluments", NHFStatutoryRate*100),
+			})
+			e.priceRecommendation(&candidates[len(candidates)-1], report, adjusted)
+		}
+
+		if headroom := emoluments*NHISStatutoryRate - req.Reliefs.NHISContribution; headroom > 0 {
+			adjusted := req.Reliefs
+			adjusted.NHISContribution += headroom
+			candidates = append(candidates, model.Recommendation{
+				Action:          ActionNHISTopUp,
+				Title:           "Enrol in a health insurance scheme",
+				Description:     fmt.Sprintf("Contribute ₦%.2f to a National Health Insurance scheme.", headroom),
+				SuggestedAmount: headroom,
+				Rule:            fmt.Sprintf("NHIS contributions are deductible up to %.0f%% of gross emoluments", NHISStatutoryRate*100),
+			})
+			e.priceRecommendation(&candidates[len(candidates)-1], report, adjusted)
+		}
+	}
+
+	// Rent paid in the statement but not claimed
+	var rentPaid float64
+	for _, tx := range req.Transactions {
+		if tx.Category == model.CategoryRentExpense && tx.TransactionType == "debit" {
+			rentPaid += tx.Amount
+		}
+	}
+	if rentPaid > req.Reliefs.AnnualRent && e.reliefCalculator.CalculateRentRelief(req.Reliefs.AnnualRent) < RentReliefCap {
+		adjusted := req.Reliefs
+		adjusted.AnnualRent = rentPaid
+		candidates = append(candidates, model.Recommendation{
+			Action:          ActionClaimRentRelief,
+			Title:           "Claim rent relief",
+			Description:     fmt.Sprintf("Your statement shows ₦%.2f paid in rent. Declare it to claim rent relief.", rentPaid),
+			SuggestedAmount: rentPaid,
+			Rule:            fmt.Sprintf("Rent relief is %.0f%% of annual rent, capped at ₦%.2f", RentReliefPercentage*100, RentReliefCap),
+		})
+		e.priceRecommendation(&candidates[len(candidates)-1], report, adjusted)
+	}
+
+	recommendations := make([]model.Recommendation, 0, len(candidates))
+	for _, rec := range candidates {
+		if rec.ExpectedSaving > 0 {
+			recommendations = append(recommendations, rec)
+		}
+	}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].ExpectedSaving > recommendations[j].ExpectedSaving
+	})
+
+	return recommendations, nil
+}
+
+// priceRecommendation fills in the relief gained and tax saved by switching to the adjusted reliefs
+func (e *Engine) priceRecommendation(rec *model.Recommendation, report *model.TaxReport, adjusted model.ReliefInput) {
+	totalReliefs, _ := e.reliefCalculator.ApplyReliefs(adjusted, ReliefIncome{
+		Gross:      report.TotalIncome,
+		Emoluments: report.EmploymentIncome,
+	})
+
+	taxable := report.TotalIncome - totalReliefs
+	if taxable < 0 {
+		taxable = 0
+	}
+
+	rec.AdditionalRelief = totalReliefs - report.TotalReliefs
+	rec.MarginalRate = e.pitCalculator.MarginalRate(report.TaxableIncome)
+	rec.ExpectedSaving = report.PITAmount - e.pitCalculator.CalculateSimple(taxable)
+}
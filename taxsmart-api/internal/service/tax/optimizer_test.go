@@ -0,0 +1,79 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestEngine_Recommend(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.TaxCalculationRequest{
+		TaxYear: 2026,
+		Transactions: []model.Transaction{
+			{Description: "SALARY", Amount: 10_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+			{Description: "HOUSE RENT", Amount: 1_500_000, TransactionType: "debit", Category: model.CategoryRentExpense},
+		},
+		Reliefs: model.ReliefInput{PensionContribution: 500_000, NHISContribution: 500_000},
+	}
+
+	recs, err := engine.Recommend(req)
+	if err != nil {
+		t.Fatalf("Recommend failed: %v", err)
+	}
+
+	// Pension top-up 300,000, rent relief 300,000, NHF 250,000; NHIS is already at its limit
+	expected := []struct {
+		action string
+		relief float64
+	}{
+		{ActionPensionTopUp, 300_000},
+		{ActionClaimRentRelief, 300_000},
+		{ActionNHFRegistration, 250_000},
+	}
+	if len(recs) != len(expected) {
+		t.Fatalf("Expected %d recommendations, got %d: %+v", len(expected), len(recs), recs)
+	}
+
+	for i, want := range expected {
+		rec := recs[i]
+		if rec.Action != want.action {
+			t.Errorf("Recommendation %d: expected %s, got %s", i, want.action, rec.Action)
+		}
+		if rec.AdditionalRelief != want.relief {
+			t.Errorf("%s: expected additional relief %.2f, got %.2f", rec.Action, want.relief, rec.AdditionalRelief)
+		}
+		if rec.MarginalRate != 0.18 {
+			t.Errorf("%s: expected marginal rate 0.18, got %.2f", rec.Action, rec.MarginalRate)
+		}
+		if rec.Rule == "" {
+			t.Errorf("%s: expected the rule to be reported", rec.Action)
+		}
+	}
+
+	for _, rec := range recs {
+		want := rec.AdditionalRelief * 0.18
+		if diff := rec.ExpectedSaving - want; diff > 0.01 || diff < -0.01 {
+			t.Errorf("%s: expected saving %.2f, got %.2f", rec.Action, want, rec.ExpectedSaving)
+		}
+	}
+}
+
+func TestEngine_RecommendBelowThreshold(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.TaxCalculationRequest{
+		Transactions: []model.Transaction{
+			{Description: "SALARY", Amount: 600_000, TransactionType: "credit", Category: model.CategoryEmployment},
+		},
+	}
+
+	recs, err := engine.Recommend(req)
+	if err != nil {
+		t.Fatalf("Recommend failed: %v", err)
+	}
+	if len(recs) != 0 {
+		t.Errorf("Expected no recommendations below the tax-free threshold, got %d", len(recs))
+	}
+}
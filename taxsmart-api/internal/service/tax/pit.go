@@ -73,6 +73,19 @@ func (c *PITCalculator) Calculate(annualIncome float64) (float64, []model.Bracke
 	return totalTax, breakdown
 }
 
+// MarginalRate returns the rate applied to the last naira of the given income
+func (c *PITCalculator) MarginalRate(annualIncome float64) float64 {
+	if annualIncome <= TaxFreeThreshold {
+		return 0
+	}
+	for _, bracket := range c.brackets {
+		if annualIncome <= bracket.Max {
+			return bracket.Rate
+		}
+	}
+	return c.brackets[len(c.brackets)-1].Rate
+}
+
 // CalculateSimple returns just the total tax amount
 func (c *PITCalculator) CalculateSimple(annualIncome float64) float64 {
 	tax, _ := c.Calculate(annualIncome)
@@ -0,0 +1,184 @@
+package tax
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// EstimateProvisional groups income by month or quarter and, at the end of
+// each period up to AsOf, projects the annual income and tax. The year-to-date
+// tax is the projected annual tax pro-rated over the months elapsed, and the
+// amount to set aside each period tops the running total up to it. AsOf
+// defaults to the end of the month of the year's last transaction, so a month
+// paid once is not annualised as if only part of it had passed.
+func (e *Engine) EstimateProvisional(req model.ProvisionalRequest) (*model.ProvisionalEstimate, error) {
+	if req.TaxYear == 0 {
+		return nil, fmt.Errorf("tax_year is required")
+	}
+
+	period := req.Period
+	if period == "" {
+		period = model.PeriodMonthly
+	}
+	var monthsPerPeriod int
+	switch period {
+	case model.PeriodMonthly:
+		monthsPerPeriod = 1
+	case model.PeriodQuarterly:
+		monthsPerPeriod = 3
+	default:
+		return nil, fmt.Errorf("unsupported period %q", req.Period)
+	}
+
+	method := req.Method
+	if method == "" {
+		method = model.ProjectionAnnualise
+	}
+	if method != model.ProjectionAnnualise && method != model.ProjectionTrailing12 {
+		return nil, fmt.Errorf("unsupported projection method %q", req.Method)
+	}
+
+	yearStart := time.Date(req.TaxYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := yearStart.AddDate(1, 0, 0).Add(-time.Nanosecond)
+
+	asOf := req.AsOf
+	if asOf.IsZero() {
+		for _, tx := range req.Transactions {
+			if tx.TransactionDate.Year() == req.TaxYear && tx.TransactionDate.After(asOf) {
+				asOf = tx.TransactionDate
+			}
+		}
+		if asOf.IsZero() {
+			asOf = yearEnd
+		} else {
+			asOf = time.Date(asOf.Year(), asOf.Month()+1, 0, 0, 0, 0, 0, asOf.Location())
+		}
+	}
+	asOf = endOfDay(asOf)
+	if asOf.Before(yearStart) {
+		return nil, fmt.Errorf("as_of is before the start of %d", req.TaxYear)
+	}
+	if asOf.After(yearEnd) {
+		asOf = yearEnd
+	}
+
+	estimate := &model.ProvisionalEstimate{
+		TaxYear: req.TaxYear,
+		Period:  period,
+		Method:  method,
+		AsOf:    asOf,
+		Periods: []model.PeriodEstimate{},
+	}
+
+	var ytdIncome, setAsideSoFar float64
+	for start := yearStart; !start.After(asOf); start = start.AddDate(0, monthsPerPeriod, 0) {
+		end := start.AddDate(0, monthsPerPeriod, 0).Add(-time.Nanosecond)
+		if end.After(asOf) {
+			end = asOf
+		}
+
+		periodIncome := incomeByCategory(req.Transactions, start, end)
+		var income float64
+		for _, amount := range periodIncome {
+			income += amount
+		}
+		ytdIncome += income
+
+		// Project annual income by category so reliefs see the right emoluments
+		var projected map[model.Category]float64
+		switch method {
+		case model.ProjectionAnnualise:
+			elapsed := monthsElapsed(end)
+			projected = incomeByCategory(req.Transactions, yearStart, end)
+			for category, amount := range projected {
+				projected[category] = amount * 12 / elapsed
+			}
+		case model.ProjectionTrailing12:
+			projected = incomeByCategory(req.Transactions, end.AddDate(-1, 0, 0).Add(time.Nanosecond), end)
+		}
+
+		report, err := e.CalculateTax(model.TaxCalculationRequest{
+			TaxYear:      req.TaxYear,
+			Transactions: projectedTransactions(projected, end),
+			Reliefs:      req.Reliefs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ytdTax := report.TotalTax * monthsElapsed(end) / 12
+		setAside := ytdTax - setAsideSoFar
+		if setAside < 0 {
+			setAside = 0
+		}
+		setAsideSoFar += setAside
+
+		estimate.Periods = append(estimate.Periods, model.PeriodEstimate{
+			Label:                 periodLabel(start, monthsPerPeriod),
+			Start:                 start,
+			End:                   end,
+			Income:                income,
+			YTDIncome:             ytdIncome,
+			ProjectedAnnualIncome: report.TotalIncome,
+			ProjectedAnnualTax:    report.TotalTax,
+			YTDTax:                ytdTax,
+			SetAside:              setAside,
+		})
+		estimate.ProjectedAnnualTax = report.TotalTax
+		estimate.YTDTax = ytdTax
+	}
+	estimate.TotalSetAside = setAsideSoFar
+
+	return estimate, nil
+}
+
+// incomeByCategory sums taxable income credits dated within [start, end]
+func incomeByCategory(transactions []model.Transaction, start, end time.Time) map[model.Category]float64 {
+	totals := make(map[model.Category]float64)
+	for _, tx := range transactions {
+		if !tx.Category.IsTaxable() || tx.TransactionType != "credit" {
+			continue
+		}
+		if tx.TransactionDate.Before(start) || tx.TransactionDate.After(end) {
+			continue
+		}
+		totals[tx.Category] += tx.Amount
+	}
+	return totals
+}
+
+// projectedTransactions turns projected category totals into transactions the engine can consume
+func projectedTransactions(projected map[model.Category]float64, date time.Time) []model.Transaction {
+	txs := make([]model.Transaction, 0, len(projected))
+	for category, amount := range projected {
+		txs = append(txs, model.Transaction{
+			TransactionDate: date,
+			Description:     "Projected " + string(category),
+			Amount:          amount,
+			TransactionType: "credit",
+			Category:        category,
+		})
+	}
+	return txs
+}
+
+// monthsElapsed returns the months from the start of the year to t, counting part months by day
+func monthsElapsed(t time.Time) float64 {
+	daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return float64(t.Month()-1) + float64(t.Day())/float64(daysInMonth)
+}
+
+// endOfDay returns the last instant of t's day
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// periodLabel names a period, e.g. "2026-03" or "2026-Q1"
+func periodLabel(start time.Time, monthsPerPeriod int) string {
+	if monthsPerPeriod == 3 {
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	}
+	return start.Format("2006-01")
+}
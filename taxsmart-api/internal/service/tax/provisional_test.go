@@ -0,0 +1,122 @@
+package tax
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func monthlySalaries(year int, from, to time.Month, amount float64) []model.Transaction {
+	var txs []model.Transaction
+	for m := from; m <= to; m++ {
+		txs = append(txs, model.Transaction{
+			TransactionDate: time.Date(year, m, 25, 0, 0, 0, 0, time.UTC),
+			Description:     "SALARY",
+			Amount:          amount,
+			TransactionType: "credit",
+			Category:        model.CategoryEmployment,
+		})
+	}
+	return txs
+}
+
+func TestEngine_EstimateProvisionalAnnualise(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.ProvisionalRequest{
+		TaxYear:      2026,
+		Period:       model.PeriodMonthly,
+		Method:       model.ProjectionAnnualise,
+		AsOf:         time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC),
+		Transactions: monthlySalaries(2026, time.January, time.June, 500_000),
+	}
+
+	estimate, err := engine.EstimateProvisional(req)
+	if err != nil {
+		t.Fatalf("EstimateProvisional failed: %v", err)
+	}
+	if len(estimate.Periods) != 6 {
+		t.Fatalf("Expected 6 periods, got %d", len(estimate.Periods))
+	}
+
+	// 500,000 a month projects to 6,000,000 a year, taxed at 869,999.67
+	annualTax := engine.QuickCalculatePIT(6_000_000)
+	for _, p := range estimate.Periods {
+		if math.Abs(p.ProjectedAnnualIncome-6_000_000) > 0.01 {
+			t.Errorf("%s: expected projected income 6,000,000, got %.2f", p.Label, p.ProjectedAnnualIncome)
+		}
+		if math.Abs(p.SetAside-annualTax/12) > 0.01 {
+			t.Errorf("%s: expected set aside %.2f, got %.2f", p.Label, annualTax/12, p.SetAside)
+		}
+	}
+	if math.Abs(estimate.YTDTax-annualTax/2) > 0.01 {
+		t.Errorf("Expected YTD tax %.2f, got %.2f", annualTax/2, estimate.YTDTax)
+	}
+}
+
+func TestEngine_EstimateProvisionalTrailing(t *testing.T) {
+	engine := NewEngine()
+
+	// A full prior year at 250,000 a month, then 500,000 a month from January
+	txs := monthlySalaries(2025, time.January, time.December, 250_000)
+	txs = append(txs, monthlySalaries(2026, time.January, time.March, 500_000)...)
+
+	req := model.ProvisionalRequest{
+		TaxYear:      2026,
+		Period:       model.PeriodQuarterly,
+		Method:       model.ProjectionTrailing12,
+		Transactions: txs,
+	}
+
+	estimate, err := engine.EstimateProvisional(req)
+	if err != nil {
+		t.Fatalf("EstimateProvisional failed: %v", err)
+	}
+	if len(estimate.Periods) != 1 || estimate.Periods[0].Label != "2026-Q1" {
+		t.Fatalf("Expected a single 2026-Q1 period, got %+v", estimate.Periods)
+	}
+
+	// Trailing 12 months to the end of March: April-December 2025 plus January-March 2026
+	q1 := estimate.Periods[0]
+	if q1.ProjectedAnnualIncome != 9*250_000+3*500_000 {
+		t.Errorf("Expected trailing income 3,750,000, got %.2f", q1.ProjectedAnnualIncome)
+	}
+	if q1.YTDIncome != 1_500_000 {
+		t.Errorf("Expected YTD income 1,500,000, got %.2f", q1.YTDIncome)
+	}
+}
+
+func TestEngine_EstimateProvisionalDefaultAsOf(t *testing.T) {
+	engine := NewEngine()
+
+	// Salary paid on the 25th of January to March, with no AsOf given
+	req := model.ProvisionalRequest{
+		TaxYear:      2026,
+		Transactions: monthlySalaries(2026, time.January, time.March, 500_000),
+	}
+
+	estimate, err := engine.EstimateProvisional(req)
+	if err != nil {
+		t.Fatalf("EstimateProvisional failed: %v", err)
+	}
+	if got := estimate.AsOf.Format(time.DateOnly); got != "2026-03-31" {
+		t.Errorf("Expected AsOf to default to 2026-03-31, got %s", got)
+	}
+	// Three whole months annualise to 6,000,000, not inflated by the days after the 25th
+	if got := estimate.Periods[len(estimate.Periods)-1].ProjectedAnnualIncome; math.Abs(got-6_000_000) > 0.01 {
+		t.Errorf("Expected projected income 6,000,000, got %.2f", got)
+	}
+}
+
+func TestEngine_EstimateProvisionalInvalid(t *testing.T) {
+	engine := NewEngine()
+
+	if _, err := engine.EstimateProvisional(model.ProvisionalRequest{TaxYear: 2026, Period: "weekly"}); err == nil {
+		t.Error("Expected an error for an unsupported period")
+	}
+	if _, err := engine.EstimateProvisional(model.ProvisionalRequest{TaxYear: 2026, Method: "guess"}); err == nil {
+		t.Error("Expected an error for an unsupported method")
+	}
+}
@@ -1,6 +1,8 @@
 package tax
 
 import (
+	"fmt"
+
 	"github.com/taxsmart/taxsmart-api/internal/model"
 )
 
@@ -12,40 +14,141 @@ func NewReliefCalculator() *ReliefCalculator {
 	return &ReliefCalculator{}
 }
 
-// CalculateReliefs computes all applicable reliefs
+// ReliefIncome holds the income figures that bound relief claims
+type ReliefIncome struct {
+	Gross      float64 // Total income for the year
+	Emoluments float64 // Employment income; Gross is used when zero (self-employed)
+}
+
+// reliefRule describes how a single relief is derived from a claim and bounded
+type reliefRule struct {
+	key        string
+	claimed    func(model.ReliefInput) float64
+	claimRate  float64 // Share of the claim that is deductible (0 means all of it)
+	claimCap   float64 // Absolute cap on the relief (0 means none)
+	incomeRate float64 // Cap as a share of gross emoluments (0 means none)
+	label      string
+}
+
+// reliefRules lists reliefs in the order they are applied against income.
+// Statutory contributions come first so rent relief cannot crowd them out.
+var reliefRules = []reliefRule{
+	{
+		key:        "pension",
+		claimed:    func(in model.ReliefInput) float64 { return in.PensionContribution },
+		incomeRate: PensionStatutoryRate,
+		label:      "pension",
+	},
+	{
+		key:        "nhis",
+		claimed:    func(in model.ReliefInput) float64 { return in.NHISContribution },
+		incomeRate: NHISStatutoryRate,
+		label:      "NHIS",
+	},
+	{
+		key:        "nhf",
+		claimed:    func(in model.ReliefInput) float64 { return in.NHFContribution },
+		incomeRate: NHFStatutoryRate,
+		label:      "NHF",
+	},
+	{
+		key:       "rent_relief",
+		claimed:   func(in model.ReliefInput) float64 { return in.AnnualRent },
+		claimRate: RentReliefPercentage,
+		claimCap:  RentReliefCap,
+		label:     "rent relief",
+	},
+}
+
+// relief applies the claim rate and absolute cap, ignoring income
+func (r reliefRule) relief(claimed float64) (float64, bool) {
+	relief := claimed
+	if r.claimRate > 0 {
+		relief = claimed * r.claimRate
+	}
+	if r.claimCap > 0 && relief > r.claimCap {
+		return r.claimCap, true
+	}
+	return relief, false
+}
+
+// CalculateReliefs computes all applicable reliefs without regard to income
 func (c *ReliefCalculator) CalculateReliefs(input model.ReliefInput) (float64, map[string]float64) {
 	reliefs := make(map[string]float64)
 	var total float64
 
-	// Rent Relief: 20% of annual rent, capped at ₦500,000
-	if input.AnnualRent > 0 {
-		rentRelief := input.AnnualRent * RentReliefPercentage
-		if rentRelief > RentReliefCap {
-			rentRelief = RentReliefCap
+	for _, rule := range reliefRules {
+		claimed := rule.claimed(input)
+		if claimed <= 0 {
+			continue
 		}
-		reliefs["rent_relief"] = rentRelief
-		total += rentRelief
+		relief, _ := rule.relief(claimed)
+		reliefs[rule.key] = relief
+		total += relief
 	}
 
-	// Pension contributions - fully deductible
-	if input.PensionContribution > 0 {
-		reliefs["pension"] = input.PensionContribution
-		total += input.PensionContribution
-	}
+	return total, reliefs
+}
 
-	// NHIS contributions - fully deductible
-	if input.NHISContribution > 0 {
-		reliefs["nhis"] = input.NHISContribution
-		total += input.NHISContribution
+// ApplyReliefs computes reliefs bounded by income. Each relief is capped at its
+// statutory share of gross emoluments, and reliefs are applied in order until
+// income is exhausted. Capped and rejected claims are reported with a reason.
+func (c *ReliefCalculator) ApplyReliefs(input model.ReliefInput, income ReliefIncome) (float64, map[string]model.ReliefDetail) {
+	details := make(map[string]model.ReliefDetail)
+	var total float64
+
+	emoluments := income.Emoluments
+	if emoluments <= 0 {
+		emoluments = income.Gross
 	}
+	remaining := income.Gross
+
+	for _, rule := range reliefRules {
+		claimed := rule.claimed(input)
+		if claimed <= 0 {
+			continue
+		}
 
-	// NHF contributions - fully deductible
-	if input.NHFContribution > 0 {
-		reliefs["nhf"] = input.NHFContribution
-		total += input.NHFContribution
+		detail := model.ReliefDetail{Claimed: claimed, Status: model.ReliefStatusApplied}
+		allowed, capped := rule.relief(claimed)
+		if capped {
+			detail.Status = model.ReliefStatusCapped
+			detail.Reason = fmt.Sprintf("%s is capped at ₦%.2f", rule.label, rule.claimCap)
+		}
+
+		if rule.incomeRate > 0 {
+			limit := emoluments * rule.incomeRate
+			if allowed > limit {
+				allowed = limit
+				detail.Status = model.ReliefStatusCapped
+				detail.Reason = fmt.Sprintf("%s is limited to %.1f%% of gross emoluments (₦%.2f)",
+					rule.label, rule.incomeRate*100, limit)
+			}
+		}
+
+		if allowed > remaining {
+			allowed = remaining
+			detail.Status = model.ReliefStatusCapped
+			detail.Reason = fmt.Sprintf("%s exceeds the income left after earlier reliefs", rule.label)
+		}
+
+		if allowed <= 0 {
+			allowed = 0
+			detail.Status = model.ReliefStatusRejected
+			if income.Gross <= 0 {
+				detail.Reason = "no income to claim relief against"
+			} else {
+				detail.Reason = "income is already fully relieved"
+			}
+		}
+
+		detail.Allowed = allowed
+		details[rule.key] = detail
+		total += allowed
+		remaining -= allowed
 	}
 
-	return total, reliefs
+	return total, details
 }
 
 // CalculateRentRelief calculates rent relief only
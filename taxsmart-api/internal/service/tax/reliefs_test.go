@@ -75,3 +75,85 @@ func TestReliefCalculator_CalculateReliefs(t *testing.T) {
 		t.Errorf("Expected total %.2f, got %.2f", expectedTotal, total)
 	}
 }
+
+func TestReliefCalculator_ApplyReliefs(t *testing.T) {
+	calc := NewReliefCalculator()
+
+	tests := []struct {
+		name          string
+		input         model.ReliefInput
+		income        ReliefIncome
+		expectedTotal float64
+		expected      map[string]model.ReliefDetail
+	}{
+		{
+			name:          "Within statutory limits",
+			input:         model.ReliefInput{AnnualRent: 1_000_000, PensionContribution: 400_000},
+			income:        ReliefIncome{Gross: 6_000_000, Emoluments: 6_000_000},
+			expectedTotal: 600_000,
+			expected: map[string]model.ReliefDetail{
+				"pension":     {Claimed: 400_000, Allowed: 400_000, Status: model.ReliefStatusApplied},
+				"rent_relief": {Claimed: 1_000_000, Allowed: 200_000, Status: model.ReliefStatusApplied},
+			},
+		},
+		{
+			name:          "Pension capped at 8% of emoluments",
+			input:         model.ReliefInput{PensionContribution: 5_000_000},
+			income:        ReliefIncome{Gross: 2_000_000, Emoluments: 2_000_000},
+			expectedTotal: 160_000,
+			expected: map[string]model.ReliefDetail{
+				"pension": {Claimed: 5_000_000, Allowed: 160_000, Status: model.ReliefStatusCapped},
+			},
+		},
+		{
+			name:          "Self-employed falls back to gross income",
+			input:         model.ReliefInput{NHFContribution: 100_000},
+			income:        ReliefIncome{Gross: 2_000_000},
+			expectedTotal: 50_000,
+			expected: map[string]model.ReliefDetail{
+				"nhf": {Claimed: 100_000, Allowed: 50_000, Status: model.ReliefStatusCapped},
+			},
+		},
+		{
+			name:          "Rent relief limited to remaining income",
+			input:         model.ReliefInput{AnnualRent: 2_500_000, PensionContribution: 20_000},
+			income:        ReliefIncome{Gross: 250_000, Emoluments: 250_000},
+			expectedTotal: 250_000,
+			expected: map[string]model.ReliefDetail{
+				"pension":     {Claimed: 20_000, Allowed: 20_000, Status: model.ReliefStatusApplied},
+				"rent_relief": {Claimed: 2_500_000, Allowed: 230_000, Status: model.ReliefStatusCapped},
+			},
+		},
+		{
+			name:          "No income rejects every claim",
+			input:         model.ReliefInput{AnnualRent: 1_000_000, NHISContribution: 50_000},
+			income:        ReliefIncome{},
+			expectedTotal: 0,
+			expected: map[string]model.ReliefDetail{
+				"nhis":        {Claimed: 50_000, Allowed: 0, Status: model.ReliefStatusRejected},
+				"rent_relief": {Claimed: 1_000_000, Allowed: 0, Status: model.ReliefStatusRejected},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, details := calc.ApplyReliefs(tt.input, tt.income)
+			if total != tt.expectedTotal {
+				t.Errorf("Expected total %.2f, got %.2f", tt.expectedTotal, total)
+			}
+			if len(details) != len(tt.expected) {
+				t.Fatalf("Expected %d reliefs, got %d", len(tt.expected), len(details))
+			}
+			for key, want := range tt.expected {
+				got := details[key]
+				if got.Claimed != want.Claimed || got.Allowed != want.Allowed || got.Status != want.Status {
+					t.Errorf("%s: expected %+v, got %+v", key, want, got)
+				}
+				if got.Status != model.ReliefStatusApplied && got.Reason == "" {
+					t.Errorf("%s: expected a reason for status %s", key, got.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package tax
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+// simTransaction tracks a transaction's position in the base request so
+// adjustments can reference it by index after earlier removals
+type simTransaction struct {
+	index int
+	tx    model.Transaction
+}
+
+// Simulate calculates the baseline report and one report per scenario.
+// Nothing is persisted; each scenario starts from a copy of the base request.
+func (e *Engine) Simulate(req model.SimulationRequest) (*model.SimulationResult, error) {
+	baseline, err := e.CalculateTax(req.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.SimulationResult{
+		Baseline:  baseline,
+		Scenarios: make([]model.ScenarioResult, 0, len(req.Scenarios)),
+	}
+
+	for i, scenario := range req.Scenarios {
+		name := scenario.Name
+		if name == "" {
+			name = fmt.Sprintf("scenario %d", i+1)
+		}
+
+		scenarioReq, err := applyAdjustments(req.Base, scenario.Adjustments)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		report, err := e.CalculateTax(scenarioReq)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		result.Scenarios = append(result.Scenarios, model.ScenarioResult{
+			Name:   name,
+			Report: report,
+			Diff:   DiffReports(baseline, report),
+		})
+	}
+
+	return result, nil
+}
+
+// applyAdjustments returns a copy of the base request with adjustments applied in order
+func applyAdjustments(base model.TaxCalculationRequest, adjustments []model.Adjustment) (model.TaxCalculationRequest, error) {
+	txs := make([]simTransaction, len(base.Transactions))
+	for i, tx := range base.Transactions {
+		txs[i] = simTransaction{index: i, tx: tx}
+	}
+	reliefs := base.Reliefs
+
+	for i, adj := range adjustments {
+		switch adj.Type {
+		case model.AdjustmentAddTransaction:
+			if adj.Transaction == nil {
+				return base, fmt.Errorf("adjustment %d: transaction is required", i+1)
+			}
+			txs = append(txs, simTransaction{index: -1, tx: *adj.Transaction})
+
+		case model.AdjustmentRemoveTransaction:
+			pos, err := findTransaction(txs, adj)
+			if err != nil {
+				return base, fmt.Errorf("adjustment %d: %w", i+1, err)
+			}
+			txs = append(txs[:pos], txs[pos+1:]...)
+
+		case model.AdjustmentRecategorise:
+			if adj.Category == "" {
+				return base, fmt.Errorf("adjustment %d: category is required", i+1)
+			}
+			pos, err := findTransaction(txs, adj)
+			if err != nil {
+				return base, fmt.Errorf("adjustment %d: %w", i+1, err)
+			}
+			txs[pos].tx.Category = adj.Category
+
+		case model.AdjustmentAdjustReliefs:
+			if adj.Reliefs == nil {
+				return base, fmt.Errorf("adjustment %d: reliefs are required", i+1)
+			}
+			reliefs.AnnualRent += adj.Reliefs.AnnualRent
+			reliefs.PensionContribution += adj.Reliefs.PensionContribution
+			reliefs.NHISContribution += adj.Reliefs.NHISContribution
+			reliefs.NHFContribution += adj.Reliefs.NHFContribution
+
+		case model.AdjustmentSetReliefs:
+			if adj.Reliefs == nil {
+				return base, fmt.Errorf("adjustment %d: reliefs are required", i+1)
+			}
+			reliefs = *adj.Reliefs
+
+		default:
+			return base, fmt.Errorf("adjustment %d: unsupported type %q", i+1, adj.Type)
+		}
+	}
+
+	req := base
+	req.Reliefs = reliefs
+	req.Transactions = make([]model.Transaction, len(txs))
+	for i, st := range txs {
+		req.Transactions[i] = st.tx
+	}
+	return req, nil
+}
+
+// findTransaction locates the transaction an adjustment refers to
+func findTransaction(txs []simTransaction, adj model.Adjustment) (int, error) {
+	if adj.TransactionID != uuid.Nil {
+		for i, st := range txs {
+			if st.tx.ID == adj.TransactionID {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("transaction %s not found", adj.TransactionID)
+	}
+	if adj.TransactionIndex != nil {
+		for i, st := range txs {
+			if st.index == *adj.TransactionIndex {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("transaction at index %d not found", *adj.TransactionIndex)
+	}
+	return 0, fmt.Errorf("transaction_id or transaction_index is required")
+}
+
+// DiffReports returns the change from one report to another
+func DiffReports(from, to *model.TaxReport) model.TaxReportDiff {
+	diff := model.TaxReportDiff{
+		TotalIncome:      to.TotalIncome - from.TotalIncome,
+		TotalReliefs:     to.TotalReliefs - from.TotalReliefs,
+		TaxableIncome:    to.TaxableIncome - from.TaxableIncome,
+		PITAmount:        to.PITAmount - from.PITAmount,
+		TotalTax:         to.TotalTax - from.TotalTax,
+		EffectiveRate:    EffectiveRate(to) - EffectiveRate(from),
+		IncomeByCategory: make(map[string]float64),
+	}
+
+	if from.Breakdown != nil {
+		for category, amount := range from.Breakdown.IncomeByCategory {
+			diff.IncomeByCategory[category] -= amount
+		}
+	}
+	if to.Breakdown != nil {
+		for category, amount := range to.Breakdown.IncomeByCategory {
+			diff.IncomeByCategory[category] += amount
+		}
+	}
+	for category, amount := range diff.IncomeByCategory {
+		if amount == 0 {
+			delete(diff.IncomeByCategory, category)
+		}
+	}
+
+	diff.Reliefs = make(map[string]float64)
+	if from.Breakdown != nil {
+		for key, relief := range from.Breakdown.ReliefsApplied {
+			diff.Reliefs[key] -= relief.Allowed
+		}
+	}
+	if to.Breakdown != nil {
+		for key, relief := range to.Breakdown.ReliefsApplied {
+			diff.Reliefs[key] += relief.Allowed
+		}
+	}
+	for key, amount := range diff.Reliefs {
+		if amount == 0 {
+			delete(diff.Reliefs, key)
+		}
+	}
+
+	return diff
+}
+
+// EffectiveRate returns total tax as a percentage of total income
+func EffectiveRate(report *model.TaxReport) float64 {
+	if report.TotalIncome <= 0 {
+		return 0
+	}
+	return report.TotalTax / report.TotalIncome * 100
+}
@@ -0,0 +1,93 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+)
+
+func TestEngine_Simulate(t *testing.T) {
+	engine := NewEngine()
+
+	bonusID := uuid.New()
+	base := model.TaxCalculationRequest{
+		TaxYear: 2026,
+		Transactions: []model.Transaction{
+			{Description: "SALARY", Amount: 6_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+			{ID: bonusID, Description: "BONUS", Amount: 1_000_000, TransactionType: "credit", Category: model.CategoryEmployment},
+		},
+		Reliefs: model.ReliefInput{PensionContribution: 200_000},
+	}
+
+	first := 0
+	req := model.SimulationRequest{
+		Base: base,
+		Scenarios: []model.Scenario{
+			{
+				Name: "More pension",
+				Adjustments: []model.Adjustment{
+					{Type: model.AdjustmentAdjustReliefs, Reliefs: &model.ReliefInput{PensionContribution: 300_000}},
+				},
+			},
+			{
+				Name: "Defer bonus",
+				Adjustments: []model.Adjustment{
+					{Type: model.AdjustmentRemoveTransaction, TransactionID: bonusID},
+				},
+			},
+			{
+				Name: "Salary is a transfer",
+				Adjustments: []model.Adjustment{
+					{Type: model.AdjustmentRecategorise, TransactionIndex: &first, Category: model.CategoryTransfer},
+				},
+			},
+		},
+	}
+
+	result, err := engine.Simulate(req)
+	if err != nil {
+		t.Fatalf("Simulate failed: %v", err)
+	}
+	if len(result.Scenarios) != 3 {
+		t.Fatalf("Expected 3 scenarios, got %d", len(result.Scenarios))
+	}
+
+	// 300,000 more pension at the 18% marginal rate saves 54,000
+	pension := result.Scenarios[0]
+	if pension.Diff.TotalReliefs != 300_000 {
+		t.Errorf("Expected reliefs to rise by 300,000, got %.2f", pension.Diff.TotalReliefs)
+	}
+	if diff := pension.Diff.TotalTax + 54_000; diff > 0.01 || diff < -0.01 {
+		t.Errorf("Expected tax to fall by 54,000, got %.2f", pension.Diff.TotalTax)
+	}
+
+	deferred := result.Scenarios[1]
+	if deferred.Diff.TotalIncome != -1_000_000 {
+		t.Errorf("Expected income to fall by 1,000,000, got %.2f", deferred.Diff.TotalIncome)
+	}
+
+	transfer := result.Scenarios[2]
+	if transfer.Report.EmploymentIncome != 1_000_000 {
+		t.Errorf("Expected employment income 1,000,000, got %.2f", transfer.Report.EmploymentIncome)
+	}
+
+	// The baseline must not be modified by scenarios
+	if base.Transactions[0].Category != model.CategoryEmployment || result.Baseline.TotalIncome != 7_000_000 {
+		t.Errorf("Baseline was modified by a scenario")
+	}
+}
+
+func TestEngine_SimulateInvalidAdjustment(t *testing.T) {
+	engine := NewEngine()
+
+	req := model.SimulationRequest{
+		Scenarios: []model.Scenario{
+			{Adjustments: []model.Adjustment{{Type: model.AdjustmentRemoveTransaction, TransactionID: uuid.New()}}},
+		},
+	}
+
+	if _, err := engine.Simulate(req); err == nil {
+		t.Error("Expected an error for an unknown transaction")
+	}
+}
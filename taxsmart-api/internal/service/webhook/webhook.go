@@ -0,0 +1,433 @@
+// Package webhook notifies the webhooks users subscribe of events such as a
+// classified upload or a ready report. Every delivery is stored before it is
+// sent, signed with the webhook's secret, and retried with exponential
+// backoff until it succeeds or runs out of attempts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+)
+
+// Headers sent with every delivery
+const (
+	EventHeader     = "X-TaxSmart-Event"
+	DeliveryHeader  = "X-TaxSmart-Delivery"
+	SignatureHeader = "X-TaxSmart-Signature"
+)
+
+// Defaults for a zero Config
+const (
+	DefaultMaxAttempts = 8
+	DefaultRetryDelay  = 30 * time.Second
+	DefaultMaxDelay    = 6 * time.Hour
+	DefaultTimeout     = 10 * time.Second
+	DefaultWorkers     = 4
+)
+
+// pollInterval is how often due retries are looked for when nothing is published
+const pollInterval = 5 * time.Second
+
+// dueBatch is the most deliveries attempted per poll
+const dueBatch = 50
+
+// Config controls how deliveries are sent and retried
+type Config struct {
+	MaxAttempts int           // Attempts before a delivery fails for good
+	RetryDelay  time.Duration // Wait after the first failed attempt, doubled after each further one
+	MaxDelay    time.Duration // Longest wait between attempts
+	Timeout     time.Duration // How long a webhook has to respond
+	Workers     int           // Deliveries sent at once
+	Development bool          // Allow http URLs and private addresses, such as a receiver on localhost
+}
+
+// ErrForbiddenAddress is returned for webhooks on loopback, private,
+// link-local or unspecified addresses, which could reach internal services
+var ErrForbiddenAddress = errors.New("webhook address is not publicly routable")
+
+// Envelope is the JSON body of every delivery
+type Envelope struct {
+	ID        uuid.UUID `json:"id"` // The event's ID, shared by its deliveries to every webhook and their replays
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher queues events for the webhooks subscribed to them and sends them
+type Dispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	cfg    Config
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+// NewDispatcher creates a dispatcher, using the defaults for unset config
+func NewDispatcher(repo repository.WebhookRepository, cfg Config) *Dispatcher {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultRetryDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = DefaultMaxDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = DefaultWorkers
+	}
+	return &Dispatcher{
+		repo:   repo,
+		client: newClient(cfg),
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+		now:    time.Now,
+	}
+}
+
+// newClient creates the client deliveries are sent with. Outside development
+// it checks every address it connects to, after DNS resolution, so a host
+// cannot be pointed at an internal address once its webhook is created.
+// Redirects are not followed, and no proxy is used, so nothing is sent on to
+// an address that was not checked.
+func newClient(cfg Config) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.Development {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicIP reports whether an address may be sent deliveries
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsUnspecified()
+}
+
+// CheckURL validates a webhook URL before it is subscribed. Outside
+// development it must be an absolute https URL whose host resolves only to
+// public addresses; in development http and any address are allowed.
+func (d *Dispatcher) CheckURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" || (target.Scheme != "https" && (!d.cfg.Development || target.Scheme != "http")) {
+		if d.cfg.Development {
+			return nil, errors.New("url must be an absolute http or https URL")
+		}
+		return nil, errors.New("url must be an absolute https URL")
+	}
+	if d.cfg.Development {
+		return target, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return nil, fmt.Errorf("url host %s could not be resolved", target.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return nil, ErrForbiddenAddress
+		}
+	}
+	return target, nil
+}
+
+// NewSecret generates a secret for signing a webhook's deliveries
+func NewSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(key), nil
+}
+
+// Sign returns the signature header for a payload sent at timestamp (Unix
+// seconds): "t=<timestamp>,v1=<signature>", where the signature is the hex
+// HMAC-SHA256 of "<timestamp>.<payload>" keyed with the webhook's secret
+func Sign(secret string, timestamp int64, payload []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header against a payload received at now,
+// rejecting signatures made more than tolerance earlier
+func Verify(secret, header string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == 0 || signature == "" {
+		return errors.New("malformed signature header")
+	}
+	if now.Sub(time.Unix(timestamp, 0)) > tolerance {
+		return errors.New("signature has expired")
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(header)) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// Backoff returns the wait after the given number of failed attempts: the
+// retry delay, doubled for each attempt after the first, up to the max delay
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	delay := d.cfg.RetryDelay
+	for i := 1; i < attempts && delay < d.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxDelay)
+}
+
+// Publish queues an event for each of the user's webhooks subscribed to it
+func (d *Dispatcher) Publish(ctx context.Context, userID uuid.UUID, event string, data any) error {
+	webhooks, err := d.repo.ListWebhooks(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var subscribed []model.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	now := d.now()
+	payload, err := json.Marshal(Envelope{ID: uuid.New(), Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return err
+	}
+	for _, webhook := range subscribed {
+		delivery := &model.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     webhook.ID,
+			UserID:        userID,
+			Event:         event,
+			Payload:       payload,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := d.repo.CreateWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	d.signal()
+	return nil
+}
+
+// Replay queues one of the user's deliveries to be sent again, as a new
+// delivery with the same payload
+func (d *Dispatcher) Replay(ctx context.Context, userID, id uuid.UUID) (*model.WebhookDelivery, error) {
+	original, err := d.repo.GetWebhookDelivery(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.now()
+	delivery := &model.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     original.WebhookID,
+		UserID:        userID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		ReplayOf:      &original.ID,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := d.repo.CreateWebhookDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	d.signal()
+	return delivery, nil
+}
+
+// signal wakes the delivery loop without waiting for it
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start sends due deliveries in the background until ctx is done. Deliveries
+// still pending when it stops are sent after the next start.
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			d.DeliverDue(ctx)
+			select {
+			case <-ticker.C:
+			case <-d.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// DeliverDue claims the deliveries due now and attempts them on a pool of
+// workers, returning how many were attempted. Claimed deliveries are not
+// claimed again, by this or another instance, until the batch has had time
+// to be sent; any left unattempted are retried after that.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	now := d.now()
+	due, err := d.repo.ClaimDueWebhookDeliveries(ctx, now, now.Add(d.claimLease()), dueBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		attempted int
+		firstErr  error
+		slots     = make(chan struct{}, d.cfg.Workers)
+	)
+	for i := range due {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := d.attempt(ctx, delivery)
+			mu.Lock()
+			defer mu.Unlock()
+			attempted++
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(&due[i])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return attempted, firstErr
+	}
+	return attempted, ctx.Err()
+}
+
+// claimLease is how long a claimed batch is kept from other dispatchers: long
+// enough for each worker to wait out the timeout on its share of the batch
+func (d *Dispatcher) claimLease() time.Duration {
+	return d.cfg.Timeout*time.Duration(dueBatch/d.cfg.Workers+1) + time.Minute
+}
+
+// attempt sends a delivery once and stores the outcome, scheduling the next
+// attempt after a failure until the attempts run out
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	webhook, err := d.repo.GetWebhook(ctx, delivery.UserID, delivery.WebhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil // Deleted since it was queued, along with its deliveries
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := d.send(ctx, webhook, delivery.ID, delivery.Event, delivery.Payload)
+	now := d.now()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.UpdatedAt = now
+	switch {
+	case err == nil:
+		delivery.Status, delivery.Error, delivery.NextAttemptAt = model.DeliverySucceeded, "", nil
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status, delivery.Error, delivery.NextAttemptAt = model.DeliveryFailed, err.Error(), nil
+	default:
+		next := now.Add(d.Backoff(delivery.Attempts))
+		delivery.Error, delivery.NextAttemptAt = err.Error(), &next
+	}
+	return d.repo.UpdateWebhookDelivery(context.WithoutCancel(ctx), delivery)
+}
+
+// send posts a signed payload to a webhook, returning the response status.
+// Any status other than 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, webhook *model.Webhook, id uuid.UUID, event string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaxSmart-Webhooks/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, id.String())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, d.now().Unix(), payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,346 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/taxsmart/taxsmart-api/internal/model"
+	"github.com/taxsmart/taxsmart-api/internal/repository"
+	"github.com/taxsmart/taxsmart-api/internal/repository/memory"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"event":"report.ready"}`)
+	header := Sign("whsec_a", now.Unix(), payload)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		at      time.Time
+		valid   bool
+	}{
+		{"valid", "whsec_a", header, payload, now.Add(time.Minute), true},
+		{"payload changed", "whsec_a", header, []byte(`{"event":"job.finished"}`), now, false},
+		{"another secret", "whsec_b", header, payload, now, false},
+		{"too old", "whsec_a", header, payload, now.Add(10 * time.Minute), false},
+		{"malformed", "whsec_a", "v1=abc", payload, now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.payload, tt.at, 5*time.Minute)
+			if (err == nil) != tt.valid {
+				t.Errorf("Verify() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(memory.New(), Config{RetryDelay: time.Minute, MaxDelay: 10 * time.Minute})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{5, 10 * time.Minute},
+		{30, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := d.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// receiver is a webhook endpoint that fails its first failures requests
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	bodies   [][]byte
+	invalid  int // Requests whose signature did not verify
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	if Verify(rc.secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Hour) != nil || r.Header.Get(EventHeader) == "" {
+		rc.invalid++
+	}
+	rc.bodies = append(rc.bodies, body)
+	if len(rc.bodies) <= rc.failures {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setup creates a dispatcher on a clock the test moves, and a user with a webhook at the receiver
+func setup(t *testing.T, rc *receiver, events ...string) (*Dispatcher, *memory.Store, *time.Time, *model.Webhook) {
+	t.Helper()
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	repo := memory.New()
+	now := time.Now()
+	d := NewDispatcher(repo, Config{MaxAttempts: 3, RetryDelay: time.Minute, MaxDelay: time.Hour, Development: true})
+	d.now = func() time.Time { return now }
+
+	webhook := &model.Webhook{ID: uuid.New(), UserID: uuid.New(), URL: server.URL, Events: events, Secret: rc.secret, CreatedAt: now}
+	if err := repo.CreateWebhook(context.Background(), webhook); err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	return d, repo, &now, webhook
+}
+
+// deliveries returns the webhook's delivery log, newest first
+func deliveries(t *testing.T, repo *memory.Store, webhook *model.Webhook) []model.WebhookDelivery {
+	t.Helper()
+	log, err := repo.ListWebhookDeliveries(context.Background(), webhook.UserID, repository.WebhookDeliveryFilter{WebhookID: webhook.ID})
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries failed: %v", err)
+	}
+	return log
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{secret: "whsec_test", failures: 2}
+	d, repo, now, webhook := setup(t, rc)
+
+	if err := d.Publish(ctx, webhook.UserID, model.EventReportReady, map[string]int{"tax_year": 2026}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	steps := []struct {
+		wait      time.Duration
+		attempted int
+		status    string
+		attempts  int
+	}{
+		{0, 1, model.DeliveryPending, 1},
+		{59 * time.Second, 0, model.DeliveryPending, 1}, // Not due until a minute after the first failure
+		{time.Second, 1, model.DeliveryPending, 2},
+		{2 * time.Minute, 1, model.DeliverySucceeded, 3},
+		{time.Hour, 0, model.DeliverySucceeded, 3},
+	}
+	for i, step := range steps {
+		*now = now.Add(step.wait)
+		attempted, err := d.DeliverDue(ctx)
+		if err != nil || attempted != step.attempted {
+			t.Fatalf("step %d: DeliverDue() = %d, %v, want %d attempted", i, attempted, err, step.attempted)
+		}
+		log := deliveries(t, repo, webhook)
+		if len(log) != 1 || log[0].Status != step.status || log[0].Attempts != step.attempts {
+			t.Fatalf("step %d: delivery log = %+v, want one %s after %d attempts", i, log, step.status, step.attempts)
+		}
+	}
+
+	delivered := deliveries(t, repo, webhook)[0]
+	if delivered.ResponseStatus != http.StatusNoContent || delivered.Error != "" || delivered.NextAttemptAt != nil {
+		t.Errorf("delivered = %+v, want the success recorded", delivered)
+	}
+	if rc.invalid != 0 || len(rc.bodies) != 3 {
+		t.Fatalf("receiver got %d requests, %d badly signed", len(rc.bodies), rc.invalid)
+	}
+	var envelope struct {
+		ID    uuid.UUID      `json:"id"`
+		Event string         `json:"event"`
+		Data  map[string]int `json:"data"`
+	}
+	if err := json.Unmarshal(rc.bodies[2], &envelope); err != nil || envelope.Event != model.EventReportReady || envelope.Data["tax_year"] != 2026 {
+		t.Errorf("body = %s, %v", rc.bodies[2], err)
+	}
+}
+
+func TestDispatcher_GivesUpAndReplays(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{secret: "whsec_test", failures: 3}
+	d, repo, now, webhook := setup(t, rc, model.EventJobFinished)
+
+	// Only subscribed events are delivered
+	d.Publish(ctx, webhook.UserID, model.EventUploadClassified, map[string]string{})
+	d.Publish(ctx, webhook.UserID, model.EventJobFinished, map[string]string{"status": "succeeded"})
+	for range 3 {
+		d.DeliverDue(ctx)
+		*now = now.Add(time.Hour)
+	}
+
+	log := deliveries(t, repo, webhook)
+	if len(log) != 1 || log[0].Status != model.DeliveryFailed || log[0].Attempts != 3 || log[0].ResponseStatus != http.StatusBadGateway || log[0].Error == "" {
+		t.Fatalf("delivery log = %+v, want one failed after 3 attempts", log)
+	}
+
+	replay, err := d.Replay(ctx, webhook.UserID, log[0].ID)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if _, err := d.Replay(ctx, uuid.New(), log[0].ID); err == nil {
+		t.Errorf("Replay succeeded for another user's delivery")
+	}
+	if attempted, err := d.DeliverDue(ctx); err != nil || attempted != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want the replay attempted", attempted, err)
+	}
+
+	got, err := repo.GetWebhookDelivery(ctx, webhook.UserID, replay.ID)
+	if err != nil || got.Status != model.DeliverySucceeded || got.ReplayOf == nil || *got.ReplayOf != log[0].ID {
+		t.Fatalf("replay = %+v, %v, want a succeeded delivery replaying the original", got, err)
+	}
+	if string(rc.bodies[3]) != string(rc.bodies[0]) {
+		t.Errorf("replayed body = %s, want the original %s", rc.bodies[3], rc.bodies[0])
+	}
+}
+
+func TestDispatcher_CheckURL(t *testing.T) {
+	tests := []struct {
+		url         string
+		development bool
+		valid       bool
+	}{
+		{"https://8.8.8.8/hooks", false, true},
+		{"http://8.8.8.8/hooks", false, false},
+		{"http://8.8.8.8/hooks", true, true},
+		{"ftp://8.8.8.8/hooks", true, false},
+		{"/hooks", true, false},
+		{"https://127.0.0.1/hooks", false, false},
+		{"https://localhost/hooks", false, false},
+		{"https://10.0.0.8/hooks", false, false},
+		{"https://192.168.1.1/hooks", false, false},
+		{"https://169.254.169.254/latest/meta-data", false, false},
+		{"https://0.0.0.0/hooks", false, false},
+		{"https://[::1]/hooks", false, false},
+		{"https://[fd00::1]/hooks", false, false},
+		{"https://[::ffff:127.0.0.1]/hooks", false, false},
+		{"http://127.0.0.1:8080/hooks", true, true},
+	}
+	for _, tt := range tests {
+		d := NewDispatcher(memory.New(), Config{Development: tt.development})
+		if _, err := d.CheckURL(context.Background(), tt.url); (err == nil) != tt.valid {
+			t.Errorf("CheckURL(%q) in development %v = %v, want valid %v", tt.url, tt.development, err, tt.valid)
+		}
+	}
+}
+
+func TestDispatcher_RefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{secret: "whsec_test"}
+	d, repo, _, webhook := setup(t, rc)
+	// The webhook is on localhost, so only development may send to it, even
+	// though it was stored without being checked
+	d.client = newClient(Config{Timeout: time.Second})
+
+	d.Publish(ctx, webhook.UserID, model.EventReportReady, map[string]int{"tax_year": 2026})
+	if attempted, err := d.DeliverDue(ctx); err != nil || attempted != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want 1 attempted", attempted, err)
+	}
+	log := deliveries(t, repo, webhook)
+	if len(log) != 1 || !strings.Contains(log[0].Error, ErrForbiddenAddress.Error()) {
+		t.Errorf("delivery log = %+v, want the address refused", log)
+	}
+	if len(rc.bodies) != 0 {
+		t.Errorf("receiver got %d requests, want none", len(rc.bodies))
+	}
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{secret: "whsec_test"}
+	target := httptest.NewServer(rc)
+	t.Cleanup(target.Close)
+	d, repo, _, webhook := setup(t, &receiver{})
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirect.Close)
+	webhook.URL = redirect.URL
+	if err := repo.DeleteWebhook(ctx, webhook.UserID, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook failed: %v", err)
+	}
+	if err := repo.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+
+	d.Publish(ctx, webhook.UserID, model.EventReportReady, map[string]int{"tax_year": 2026})
+	d.DeliverDue(ctx)
+	log := deliveries(t, repo, webhook)
+	if len(log) != 1 || log[0].ResponseStatus != http.StatusTemporaryRedirect || log[0].Status != model.DeliveryPending {
+		t.Errorf("delivery log = %+v, want the redirect recorded as a failure", log)
+	}
+	if len(rc.bodies) != 0 {
+		t.Errorf("redirect target got %d requests, want none", len(rc.bodies))
+	}
+}
+
+func TestDispatcher_DeliversOnceAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	rc := &receiver{secret: "whsec_test"}
+	d, repo, now, webhook := setup(t, rc)
+	other := NewDispatcher(repo, d.cfg)
+	other.now = func() time.Time { return *now }
+
+	for range 20 {
+		d.Publish(ctx, webhook.UserID, model.EventReportReady, map[string]int{"tax_year": 2026})
+	}
+	var wg sync.WaitGroup
+	attempted := make([]int, 2)
+	for i, dispatcher := range []*Dispatcher{d, other} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempted[i], _ = dispatcher.DeliverDue(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if attempted[0]+attempted[1] != 20 || len(rc.bodies) != 20 {
+		t.Errorf("dispatchers attempted %v and the receiver got %d requests, want each of 20 deliveries sent once", attempted, len(rc.bodies))
+	}
+}
+
+func TestDispatcher_SendsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	// Each request waits until all the deliveries are in flight at once
+	const workers = 3
+	var arrived sync.WaitGroup
+	arrived.Add(workers)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	repo := memory.New()
+	d := NewDispatcher(repo, Config{Timeout: 5 * time.Second, Workers: workers, Development: true})
+	webhook := &model.Webhook{ID: uuid.New(), UserID: uuid.New(), URL: server.URL, Secret: "whsec_test", CreatedAt: time.Now()}
+	if err := repo.CreateWebhook(ctx, webhook); err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	for range workers {
+		d.Publish(ctx, webhook.UserID, model.EventReportReady, map[string]int{"tax_year": 2026})
+	}
+
+	if attempted, err := d.DeliverDue(ctx); err != nil || attempted != workers {
+		t.Fatalf("DeliverDue() = %d, %v, want %d attempted", attempted, err, workers)
+	}
+	for _, delivery := range deliveries(t, repo, webhook) {
+		if delivery.Status != model.DeliverySucceeded {
+			t.Errorf("delivery = %+v, want succeeded", delivery)
+		}
+	}
+}
@@ -53,6 +53,11 @@ func Unauthorized(w http.ResponseWriter, message string) {
 	Error(w, http.StatusUnauthorized, message)
 }
 
+// Forbidden sends a 403 error
+func Forbidden(w http.ResponseWriter, message string) {
+	Error(w, http.StatusForbidden, message)
+}
+
 // NotFound sends a 404 error
 func NotFound(w http.ResponseWriter, message string) {
 	Error(w, http.StatusNotFound, message)
@@ -5,8 +5,8 @@ import { Button } from '@/components/ui/Button';
 import { Card } from '@/components/ui/Card';
 import { endpoints } from '@/lib/api';
 import { formatCurrency } from '@/lib/utils';
-import { TaxReport } from '@/types';
-import { ArrowLeft, Download, FileText, Share2 } from 'lucide-react';
+import { IncomeSource, TaxReport } from '@/types';
+import { ArrowLeft, Download, FileText, Repeat, Share2, Wallet } from 'lucide-react';
 import { useRouter } from 'next/navigation';
 import { useEffect, useState } from 'react';
 import { PieChart, Pie, Cell, ResponsiveContainer, Tooltip, Legend } from 'recharts';
@@ -68,6 +68,7 @@ export default function ReportPage() {
         { name: 'Income Tax (PIT)', value: report.pit_amount, color: '#10b981' }, // Emerald-500
         { name: 'Net Income', value: report.total_income - report.total_tax, color: '#3b82f6' }, // Blue-500
     ];
+    const incomeSources: IncomeSource[] = report.breakdown?.income_sources ?? [];
 
     return (
         <div className="flex flex-col min-h-screen bg-slate-950">
@@ -157,6 +158,36 @@ export default function ReportPage() {
                             </div>
                         </Card>
 
+                        {incomeSources.length > 0 && (
+                            <Card>
+                                <h3 className="text-lg font-semibold mb-4 flex items-center gap-2">
+                                    <Wallet size={20} className="text-emerald-500" />
+                                    Your Income Sources
+                                </h3>
+
+                                <div className="space-y-2 text-sm">
+                                    {incomeSources.map((source) => (
+                                        <div key={source.payer} className="flex justify-between items-center py-2 border-b border-white/5">
+                                            <div>
+                                                <span className="block text-slate-300">{source.payer}</span>
+                                                <span className="text-xs text-slate-500 flex items-center gap-1">
+                                                    {source.kind && <Repeat size={12} />}
+                                                    {source.kind
+                                                        ? `${source.cadence} ${source.kind}`
+                                                        : `${source.count} payment${source.count === 1 ? '' : 's'}`}
+                                                    {source.treatment !== 'taxable' && ` · ${source.treatment.replace('_', ' ')}`}
+                                                </span>
+                                            </div>
+                                            <div className="text-right">
+                                                <span className="block font-medium">{formatCurrency(source.total)}</span>
+                                                <span className="text-xs text-slate-500">{(source.share * 100).toFixed(1)}%</span>
+                                            </div>
+                                        </div>
+                                    ))}
+                                </div>
+                            </Card>
+                        )}
+
                         <div className="bg-slate-900 rounded-xl p-6 border border-slate-800">
                             <h4 className="font-medium mb-4 text-slate-300">Tax Calculation logic (PIT 2026)</h4>
                             <div className="space-y-2 text-sm text-slate-500">
@@ -34,6 +34,23 @@ export const endpoints = {
 
     classify: (transactions: any[]) => api.post('/classify', transactions),
 
+    submitJob: (file: File, taxYear?: number) => {
+        const formData = new FormData();
+        formData.append('file', file);
+        if (taxYear) {
+            formData.append('tax_year', String(taxYear));
+        }
+        return api.post('/jobs', formData, {
+            headers: {
+                'Content-Type': 'multipart/form-data',
+            },
+        });
+    },
+
+    getJob: (id: string) => api.get(`/jobs/${id}`),
+
+    cancelJob: (id: string) => api.post(`/jobs/${id}/cancel`),
+
     calculateTax: (data: any) => api.post('/tax/calculate', data),
 
     quickPit: (annualIncome: number) => api.post('/tax/quick-pit', { annual_income: annualIncome }),
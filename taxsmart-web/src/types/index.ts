@@ -3,16 +3,34 @@ export type TransactionType = 'credit' | 'debit';
 export type Category =
     | 'employment_income'
     | 'freelance_income'
+    | 'freelance_income.domestic'
+    | 'freelance_income.foreign'
     | 'rental_income'
     | 'investment_income'
+    | 'investment_income.dividend'
+    | 'investment_income.capital_gain'
     | 'crypto_income'
     | 'interest_income'
     | 'other_income'
+    | 'other_income.gift'
     | 'expense'
     | 'rent_expense'
     | 'transfer'
+    | 'transfer.owner'
+    | 'transfer.loan'
+    | 'transfer.refund'
+    | 'transfer.reversal'
     | 'uncategorized';
 
+export type TaxTreatment = 'taxable' | 'exempt' | 'final_wht' | 'not_income';
+
+export interface CategoryNode {
+    category: Category;
+    parent?: Category;
+    description: string;
+    treatment: TaxTreatment;
+}
+
 export interface Transaction {
     id?: string;
     date: string;
@@ -22,6 +40,36 @@ export interface Transaction {
     category: Category;
     confidence: number;
     is_manual?: boolean;
+    linked_transaction_id?: string;
+}
+
+export interface IncomeSource {
+    payer: string;
+    category: Category;
+    treatment: TaxTreatment;
+    kind?: 'salary' | 'retainer' | 'recurring';
+    cadence?: 'weekly' | 'biweekly' | 'monthly' | 'quarterly';
+    count: number;
+    total: number;
+    share: number;
+}
+
+export interface Job {
+    id: string;
+    status: 'queued' | 'running' | 'succeeded' | 'failed' | 'canceled';
+    stage: 'parse' | 'classify' | 'calculate';
+    progress: number;
+    processed: number;
+    total: number;
+    filename: string;
+    bank_format?: string;
+    tax_year?: number;
+    upload_id?: string;
+    report_id?: string;
+    error?: string;
+    created_at: string;
+    updated_at: string;
+    finished_at?: string;
 }
 
 export interface TaxReport {